@@ -16,9 +16,8 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Connect to the database
+	// Connect to the database, it is closed by the app on shutdown
 	db := database.Connect()
-	defer db.Close()
 
 	// migrations
 	if err := db.RunMigrations(); err != nil {
@@ -0,0 +1,338 @@
+package alert
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
+)
+
+// OnQuote is called for every saved quote, it only marks the asset so its alerts are evaluated
+// in the background, as it runs on the ingestion path
+func (s *Service) OnQuote(quote asset.AssetQuoteChanData) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.dirty[quote.AssetId] = true
+}
+
+// Start evaluates the alerts of the quoted assets in the background, once
+func (s *Service) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+
+	go func() {
+		defer close(s.done)
+		ticker := time.NewTicker(evaluationInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.evaluateDirtyAssets()
+			}
+		}
+	}()
+}
+
+// Stop ends the evaluation, waiting for a running one to finish. Stopping a service that
+// never started or stopping it again returns right away.
+func (s *Service) Stop(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stop) })
+
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if !started {
+		return nil
+	}
+
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (s *Service) evaluateDirtyAssets() {
+	s.mu.Lock()
+	dirty := s.dirty
+	s.dirty = make(map[int64]bool)
+	s.mu.Unlock()
+	if len(dirty) == 0 {
+		return
+	}
+
+	assetIds := make([]int64, 0, len(dirty))
+	for assetId := range dirty {
+		assetIds = append(assetIds, assetId)
+	}
+	alerts, err := s.repo.GetActiveAlerts(assetIds)
+	if err != nil {
+		log.Errorf("Error evaluating alerts: %v", err)
+		return
+	}
+
+	var assetAlerts, portfolioAlerts []PriceAlert
+	for _, alert := range alerts {
+		if alert.AssetId.Valid {
+			assetAlerts = append(assetAlerts, alert)
+		} else {
+			portfolioAlerts = append(portfolioAlerts, alert)
+		}
+	}
+	if len(assetAlerts) > 0 {
+		if err := s.evaluateAssetAlerts(assetAlerts); err != nil {
+			log.Errorf("Error evaluating asset alerts: %v", err)
+		}
+	}
+	s.evaluatePortfolioAlerts(portfolioAlerts)
+}
+
+// evaluateAssetAlerts checks the alerts against the latest quotes of their assets, the quote
+// that marked an asset may be an older bar of a backfill
+func (s *Service) evaluateAssetAlerts(alerts []PriceAlert) error {
+	var assetIds []int64
+	seen := make(map[int64]bool)
+	for _, alert := range alerts {
+		if !seen[alert.AssetId.Int64] {
+			seen[alert.AssetId.Int64] = true
+			assetIds = append(assetIds, alert.AssetId.Int64)
+		}
+	}
+
+	assets, err := s.assetService.GetAssetsByIds(assetIds)
+	if err != nil {
+		return err
+	}
+	assetsById := make(map[int64]asset.Asset, len(assets))
+	for _, a := range assets {
+		assetsById[a.Id] = a
+	}
+
+	latestQuotes, err := s.assetService.GetLatestQuotes(assetIds)
+	if err != nil {
+		return err
+	}
+
+	previousRequests := make([]asset.PreviousCloseRequest, 0, len(assetIds))
+	for _, assetId := range assetIds {
+		if latestQuote, ok := latestQuotes[assetId]; ok {
+			previousRequests = append(previousRequests, asset.PreviousCloseRequest{
+				AssetId:  assetId,
+				Exchange: assetsById[assetId].Exchange.String,
+				Time:     latestQuote.QuoteTime,
+			})
+		}
+	}
+	previousQuotes, err := s.assetService.GetPreviousTradingDayQuotes(previousRequests)
+	if err != nil {
+		return err
+	}
+
+	// a new 52 week high or low is measured against the days before today
+	now := time.Now()
+	ranges, err := s.assetService.Get52WeekRanges(assetIds, now)
+	if err != nil {
+		return err
+	}
+
+	for _, alert := range alerts {
+		assetId := alert.AssetId.Int64
+		latestQuote, ok := latestQuotes[assetId]
+		if !ok {
+			continue
+		}
+		symbol := assetsById[assetId].Symbol
+		price := latestQuote.Quote
+
+		var met bool
+		var value float64
+		var message string
+		switch alert.Condition {
+		case ConditionAbove, ConditionBelow:
+			met = crossed(alert, price)
+			value = price
+			message = fmt.Sprintf("%s is %s %.2f at %.2f", symbol, alert.Condition, alert.Threshold.Float64, price)
+		case ConditionDailyMove:
+			previousQuote, ok := previousQuotes[assetId]
+			if !ok || previousQuote.Quote == 0 {
+				continue
+			}
+			value = ((price - previousQuote.Quote) / previousQuote.Quote) * 100
+			met = math.Abs(value) >= alert.Threshold.Float64
+			message = fmt.Sprintf("%s moved %+.2f%% since the previous close", symbol, value)
+		case ConditionHigh52w:
+			priceRange, ok := ranges[assetId]
+			if !ok {
+				continue
+			}
+			met = price > priceRange.High
+			value = price
+			message = fmt.Sprintf("%s reached a new 52 week high at %.2f", symbol, price)
+		case ConditionLow52w:
+			priceRange, ok := ranges[assetId]
+			if !ok {
+				continue
+			}
+			met = price < priceRange.Low
+			value = price
+			message = fmt.Sprintf("%s reached a new 52 week low at %.2f", symbol, price)
+		default:
+			continue
+		}
+		s.apply(alert, met, value, message, now)
+	}
+	return nil
+}
+
+// evaluatePortfolioAlerts checks the alerts against the valuation of their portfolio in the
+// base currency of the user, above and below compare the value of the holdings. Alerts on
+// portfolios the user neither owns nor follows anymore are deactivated instead.
+func (s *Service) evaluatePortfolioAlerts(alerts []PriceAlert) {
+	alertIds := make([]int64, len(alerts))
+	var portfolioIds []int64
+	seen := make(map[int64]bool)
+	for i, alert := range alerts {
+		alertIds[i] = alert.Id
+		if !seen[alert.PortfolioId.Int64] {
+			seen[alert.PortfolioId.Int64] = true
+			portfolioIds = append(portfolioIds, alert.PortfolioId.Int64)
+		}
+	}
+
+	deactivated, err := s.repo.DeactivateUnwatchedAlerts(alertIds)
+	if err != nil {
+		log.Errorf("Error evaluating portfolio alerts: %v", err)
+		return
+	}
+	unwatched := make(map[int64]bool, len(deactivated))
+	for _, alertId := range deactivated {
+		unwatched[alertId] = true
+	}
+
+	portfolios, err := s.portfolioService.GetPortfoliosWithAllocations(portfolioIds)
+	if err != nil {
+		log.Errorf("Error evaluating portfolio alerts: %v", err)
+		return
+	}
+	portfoliosById := make(map[int64]portfolio.PortfolioDTO, len(portfolios))
+	for _, p := range portfolios {
+		portfoliosById[p.Id] = p
+	}
+
+	currencies := make(map[int64]string)
+	now := time.Now()
+	for _, alert := range alerts {
+		p, ok := portfoliosById[alert.PortfolioId.Int64]
+		if !ok || unwatched[alert.Id] {
+			continue
+		}
+
+		currency, ok := currencies[alert.UserId]
+		if !ok {
+			var err error
+			currency, err = s.portfolioService.GetUserBaseCurrency(alert.UserId)
+			if err != nil {
+				log.Errorf("Error evaluating alert %d: %v", alert.Id, err)
+				continue
+			}
+			currencies[alert.UserId] = currency
+		}
+
+		valuation, err := s.portfolioService.GetPortfolioValuation(p, currency, p.UserId != alert.UserId)
+		if err != nil {
+			log.Errorf("Error evaluating alert %d: %v", alert.Id, err)
+			continue
+		}
+
+		var met bool
+		var value float64
+		var message string
+		switch alert.Condition {
+		case ConditionAbove, ConditionBelow:
+			met = crossed(alert, valuation.Amount)
+			value = valuation.Amount
+			message = fmt.Sprintf("%s is worth %.2f %s, %s %.2f", p.Name, valuation.Amount, currency, alert.Condition, alert.Threshold.Float64)
+		case ConditionDailyMove:
+			met = math.Abs(valuation.Change) >= alert.Threshold.Float64
+			value = valuation.Change
+			message = fmt.Sprintf("%s moved %+.2f%% since the previous close", p.Name, valuation.Change)
+		default:
+			continue
+		}
+		s.apply(alert, met, value, message, now)
+	}
+}
+
+// apply triggers an armed alert whose condition is met and re-arms a triggered one once its
+// condition no longer holds, so an alert fires when the value crosses over instead of while
+// it stays there. Daily move and 52 week alerts measure against the previous close and the
+// days before today, a new day re-arms them as well.
+func (s *Service) apply(alert PriceAlert, met bool, value float64, message string, now time.Time) {
+	if !met {
+		if !alert.Armed {
+			if err := s.repo.Rearm(alert.Id); err != nil {
+				log.Errorf("Error re-arming alert %d: %v", alert.Id, err)
+			}
+		}
+		return
+	}
+
+	rearmBefore := rearmBoundary(alert, now)
+	armed := alert.Armed || (alert.LastTriggeredAt.Valid && alert.LastTriggeredAt.Time.Before(rearmBefore))
+	if !armed || coolingDown(alert, now) {
+		return
+	}
+	s.trigger(alert, value, message, now, rearmBefore)
+}
+
+// rearmBoundary is the time before which a trigger no longer keeps the alert disarmed,
+// the zero time for alerts only re-armed by their condition
+func rearmBoundary(alert PriceAlert, now time.Time) time.Time {
+	switch alert.Condition {
+	case ConditionDailyMove, ConditionHigh52w, ConditionLow52w:
+		return startOfDay(now)
+	default:
+		return time.Time{}
+	}
+}
+
+func startOfDay(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+// coolingDown reports whether a recurring alert triggered too recently to trigger again,
+// the repository checks it once more when recording the trigger
+func coolingDown(alert PriceAlert, now time.Time) bool {
+	cooldown := time.Duration(alert.CooldownSeconds) * time.Second
+	return alert.LastTriggeredAt.Valid && now.Before(alert.LastTriggeredAt.Time.Add(cooldown))
+}
+
+// crossed reports whether the value is on the side of the threshold the alert waits for
+func crossed(alert PriceAlert, value float64) bool {
+	if alert.Condition == ConditionAbove {
+		return value >= alert.Threshold.Float64
+	}
+	return value <= alert.Threshold.Float64
+}
+
+func (s *Service) trigger(alert PriceAlert, value float64, message string, at, rearmBefore time.Time) {
+	triggered, err := s.repo.Trigger(alert, value, message, at, rearmBefore)
+	if err != nil {
+		log.Errorf("Error triggering alert %d: %v", alert.Id, err)
+		return
+	}
+	if triggered {
+		log.Infof("Alert %d triggered: %s", alert.Id, message)
+	}
+}
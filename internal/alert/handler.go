@@ -0,0 +1,124 @@
+package alert
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetAlerts(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	alerts, err := h.service.GetAlerts(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch alerts"})
+	}
+	return c.JSON(alerts)
+}
+
+func (h *Handler) CreateAlert(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	var req CreateAlertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	alert, err := h.service.CreateAlert(userId, req)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+	}
+	if err != nil {
+		return h.alertError(c, err, "Failed to create alert")
+	}
+	return c.Status(fiber.StatusCreated).JSON(alert)
+}
+
+func (h *Handler) UpdateAlert(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	alertId, err := c.ParamsInt("alertId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Alert ID"})
+	}
+
+	var req UpdateAlertRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	alert, err := h.service.UpdateAlert(userId, int64(alertId), req)
+	if err != nil {
+		return h.alertError(c, err, "Failed to update alert")
+	}
+	return c.JSON(alert)
+}
+
+func (h *Handler) DeleteAlert(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	alertId, err := c.ParamsInt("alertId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Alert ID"})
+	}
+
+	if err := h.service.DeleteAlert(userId, int64(alertId)); err != nil {
+		return h.alertError(c, err, "Failed to delete alert")
+	}
+	return c.JSON(fiber.Map{"message": "Alert deleted"})
+}
+
+func (h *Handler) GetInbox(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	unreadOnly := c.QueryBool("unread", false)
+
+	events, err := h.service.GetInbox(userId, unreadOnly)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch alert inbox"})
+	}
+	return c.JSON(fiber.Map{"events": events})
+}
+
+func (h *Handler) MarkRead(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	var req struct {
+		Ids []int64 `json:"ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	if err := h.service.MarkRead(userId, req.Ids...); err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to mark alert events as read"})
+	}
+	return c.JSON(fiber.Map{"message": "Alert events marked as read"})
+}
+
+func (h *Handler) alertError(c *fiber.Ctx, err error, message string) error {
+	switch {
+	case err == AlertNotFoundErr:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case err == PortfolioNotWatchedErr:
+		return c.Status(fiber.StatusForbidden).JSON(fiber.Map{"error": err.Error()})
+	case err == FollowedPortfolioConditionErr, errors.Is(err, InvalidAlertErr):
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
+	}
+}
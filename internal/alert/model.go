@@ -0,0 +1,128 @@
+package alert
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var AlertNotFoundErr error = errors.New("alert not found")
+var PortfolioNotWatchedErr error = errors.New("alerts can only be set on owned or followed portfolios")
+var InvalidAlertErr error = errors.New("invalid alert")
+var FollowedPortfolioConditionErr error = errors.New("alerts on followed portfolios only support daily_move")
+
+const (
+	ConditionAbove     = "above"
+	ConditionBelow     = "below"
+	ConditionDailyMove = "daily_move"
+	ConditionHigh52w   = "high_52w"
+	ConditionLow52w    = "low_52w"
+)
+
+const (
+	// defaultCooldown is how long a recurring alert stays quiet after it triggered
+	defaultCooldown = time.Hour
+	// evaluationInterval batches the quotes of an asset into one evaluation of its alerts
+	evaluationInterval = 2 * time.Second
+)
+
+// PriceAlert watches an asset or a portfolio. One-shot alerts are deactivated when they trigger,
+// recurring ones trigger again after the value went back across the threshold and the cooldown passed.
+type PriceAlert struct {
+	Id              int64           `db:"id" json:"id"`
+	UserId          int64           `db:"user_id" json:"-"`
+	AssetId         sql.NullInt64   `db:"asset_id" json:"asset_id"`
+	PortfolioId     sql.NullInt64   `db:"portfolio_id" json:"portfolio_id"`
+	Condition       string          `db:"condition" json:"condition"`
+	Threshold       sql.NullFloat64 `db:"threshold" json:"threshold"`
+	Recurring       bool            `db:"recurring" json:"recurring"`
+	CooldownSeconds int             `db:"cooldown_seconds" json:"cooldown_seconds"`
+	Active          bool            `db:"active" json:"active"`
+	// Armed is cleared by a trigger and set again once the condition stopped holding
+	Armed           bool         `db:"armed" json:"armed"`
+	LastTriggeredAt sql.NullTime `db:"last_triggered_at" json:"last_triggered_at"`
+	CreatedAt       time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt       time.Time    `db:"updated_at" json:"updated_at"`
+}
+
+// AlertEvent is a trigger of an alert, the events of a user make up their alerts inbox
+type AlertEvent struct {
+	Id          int64     `db:"id" json:"id"`
+	AlertId     int64     `db:"alert_id" json:"alert_id"`
+	UserId      int64     `db:"user_id" json:"-"`
+	Value       float64   `db:"value" json:"value"`
+	Message     string    `db:"message" json:"message"`
+	IsRead      bool      `db:"is_read" json:"is_read"`
+	TriggeredAt time.Time `db:"triggered_at" json:"triggered_at"`
+	CreatedAt   time.Time `db:"created_at" json:"created_at"`
+}
+
+type CreateAlertRequest struct {
+	// Symbol or PortfolioId selects what the alert watches
+	Symbol      string   `json:"symbol"`
+	PortfolioId int64    `json:"portfolio_id"`
+	Condition   string   `json:"condition"`
+	Threshold   *float64 `json:"threshold"`
+	Recurring   bool     `json:"recurring"`
+	// CooldownSeconds defaults to an hour
+	CooldownSeconds *int `json:"cooldown_seconds"`
+}
+
+func (r *CreateAlertRequest) validate() error {
+	r.Symbol = strings.ToUpper(strings.TrimSpace(r.Symbol))
+	if (r.Symbol == "") == (r.PortfolioId == 0) {
+		return errors.New("either symbol or portfolio_id is required")
+	}
+	if r.PortfolioId != 0 && (r.Condition == ConditionHigh52w || r.Condition == ConditionLow52w) {
+		return errors.New("52 week conditions are only supported on assets")
+	}
+	if r.CooldownSeconds != nil && *r.CooldownSeconds < 0 {
+		return errors.New("cooldown_seconds must not be negative")
+	}
+	return validateThreshold(r.Condition, r.Threshold)
+}
+
+func (r *CreateAlertRequest) cooldownSeconds() int {
+	if r.CooldownSeconds == nil {
+		return int(defaultCooldown.Seconds())
+	}
+	return *r.CooldownSeconds
+}
+
+type UpdateAlertRequest struct {
+	Threshold       *float64 `json:"threshold"`
+	Recurring       *bool    `json:"recurring"`
+	CooldownSeconds *int     `json:"cooldown_seconds"`
+	Active          *bool    `json:"active"`
+}
+
+func (r *UpdateAlertRequest) validate(condition string) error {
+	if r.CooldownSeconds != nil && *r.CooldownSeconds < 0 {
+		return errors.New("cooldown_seconds must not be negative")
+	}
+	if r.Threshold != nil {
+		return validateThreshold(condition, r.Threshold)
+	}
+	return nil
+}
+
+func validateThreshold(condition string, threshold *float64) error {
+	switch condition {
+	case ConditionAbove, ConditionBelow:
+		if threshold == nil || *threshold <= 0 {
+			return errors.New("a positive threshold price is required")
+		}
+	case ConditionDailyMove:
+		if threshold == nil || *threshold <= 0 {
+			return errors.New("a positive threshold percentage is required")
+		}
+	case ConditionHigh52w, ConditionLow52w:
+		if threshold != nil {
+			return errors.New("52 week conditions take no threshold")
+		}
+	default:
+		return errors.New("condition must be one of above, below, daily_move, high_52w or low_52w")
+	}
+	return nil
+}
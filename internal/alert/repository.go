@@ -0,0 +1,213 @@
+package alert
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetAlertsByUser(userId int64) ([]PriceAlert, error) {
+	query := `
+		SELECT *
+		FROM price_alert
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	alerts := []PriceAlert{}
+	err := r.db.Select(&alerts, query, userId)
+	if err != nil {
+		log.Errorf("Error fetching alerts: %v", err)
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// GetAlert returns the alert if it belongs to the user
+func (r *Repository) GetAlert(userId, alertId int64) (*PriceAlert, error) {
+	query := `
+		SELECT *
+		FROM price_alert
+		WHERE id = $1 AND user_id = $2
+	`
+	var alert PriceAlert
+	err := r.db.Get(&alert, query, alertId, userId)
+	if err == sql.ErrNoRows {
+		return nil, AlertNotFoundErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &alert, nil
+}
+
+func (r *Repository) CreateAlert(alert *PriceAlert) (*PriceAlert, error) {
+	query := `
+		INSERT INTO price_alert (user_id, asset_id, portfolio_id, condition, threshold, recurring, cooldown_seconds)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING *
+	`
+	var created PriceAlert
+	err := r.db.Get(&created, query, alert.UserId, alert.AssetId, alert.PortfolioId, alert.Condition,
+		alert.Threshold, alert.Recurring, alert.CooldownSeconds)
+	if err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (r *Repository) UpdateAlert(alert *PriceAlert) (*PriceAlert, error) {
+	query := `
+		UPDATE price_alert
+		SET threshold = $3, recurring = $4, cooldown_seconds = $5, active = $6, armed = TRUE
+		WHERE id = $1 AND user_id = $2
+		RETURNING *
+	`
+	var updated PriceAlert
+	err := r.db.Get(&updated, query, alert.Id, alert.UserId, alert.Threshold, alert.Recurring,
+		alert.CooldownSeconds, alert.Active)
+	if err == sql.ErrNoRows {
+		return nil, AlertNotFoundErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (r *Repository) DeleteAlert(userId, alertId int64) error {
+	result, err := r.db.Exec("DELETE FROM price_alert WHERE id = $1 AND user_id = $2", alertId, userId)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return AlertNotFoundErr
+	}
+	return nil
+}
+
+// GetActiveAlerts returns the active alerts on the assets and on the portfolios holding them
+func (r *Repository) GetActiveAlerts(assetIds []int64) ([]PriceAlert, error) {
+	query := `
+		SELECT *
+		FROM price_alert
+		WHERE active AND (
+			asset_id = ANY($1)
+			OR portfolio_id IN (SELECT portfolio_id FROM allocation WHERE asset_id = ANY($1))
+		)
+	`
+	var alerts []PriceAlert
+	err := r.db.Select(&alerts, query, pq.Array(assetIds))
+	if err != nil {
+		log.Errorf("Error fetching active alerts: %v", err)
+		return nil, err
+	}
+	return alerts, nil
+}
+
+// DeactivateUnwatchedAlerts deactivates the portfolio alerts whose user neither owns nor
+// follows the portfolio anymore and returns their ids
+func (r *Repository) DeactivateUnwatchedAlerts(alertIds []int64) ([]int64, error) {
+	query := `
+		UPDATE price_alert pa
+		SET active = FALSE
+		WHERE pa.id = ANY($1) AND pa.active AND pa.portfolio_id IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM portfolio p WHERE p.id = pa.portfolio_id AND p.user_id = pa.user_id)
+			AND NOT EXISTS (SELECT 1 FROM portfolio_follow pf WHERE pf.portfolio_id = pa.portfolio_id AND pf.user_id = pa.user_id)
+		RETURNING pa.id
+	`
+	var deactivated []int64
+	err := r.db.Select(&deactivated, query, pq.Array(alertIds))
+	if err != nil {
+		log.Errorf("Error deactivating unwatched alerts: %v", err)
+		return nil, err
+	}
+	return deactivated, nil
+}
+
+// Trigger records an event of the alert unless it's inactive, disarmed or cooling down, which
+// another instance may have caused since the alert was read. A trigger before rearmBefore no
+// longer disarms it. The alert is disarmed and one-shot alerts are deactivated.
+func (r *Repository) Trigger(alert PriceAlert, value float64, message string, at, rearmBefore time.Time) (bool, error) {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback() // Will be ignored if the tx has been committed later
+
+	query := `
+		UPDATE price_alert
+		SET last_triggered_at = $2, active = recurring, armed = FALSE
+		WHERE id = $1 AND active
+			AND (armed OR last_triggered_at < $3)
+			AND (last_triggered_at IS NULL OR last_triggered_at <= $2 - cooldown_seconds * INTERVAL '1 second')
+	`
+	result, err := tx.Exec(query, alert.Id, at, rearmBefore)
+	if err != nil {
+		return false, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if updated == 0 {
+		return false, nil
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO alert_event (alert_id, user_id, value, message, triggered_at)
+		VALUES ($1, $2, $3, $4, $5)
+	`, alert.Id, alert.UserId, value, message, at)
+	if err != nil {
+		return false, err
+	}
+
+	return true, tx.Commit()
+}
+
+// Rearm lets a triggered alert trigger again, its condition stopped holding
+func (r *Repository) Rearm(alertId int64) error {
+	_, err := r.db.Exec("UPDATE price_alert SET armed = TRUE WHERE id = $1 AND NOT armed", alertId)
+	return err
+}
+
+func (r *Repository) GetEvents(userId int64, unreadOnly bool) ([]AlertEvent, error) {
+	query := `
+		SELECT *
+		FROM alert_event
+		WHERE user_id = $1 AND (NOT $2 OR NOT is_read)
+		ORDER BY triggered_at DESC
+		LIMIT 100
+	`
+	events := []AlertEvent{}
+	err := r.db.Select(&events, query, userId, unreadOnly)
+	if err != nil {
+		log.Errorf("Error fetching alert events: %v", err)
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *Repository) MarkEventsRead(userId int64, eventIds []int64) error {
+	query := `
+		UPDATE alert_event
+		SET is_read = TRUE
+		WHERE user_id = $1 AND (COALESCE(cardinality($2::bigint[]), 0) = 0 OR id = ANY($2))
+	`
+	_, err := r.db.Exec(query, userId, pq.Array(eventIds))
+	return err
+}
@@ -0,0 +1,133 @@
+package alert
+
+import (
+	"database/sql"
+	"fmt"
+	"sync"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
+)
+
+type Service struct {
+	repo             *Repository
+	assetService     *asset.Service
+	portfolioService *portfolio.Service
+
+	mu       sync.Mutex
+	dirty    map[int64]bool
+	started  bool
+	stop     chan struct{}
+	stopOnce sync.Once
+	done     chan struct{}
+}
+
+func NewService(repo *Repository, assetService *asset.Service, portfolioService *portfolio.Service) *Service {
+	return &Service{
+		repo:             repo,
+		assetService:     assetService,
+		portfolioService: portfolioService,
+		dirty:            make(map[int64]bool),
+		stop:             make(chan struct{}),
+		done:             make(chan struct{}),
+	}
+}
+
+func (s *Service) GetAlerts(userId int64) ([]PriceAlert, error) {
+	return s.repo.GetAlertsByUser(userId)
+}
+
+// CreateAlert watches the asset of the symbol, or a portfolio the user owns or follows
+func (s *Service) CreateAlert(userId int64, request CreateAlertRequest) (*PriceAlert, error) {
+	alert := &PriceAlert{
+		UserId:          userId,
+		Condition:       request.Condition,
+		Recurring:       request.Recurring,
+		CooldownSeconds: request.cooldownSeconds(),
+	}
+	if request.Threshold != nil {
+		alert.Threshold = sql.NullFloat64{Float64: *request.Threshold, Valid: true}
+	}
+
+	if request.Symbol != "" {
+		a, err := s.assetService.GetAssetBySymbol(request.Symbol)
+		if err != nil {
+			return nil, err
+		}
+		alert.AssetId = sql.NullInt64{Int64: a.Id, Valid: true}
+	} else {
+		followed, err := s.isFollowedPortfolio(userId, request.PortfolioId)
+		if err != nil {
+			return nil, err
+		}
+		if followed && request.Condition != ConditionDailyMove {
+			return nil, FollowedPortfolioConditionErr
+		}
+		alert.PortfolioId = sql.NullInt64{Int64: request.PortfolioId, Valid: true}
+	}
+
+	return s.repo.CreateAlert(alert)
+}
+
+// isFollowedPortfolio reports whether the portfolio is followed rather than owned by the user,
+// it fails when the user does neither
+func (s *Service) isFollowedPortfolio(userId, portfolioId int64) (bool, error) {
+	p, err := s.portfolioService.GetPortfolio(portfolioId)
+	if err == sql.ErrNoRows {
+		return false, PortfolioNotWatchedErr
+	}
+	if err != nil {
+		return false, err
+	}
+	if p.UserId == userId {
+		return false, nil
+	}
+
+	following, err := s.portfolioService.IsFollowing(userId, portfolioId)
+	if err != nil {
+		return false, err
+	}
+	if !following {
+		return false, PortfolioNotWatchedErr
+	}
+	return true, nil
+}
+
+// UpdateAlert changes the given fields and arms the alert again
+func (s *Service) UpdateAlert(userId, alertId int64, request UpdateAlertRequest) (*PriceAlert, error) {
+	alert, err := s.repo.GetAlert(userId, alertId)
+	if err != nil {
+		return nil, err
+	}
+	if err := request.validate(alert.Condition); err != nil {
+		return nil, fmt.Errorf("%w: %v", InvalidAlertErr, err)
+	}
+
+	if request.Threshold != nil {
+		alert.Threshold = sql.NullFloat64{Float64: *request.Threshold, Valid: true}
+	}
+	if request.Recurring != nil {
+		alert.Recurring = *request.Recurring
+	}
+	if request.CooldownSeconds != nil {
+		alert.CooldownSeconds = *request.CooldownSeconds
+	}
+	if request.Active != nil {
+		alert.Active = *request.Active
+	}
+	return s.repo.UpdateAlert(alert)
+}
+
+func (s *Service) DeleteAlert(userId, alertId int64) error {
+	return s.repo.DeleteAlert(userId, alertId)
+}
+
+// GetInbox returns the latest trigger events of the user's alerts
+func (s *Service) GetInbox(userId int64, unreadOnly bool) ([]AlertEvent, error) {
+	return s.repo.GetEvents(userId, unreadOnly)
+}
+
+// MarkRead marks the given events as read, or all of them when no ids are given
+func (s *Service) MarkRead(userId int64, eventIds ...int64) error {
+	return s.repo.MarkEventsRead(userId, eventIds)
+}
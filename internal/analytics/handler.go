@@ -0,0 +1,103 @@
+package analytics
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetCorrelation(c *fiber.Ctx) error {
+	portfolioId, err := c.ParamsInt("portfolioId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid portfolio ID"})
+	}
+
+	windowDays := c.QueryInt("window", 90)
+	if windowDays < 7 || windowDays > 365 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Window must be between 7 and 365 days"})
+	}
+
+	threshold := c.QueryFloat("threshold", 0.8)
+	if threshold < -1 || threshold > 1 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Threshold must be between -1 and 1"})
+	}
+
+	resp, err := h.service.CalculateCorrelation(int64(portfolioId), windowDays, threshold)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Portfolio not found"})
+		}
+		if err == NotEnoughDataErr {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to calculate correlation"})
+	}
+
+	return c.JSON(resp)
+}
+
+func (h *Handler) GetExposure(c *fiber.Ctx) error {
+	portfolioId, err := c.ParamsInt("portfolioId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid portfolio ID"})
+	}
+
+	resp, err := h.service.CalculateExposure(int64(portfolioId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Portfolio not found"})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to calculate exposure"})
+	}
+
+	return c.JSON(resp)
+}
+
+func (h *Handler) GetLookThrough(c *fiber.Ctx) error {
+	portfolioId, err := c.ParamsInt("portfolioId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid portfolio ID"})
+	}
+
+	resp, err := h.service.CalculateLookThrough(int64(portfolioId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Portfolio not found"})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to calculate look-through"})
+	}
+
+	return c.JSON(resp)
+}
+
+func (h *Handler) GetOverlap(c *fiber.Ctx) error {
+	symbolA := c.Query("a")
+	symbolB := c.Query("b")
+	if symbolA == "" || symbolB == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Both a and b symbols are required"})
+	}
+
+	resp, err := h.service.CalculateOverlap(symbolA, symbolB)
+	if err != nil {
+		if errors.Is(err, SymbolNotFoundErr) {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to calculate overlap"})
+	}
+
+	return c.JSON(resp)
+}
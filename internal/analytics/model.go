@@ -0,0 +1,81 @@
+package analytics
+
+import "github.com/karataydev/portfoliomanbackend/internal/asset"
+
+const UnknownBucket = "Unknown"
+
+type CorrelatedPair struct {
+	SymbolA     string  `json:"symbol_a"`
+	SymbolB     string  `json:"symbol_b"`
+	Correlation float64 `json:"correlation"`
+}
+
+type AssetVolatility struct {
+	AssetId    int64   `json:"asset_id"`
+	Symbol     string  `json:"symbol"`
+	Weight     float64 `json:"weight"`
+	Volatility float64 `json:"volatility"`
+}
+
+type CorrelationResponse struct {
+	PortfolioId           int64             `json:"portfolio_id"`
+	WindowDays            int               `json:"window_days"`
+	Observations          int               `json:"observations"`
+	Symbols               []string          `json:"symbols"`
+	Matrix                [][]float64       `json:"matrix"`
+	Assets                []AssetVolatility `json:"assets"`
+	PortfolioVolatility   float64           `json:"portfolio_volatility"`
+	DiversificationRatio  float64           `json:"diversification_ratio"`
+	Threshold             float64           `json:"threshold"`
+	HighlyCorrelatedPairs []CorrelatedPair  `json:"highly_correlated_pairs"`
+	ExcludedSymbols       []string          `json:"excluded_symbols"`
+}
+
+type ExposureBucket struct {
+	Name       string  `json:"name"`
+	Percentage float64 `json:"percentage"`
+}
+
+type ExposureResponse struct {
+	PortfolioId int64            `json:"portfolio_id"`
+	Sector      []ExposureBucket `json:"sector"`
+	AssetClass  []ExposureBucket `json:"asset_class"`
+	Country     []ExposureBucket `json:"country"`
+	Currency    []ExposureBucket `json:"currency"`
+}
+
+type UnderlyingExposure struct {
+	Symbol  string   `json:"symbol"`
+	Name    string   `json:"name"`
+	Sector  string   `json:"sector"`
+	Country string   `json:"country"`
+	Weight  float64  `json:"weight"`
+	Via     []string `json:"via"`
+}
+
+type LookThroughResponse struct {
+	PortfolioId int64                `json:"portfolio_id"`
+	Exposures   []UnderlyingExposure `json:"exposures"`
+}
+
+type OverlapHolding struct {
+	Symbol  string  `json:"symbol"`
+	Name    string  `json:"name"`
+	WeightA float64 `json:"weight_a"`
+	WeightB float64 `json:"weight_b"`
+	Overlap float64 `json:"overlap"`
+}
+
+type OverlapResponse struct {
+	SymbolA        string           `json:"symbol_a"`
+	SymbolB        string           `json:"symbol_b"`
+	Overlap        float64          `json:"overlap"`
+	CommonHoldings int              `json:"common_holdings"`
+	Holdings       []OverlapHolding `json:"holdings"`
+}
+
+// weightedAsset is an asset held directly with its weight as a percentage
+type weightedAsset struct {
+	asset  asset.Asset
+	weight float64
+}
@@ -0,0 +1,472 @@
+package analytics
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
+	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
+)
+
+var NotEnoughDataErr error = errors.New("not enough quote data to calculate correlation")
+var SymbolNotFoundErr error = errors.New("symbol not found as either portfolio or asset")
+
+type Service struct {
+	portfolioService *portfolio.Service
+	assetService     *asset.Service
+}
+
+func NewService(portfolioService *portfolio.Service, assetService *asset.Service) *Service {
+	return &Service{
+		portfolioService: portfolioService,
+		assetService:     assetService,
+	}
+}
+
+func (s *Service) CalculateCorrelation(portfolioId int64, windowDays int, threshold float64) (*CorrelationResponse, error) {
+	portfolio, err := s.portfolioService.GetPortfolioWithAllocations(portfolioId, fx.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	endDate := time.Now()
+	startDate := endDate.AddDate(0, 0, -windowDays)
+
+	weights := allocationWeights(portfolio)
+
+	var assets []AssetVolatility
+	var closes []map[string]float64
+	excluded := []string{}
+	for _, allocation := range portfolio.Allocations {
+		quotes, err := s.assetService.GetAssetQuotesForPeriod(allocation.Asset.Id, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		if len(quotes) == 0 {
+			excluded = append(excluded, allocation.Asset.Symbol)
+			continue
+		}
+
+		assets = append(assets, AssetVolatility{
+			AssetId: allocation.Asset.Id,
+			Symbol:  allocation.Asset.Symbol,
+			Weight:  weights[allocation.Id],
+		})
+		closes = append(closes, dailyCloses(quotes))
+	}
+
+	if len(assets) == 0 {
+		return nil, NotEnoughDataErr
+	}
+
+	dates := commonDates(closes)
+	if len(dates) < 3 {
+		return nil, NotEnoughDataErr
+	}
+
+	returns := make([][]float64, len(assets))
+	for i := range assets {
+		returns[i] = dailyReturns(closes[i], dates)
+		assets[i].Volatility = stdDev(returns[i])
+	}
+
+	n := len(assets)
+	matrix := make([][]float64, n)
+	for i := range matrix {
+		matrix[i] = make([]float64, n)
+		matrix[i][i] = 1
+	}
+
+	pairs := []CorrelatedPair{}
+	for i := 0; i < n; i++ {
+		for j := i + 1; j < n; j++ {
+			corr := correlation(returns[i], returns[j])
+			matrix[i][j] = corr
+			matrix[j][i] = corr
+			if corr >= threshold {
+				pairs = append(pairs, CorrelatedPair{
+					SymbolA:     assets[i].Symbol,
+					SymbolB:     assets[j].Symbol,
+					Correlation: corr,
+				})
+			}
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		return pairs[i].Correlation > pairs[j].Correlation
+	})
+
+	// Normalize the weights so excluded assets don't skew the result
+	sumWeight := 0.0
+	for _, a := range assets {
+		sumWeight += a.Weight
+	}
+	for i := range assets {
+		if sumWeight != 0 {
+			assets[i].Weight = assets[i].Weight / sumWeight
+		} else {
+			assets[i].Weight = 1 / float64(n)
+		}
+	}
+
+	// Diversification ratio: weighted average volatility over portfolio volatility
+	weightedVolatility := 0.0
+	variance := 0.0
+	for i := 0; i < n; i++ {
+		weightedVolatility += assets[i].Weight * assets[i].Volatility
+		for j := 0; j < n; j++ {
+			variance += assets[i].Weight * assets[j].Weight * matrix[i][j] * assets[i].Volatility * assets[j].Volatility
+		}
+	}
+	portfolioVolatility := math.Sqrt(math.Max(variance, 0))
+
+	diversificationRatio := 0.0
+	if portfolioVolatility != 0 {
+		diversificationRatio = weightedVolatility / portfolioVolatility
+	}
+
+	symbols := make([]string, n)
+	for i, a := range assets {
+		symbols[i] = a.Symbol
+	}
+
+	return &CorrelationResponse{
+		PortfolioId:           portfolio.Id,
+		WindowDays:            windowDays,
+		Observations:          len(dates) - 1,
+		Symbols:               symbols,
+		Matrix:                matrix,
+		Assets:                assets,
+		PortfolioVolatility:   portfolioVolatility,
+		DiversificationRatio:  diversificationRatio,
+		Threshold:             threshold,
+		HighlyCorrelatedPairs: pairs,
+		ExcludedSymbols:       excluded,
+	}, nil
+}
+
+func (s *Service) CalculateExposure(portfolioId int64) (*ExposureResponse, error) {
+	holdings, err := s.portfolioHoldings(portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	assetClass := make(map[string]float64)
+	currency := make(map[string]float64)
+	for _, holding := range holdings {
+		assetClass[bucketName(holding.asset.AssetClass)] += holding.weight
+		currency[bucketName(holding.asset.Currency)] += holding.weight
+	}
+
+	// Funds are expanded into their holdings for the sector and country breakdowns
+	exposures, err := s.lookThrough(holdings)
+	if err != nil {
+		return nil, err
+	}
+
+	sector := make(map[string]float64)
+	country := make(map[string]float64)
+	for _, exposure := range exposures {
+		sector[exposure.Sector] += exposure.Weight
+		country[exposure.Country] += exposure.Weight
+	}
+
+	return &ExposureResponse{
+		PortfolioId: portfolioId,
+		Sector:      toBuckets(sector),
+		AssetClass:  toBuckets(assetClass),
+		Country:     toBuckets(country),
+		Currency:    toBuckets(currency),
+	}, nil
+}
+
+func (s *Service) CalculateLookThrough(portfolioId int64) (*LookThroughResponse, error) {
+	holdings, err := s.portfolioHoldings(portfolioId)
+	if err != nil {
+		return nil, err
+	}
+
+	exposures, err := s.lookThrough(holdings)
+	if err != nil {
+		return nil, err
+	}
+
+	return &LookThroughResponse{
+		PortfolioId: portfolioId,
+		Exposures:   exposures,
+	}, nil
+}
+
+// CalculateOverlap compares the underlying holdings of two symbols,
+// each of which can be a portfolio or an asset.
+func (s *Service) CalculateOverlap(symbolA, symbolB string) (*OverlapResponse, error) {
+	exposuresA, err := s.symbolExposures(symbolA)
+	if err != nil {
+		return nil, err
+	}
+	exposuresB, err := s.symbolExposures(symbolB)
+	if err != nil {
+		return nil, err
+	}
+
+	weightsB := make(map[string]UnderlyingExposure, len(exposuresB))
+	for _, exposure := range exposuresB {
+		weightsB[exposure.Symbol] = exposure
+	}
+
+	holdings := []OverlapHolding{}
+	total := 0.0
+	for _, a := range exposuresA {
+		b, ok := weightsB[a.Symbol]
+		if !ok {
+			continue
+		}
+		overlap := math.Min(a.Weight, b.Weight)
+		total += overlap
+		holdings = append(holdings, OverlapHolding{
+			Symbol:  a.Symbol,
+			Name:    a.Name,
+			WeightA: a.Weight,
+			WeightB: b.Weight,
+			Overlap: overlap,
+		})
+	}
+	sort.Slice(holdings, func(i, j int) bool {
+		return holdings[i].Overlap > holdings[j].Overlap
+	})
+
+	return &OverlapResponse{
+		SymbolA:        symbolA,
+		SymbolB:        symbolB,
+		Overlap:        total,
+		CommonHoldings: len(holdings),
+		Holdings:       holdings,
+	}, nil
+}
+
+func (s *Service) symbolExposures(symbol string) ([]UnderlyingExposure, error) {
+	portfolioInfo, err := s.portfolioService.GetPortfolioBySymbol(symbol)
+	if err == nil {
+		holdings, err := s.portfolioHoldings(portfolioInfo.Id)
+		if err != nil {
+			return nil, err
+		}
+		return s.lookThrough(holdings)
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	assetInfo, err := s.assetService.GetAssetBySymbol(symbol)
+	if err == nil {
+		return s.lookThrough([]weightedAsset{{asset: *assetInfo, weight: 100}})
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	return nil, fmt.Errorf("%w: %s", SymbolNotFoundErr, symbol)
+}
+
+func (s *Service) portfolioHoldings(portfolioId int64) ([]weightedAsset, error) {
+	portfolio, err := s.portfolioService.GetPortfolioWithAllocations(portfolioId, fx.DefaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	assetIds := make([]int64, 0, len(portfolio.Allocations))
+	for _, allocation := range portfolio.Allocations {
+		assetIds = append(assetIds, allocation.Asset.Id)
+	}
+
+	assets, err := s.assetService.GetAssetsByIds(assetIds)
+	if err != nil {
+		return nil, err
+	}
+	assetMap := make(map[int64]asset.Asset, len(assets))
+	for _, a := range assets {
+		assetMap[a.Id] = a
+	}
+
+	weights := allocationWeights(portfolio)
+	holdings := make([]weightedAsset, 0, len(portfolio.Allocations))
+	for _, allocation := range portfolio.Allocations {
+		holdings = append(holdings, weightedAsset{
+			asset:  assetMap[allocation.Asset.Id],
+			weight: weights[allocation.Id],
+		})
+	}
+	return holdings, nil
+}
+
+// lookThrough expands funds into their constituents. Holdings without constituent
+// data, and the part of a fund not covered by its listed constituents, are kept as is.
+func (s *Service) lookThrough(holdings []weightedAsset) ([]UnderlyingExposure, error) {
+	assetIds := make([]int64, 0, len(holdings))
+	for _, holding := range holdings {
+		assetIds = append(assetIds, holding.asset.Id)
+	}
+
+	constituents, err := s.assetService.GetConstituents(assetIds...)
+	if err != nil {
+		return nil, err
+	}
+	constituentMap := make(map[int64][]asset.AssetConstituent)
+	for _, c := range constituents {
+		constituentMap[c.AssetId] = append(constituentMap[c.AssetId], c)
+	}
+
+	exposureMap := make(map[string]*UnderlyingExposure)
+	var order []string
+	add := func(symbol, name, sector, country string, weight float64, via string) {
+		exposure, ok := exposureMap[symbol]
+		if !ok {
+			exposure = &UnderlyingExposure{Symbol: symbol, Name: name, Sector: sector, Country: country, Via: []string{}}
+			exposureMap[symbol] = exposure
+			order = append(order, symbol)
+		}
+		exposure.Weight += weight
+		exposure.Via = append(exposure.Via, via)
+	}
+
+	for _, holding := range holdings {
+		a := holding.asset
+		residual := 100.0
+		for _, c := range constituentMap[a.Id] {
+			add(c.Symbol, c.Name.String, bucketName(c.Sector), bucketName(c.Country), holding.weight*c.Weight/100, a.Symbol)
+			residual -= c.Weight
+		}
+		if residual > 0 {
+			add(a.Symbol, a.Name, bucketName(a.Sector), bucketName(a.Country), holding.weight*residual/100, a.Symbol)
+		}
+	}
+
+	exposures := make([]UnderlyingExposure, 0, len(order))
+	for _, symbol := range order {
+		exposures = append(exposures, *exposureMap[symbol])
+	}
+	sort.SliceStable(exposures, func(i, j int) bool {
+		return exposures[i].Weight > exposures[j].Weight
+	})
+	return exposures, nil
+}
+
+// allocationWeights returns the weight of every allocation keyed by allocation id.
+// Current percentages are used when the portfolio has positions, target percentages otherwise.
+func allocationWeights(portfolio *portfolio.PortfolioDTO) map[int64]float64 {
+	useTarget := true
+	for _, allocation := range portfolio.Allocations {
+		if allocation.CurrentPercentage != 0 {
+			useTarget = false
+			break
+		}
+	}
+
+	weights := make(map[int64]float64, len(portfolio.Allocations))
+	for _, allocation := range portfolio.Allocations {
+		if useTarget {
+			weights[allocation.Id] = allocation.TargetPercentage
+		} else {
+			weights[allocation.Id] = allocation.CurrentPercentage
+		}
+	}
+	return weights
+}
+
+func bucketName(value sql.NullString) string {
+	if !value.Valid || value.String == "" {
+		return UnknownBucket
+	}
+	return value.String
+}
+
+func toBuckets(values map[string]float64) []ExposureBucket {
+	buckets := make([]ExposureBucket, 0, len(values))
+	for name, percentage := range values {
+		buckets = append(buckets, ExposureBucket{Name: name, Percentage: percentage})
+	}
+	sort.Slice(buckets, func(i, j int) bool {
+		return buckets[i].Percentage > buckets[j].Percentage
+	})
+	return buckets
+}
+
+// dailyCloses keeps the last quote of each day, keyed by date
+func dailyCloses(quotes []asset.AssetQuote) map[string]float64 {
+	closes := make(map[string]float64)
+	for _, quote := range quotes {
+		// quotes are ordered by time, so later quotes overwrite earlier ones
+		closes[quote.QuoteTime.Format("2006-01-02")] = quote.Quote
+	}
+	return closes
+}
+
+// commonDates returns the sorted dates every asset has a close for
+func commonDates(closes []map[string]float64) []string {
+	var dates []string
+	for date := range closes[0] {
+		found := true
+		for _, c := range closes[1:] {
+			if _, ok := c[date]; !ok {
+				found = false
+				break
+			}
+		}
+		if found {
+			dates = append(dates, date)
+		}
+	}
+	sort.Strings(dates)
+	return dates
+}
+
+func dailyReturns(closes map[string]float64, dates []string) []float64 {
+	returns := make([]float64, 0, len(dates)-1)
+	for i := 1; i < len(dates); i++ {
+		prev := closes[dates[i-1]]
+		if prev == 0 {
+			returns = append(returns, 0)
+			continue
+		}
+		returns = append(returns, closes[dates[i]]/prev-1)
+	}
+	return returns
+}
+
+func mean(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
+
+func stdDev(values []float64) float64 {
+	if len(values) < 2 {
+		return 0
+	}
+	m := mean(values)
+	sum := 0.0
+	for _, v := range values {
+		sum += (v - m) * (v - m)
+	}
+	return math.Sqrt(sum / float64(len(values)-1))
+}
+
+func correlation(a, b []float64) float64 {
+	meanA, meanB := mean(a), mean(b)
+	var cov, varA, varB float64
+	for i := range a {
+		da, db := a[i]-meanA, b[i]-meanB
+		cov += da * db
+		varA += da * da
+		varB += db * db
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
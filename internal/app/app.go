@@ -1,7 +1,12 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/cors"
@@ -9,18 +14,33 @@ import (
 	"github.com/gofiber/fiber/v2/middleware/requestid"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
 	_ "github.com/golang-migrate/migrate/v4/source/github"
+	"github.com/karataydev/portfoliomanbackend/internal/alert"
+	"github.com/karataydev/portfoliomanbackend/internal/analytics"
 	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/assetcatalog"
 	"github.com/karataydev/portfoliomanbackend/internal/assetquotefeeder"
 	"github.com/karataydev/portfoliomanbackend/internal/auth"
+	"github.com/karataydev/portfoliomanbackend/internal/cachemetrics"
 	"github.com/karataydev/portfoliomanbackend/internal/config"
 	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
 	"github.com/karataydev/portfoliomanbackend/internal/investmentgrowth"
+	"github.com/karataydev/portfoliomanbackend/internal/job"
+	"github.com/karataydev/portfoliomanbackend/internal/livequote"
+	"github.com/karataydev/portfoliomanbackend/internal/marketlist"
+	"github.com/karataydev/portfoliomanbackend/internal/notification"
 	"github.com/karataydev/portfoliomanbackend/internal/param"
 	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
+	"github.com/karataydev/portfoliomanbackend/internal/quotebackfill"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteretention"
+	"github.com/karataydev/portfoliomanbackend/internal/quotestream"
+	"github.com/karataydev/portfoliomanbackend/internal/tradingcalendar"
 	"github.com/karataydev/portfoliomanbackend/internal/transaction"
 	"github.com/karataydev/portfoliomanbackend/internal/user"
+	"github.com/karataydev/portfoliomanbackend/internal/watchlist"
+	"github.com/karataydev/portfoliomanbackend/pkg/lifecycle"
 	"github.com/karataydev/portfoliomanbackend/pkg/scheduler"
-	"github.com/svarlamov/goyhfin"
 )
 
 type App struct {
@@ -35,10 +55,37 @@ type App struct {
 	assetService *asset.Service
 	assetHandler *asset.Handler
 
+	quoteProviders          *quoteprovider.Registry
 	assetQuoteFeederService *assetquotefeeder.Service
+	fxService               *fx.Service
 	transactionService      *transaction.Service
 	transactionHandler      *transaction.Handler
 
+	assetCatalogService *assetcatalog.Service
+	assetCatalogHandler *assetcatalog.Handler
+
+	notificationService *notification.Service
+	notificationHandler *notification.Handler
+
+	marketListService *marketlist.Service
+	marketListHandler *marketlist.Handler
+
+	watchlistService *watchlist.Service
+	watchlistHandler *watchlist.Handler
+
+	alertService *alert.Service
+	alertHandler *alert.Handler
+
+	quoteBackfillService *quotebackfill.Service
+	quoteBackfillHandler *quotebackfill.Handler
+
+	liveQuoteService *livequote.Service
+
+	quoteRetentionService *quoteretention.Service
+
+	quoteStreamService *quotestream.Service
+	quoteStreamHandler *quotestream.Handler
+
 	userService *user.Service
 	userHandler *user.Handler
 
@@ -47,20 +94,36 @@ type App struct {
 	investmentGrowthService *investmentgrowth.Service
 	investmentGrowthHandler *investmentgrowth.Handler
 
-	scheduler *scheduler.Scheduler
+	analyticsService *analytics.Service
+	analyticsHandler *analytics.Handler
+
+	scheduler  *scheduler.Scheduler
+	jobHandler *job.Handler
+
+	cacheMetricsHandler *cachemetrics.Handler
+
+	lifecycle      *lifecycle.Manager
+	quoteChan      chan asset.AssetQuoteChanData
+	quoteIngestion *sync.WaitGroup
+	feederStopped  bool
+
+	// initialLoad tracks the first start's quote load, stopInitialLoad cancels it
+	initialLoad     sync.WaitGroup
+	stopInitialLoad context.CancelFunc
 }
 
 func New(db *database.DBConnection) *App {
 	app := &App{
 		db:        db,
 		fiberApp:  createFiberApp(),
-		scheduler: scheduler.New(),
+		lifecycle: lifecycle.New(),
 	}
 
 	app.initServices()
 	app.initHandlers()
 	app.setupRoutes()
 	app.setupScheduler()
+	app.setupShutdown()
 
 	return app
 }
@@ -69,18 +132,45 @@ func (a *App) initServices() {
 	paramRepo := param.NewRepository(a.db)
 	a.paramService = param.NewService(paramRepo)
 
-	assetQuoteChan := make(chan asset.AssetQuoteChanData)
+	calendars, err := tradingcalendar.Load(config.AppConfig.TradingCalendarDir)
+	if err != nil {
+		log.Fatalf("Failed to load trading calendars: %v", err)
+	}
+
+	a.quoteChan = make(chan asset.AssetQuoteChanData, config.AppConfig.QuoteQueueSize)
 	assetRepo := asset.NewRepository(a.db)
-	a.assetService = asset.NewService(assetRepo, assetQuoteChan)
-	go a.assetService.AssetQuoteChanDataConsumer()
+	a.assetService = asset.NewService(assetRepo, calendars, config.AppConfig.QuoteCacheTTL, a.quoteChan)
 
-	a.assetQuoteFeederService = assetquotefeeder.NewService(a.assetService, a.paramService, assetQuoteChan)
+	a.quoteProviders = newQuoteProviderRegistry()
+	a.assetQuoteFeederService = assetquotefeeder.NewService(a.assetService, a.paramService, a.quoteProviders, calendars, assetquotefeeder.ValidationConfig{
+		StaleAfter: config.AppConfig.QuoteStaleAfter,
+		Tolerance:  config.AppConfig.QuoteAnomalyTolerance,
+	}, assetquotefeeder.ScrapeConfig{
+		Concurrency:      config.AppConfig.QuoteScrapeConcurrency,
+		FailureThreshold: config.AppConfig.QuoteFailureThreshold,
+	}, a.quoteChan)
 
-	transactionRepo := transaction.NewRepository(a.db)
-	a.transactionService = transaction.NewService(transactionRepo, a.assetService)
+	notificationRepo := notification.NewRepository(a.db)
+	a.notificationService = notification.NewService(notificationRepo)
 
-	portfolioRepo := portfolio.NewRepository(a.db)
-	a.portfolioService = portfolio.NewService(portfolioRepo, a.transactionService, a.assetService)
+	assetCatalogRepo := assetcatalog.NewRepository(a.db)
+	a.assetCatalogService = assetcatalog.NewService(assetCatalogRepo, a.assetService, a.assetQuoteFeederService, a.notificationService, config.AppConfig.AssetRequestAutoApprove)
+
+	marketListRepo := marketlist.NewRepository(a.db)
+	a.marketListService = marketlist.NewService(marketListRepo, a.assetService)
+
+	watchlistRepo := watchlist.NewRepository(a.db)
+	a.watchlistService = watchlist.NewService(watchlistRepo, a.assetService)
+
+	quoteBackfillRepo := quotebackfill.NewRepository(a.db)
+	a.quoteBackfillService = quotebackfill.NewService(quoteBackfillRepo, a.assetService, a.assetQuoteFeederService, calendars, backfillLookbackDays(), config.AppConfig.BackfillMaxAttempts)
+
+	quoteRetentionRepo := quoteretention.NewRepository(a.db)
+	a.quoteRetentionService = quoteretention.NewService(quoteRetentionRepo, quoteretention.Policies(config.AppConfig.QuoteRetentionDays, config.AppConfig.QuoteDownsample))
+
+	a.liveQuoteService = livequote.NewService(a.assetService, a.quoteProviders, config.AppConfig.LiveQuotePollInterval)
+
+	a.fxService = fx.NewService(a.assetService)
 
 	// Initialize auth services
 	rsaKeys, err := auth.NewRSAKeysFromByte([]byte(config.AppConfig.PrivateKey), []byte(config.AppConfig.PublicKey))
@@ -92,13 +182,73 @@ func (a *App) initServices() {
 
 	a.tokenService = auth.NewTokenService(rsaKeys, config.AppConfig.TokenDuration, googleValidator)
 
+	// Initialize user service
+	userRepo := user.NewRepository(a.db)
+	a.userService = user.NewService(userRepo, a.tokenService, a.fxService)
+
+	transactionRepo := transaction.NewRepository(a.db)
+	a.transactionService = transaction.NewService(transactionRepo, a.assetService, a.fxService)
+
+	portfolioRepo := portfolio.NewRepository(a.db)
+	a.portfolioService = portfolio.NewService(portfolioRepo, a.transactionService, a.assetService, a.userService, a.fxService, config.AppConfig.ValuationCacheTTL)
+	a.assetService.AddQuoteListener(a.portfolioService.OnQuote)
+
+	a.quoteStreamService = quotestream.NewService(quotestream.NewHub(), a.portfolioService)
+	a.assetService.AddQuoteListener(a.quoteStreamService.OnQuote)
+
+	alertRepo := alert.NewRepository(a.db)
+	a.alertService = alert.NewService(alertRepo, a.assetService, a.portfolioService)
+	a.assetService.AddQuoteListener(a.alertService.OnQuote)
+
 	// investment growth service
-	a.investmentGrowthService = investmentgrowth.NewService(a.portfolioService, a.assetService)
+	a.investmentGrowthService = investmentgrowth.NewService(a.portfolioService, a.assetService, a.fxService)
 	a.investmentGrowthHandler = investmentgrowth.NewHandler(a.investmentGrowthService)
 
-	// Initialize user service
-	userRepo := user.NewRepository(a.db)
-	a.userService = user.NewService(userRepo, a.tokenService)
+	// analytics service
+	a.analyticsService = analytics.NewService(a.portfolioService, a.assetService)
+
+	a.scheduler = scheduler.New(scheduler.NewSQLStore(a.db.DB), config.AppConfig.SchedulerLease)
+}
+
+// backfillLookbackDays keeps the gap scanner within the hourly retention,
+// otherwise it would backfill the bars the retention rolled up
+func backfillLookbackDays() int {
+	lookback := config.AppConfig.BackfillLookbackDays
+	if keep := config.AppConfig.QuoteRetentionDays[quoteprovider.IntervalOneHour]; keep > 0 && keep < lookback {
+		return keep
+	}
+	return lookback
+}
+
+func newQuoteProviderRegistry() *quoteprovider.Registry {
+	registry := quoteprovider.NewRegistry(config.AppConfig.QuoteProvider, config.AppConfig.QuoteProviderOverrides)
+	register := func(provider quoteprovider.QuoteProvider) {
+		if limit := config.AppConfig.QuoteProviderRateLimits[provider.Name()]; limit > 0 {
+			provider = quoteprovider.NewRateLimitedProvider(provider, limit)
+		}
+		registry.Register(provider)
+	}
+
+	register(quoteprovider.NewYahooProvider())
+	register(quoteprovider.NewCSVProvider(config.AppConfig.QuoteCSVDir))
+	if config.AppConfig.QuoteFakeProvider {
+		register(quoteprovider.NewFakeProvider(""))
+	}
+	if config.AppConfig.QuoteHTTPHistoryURL != "" {
+		register(quoteprovider.NewHTTPProvider(quoteprovider.HTTPProviderConfig{
+			HistoryURL: config.AppConfig.QuoteHTTPHistoryURL,
+			LatestURL:  config.AppConfig.QuoteHTTPLatestURL,
+			LookupURL:  config.AppConfig.QuoteHTTPLookupURL,
+			APIKey:     config.AppConfig.QuoteHTTPAPIKey,
+		}))
+	}
+
+	registry.SetFallbacks(config.AppConfig.QuoteFallbackProviders...)
+
+	if err := registry.Validate(); err != nil {
+		log.Fatalf("Failed to initialize quote providers: %v", err)
+	}
+	return registry
 }
 
 func (a *App) initHandlers() {
@@ -106,6 +256,16 @@ func (a *App) initHandlers() {
 	a.assetHandler = asset.NewHandler(a.assetService)
 	a.transactionHandler = transaction.NewHandler(a.transactionService)
 	a.userHandler = user.NewHandler(a.userService)
+	a.analyticsHandler = analytics.NewHandler(a.analyticsService)
+	a.assetCatalogHandler = assetcatalog.NewHandler(a.assetCatalogService)
+	a.notificationHandler = notification.NewHandler(a.notificationService)
+	a.marketListHandler = marketlist.NewHandler(a.marketListService)
+	a.watchlistHandler = watchlist.NewHandler(a.watchlistService)
+	a.alertHandler = alert.NewHandler(a.alertService)
+	a.quoteBackfillHandler = quotebackfill.NewHandler(a.quoteBackfillService)
+	a.quoteStreamHandler = quotestream.NewHandler(a.quoteStreamService)
+	a.jobHandler = job.NewHandler(a.scheduler)
+	a.cacheMetricsHandler = cachemetrics.NewHandler(a.assetService, a.portfolioService)
 }
 
 func (a *App) setupRoutes() {
@@ -119,6 +279,11 @@ func (a *App) setupRoutes() {
 	protected := api.Group("")
 	protected.Use(auth.JwtAuthMiddleware(a.tokenService))
 
+	protected.Put("/user/base-currency", a.userHandler.UpdateBaseCurrency)
+
+	protected.Get("/notification", a.notificationHandler.GetNotifications)
+	protected.Post("/notification/read", a.notificationHandler.MarkRead)
+
 	protected.Post("/portfolio", a.portfolioHandler.CreatePortfolio)
 	protected.Post("/portfolio/add-transaction", a.portfolioHandler.AddTransactionToPortfolio)
 	protected.Get("/portfolio/user-portfolios", a.portfolioHandler.GetUserPortfolios)
@@ -134,30 +299,221 @@ func (a *App) setupRoutes() {
 
 	protected.Get("/investment-growth/:symbol", a.investmentGrowthHandler.CalculateInvestmentGrowth)
 
+	protected.Get("/analytics/portfolio/:portfolioId/correlation", a.analyticsHandler.GetCorrelation)
+	protected.Get("/analytics/portfolio/:portfolioId/exposure", a.analyticsHandler.GetExposure)
+	protected.Get("/analytics/portfolio/:portfolioId/look-through", a.analyticsHandler.GetLookThrough)
+	protected.Get("/analytics/overlap", a.analyticsHandler.GetOverlap)
+
 	protected.Get("/asset", a.assetHandler.GetAsset)
-	protected.Get("/asset/market-overview", a.assetHandler.GetMarketOverview)
+	protected.Get("/asset/market-overview", a.marketListHandler.GetMarketOverview)
+	protected.Get("/market/overview", a.marketListHandler.GetOverview)
 	protected.Get("/asset/search", a.assetHandler.SearchAssets)
+	protected.Get("/asset/request", a.assetCatalogHandler.GetUserAssetRequests)
+	protected.Post("/asset/request", a.assetCatalogHandler.RequestAsset)
 
 	protected.Get("/asset/:assetId", a.assetHandler.GetAssets)
+	protected.Get("/asset/:assetId/constituents", a.assetHandler.GetConstituents)
+	protected.Get("/asset/:assetId/candles", a.assetHandler.GetCandles)
 
 	protected.Get("/transaction", a.transactionHandler.Get)
+
+	protected.Get("/watchlist", a.watchlistHandler.GetWatchlists)
+	protected.Post("/watchlist", a.watchlistHandler.CreateWatchlist)
+	protected.Get("/watchlist/:watchlistId", a.watchlistHandler.GetWatchlist)
+	protected.Put("/watchlist/:watchlistId", a.watchlistHandler.RenameWatchlist)
+	protected.Delete("/watchlist/:watchlistId", a.watchlistHandler.DeleteWatchlist)
+	protected.Post("/watchlist/:watchlistId/asset", a.watchlistHandler.AddAsset)
+	protected.Delete("/watchlist/:watchlistId/asset/:assetId", a.watchlistHandler.RemoveAsset)
+	protected.Put("/watchlist/:watchlistId/order", a.watchlistHandler.ReorderAssets)
+
+	protected.Get("/alert", a.alertHandler.GetAlerts)
+	protected.Post("/alert", a.alertHandler.CreateAlert)
+	protected.Get("/alert/inbox", a.alertHandler.GetInbox)
+	protected.Post("/alert/inbox/read", a.alertHandler.MarkRead)
+	protected.Put("/alert/:alertId", a.alertHandler.UpdateAlert)
+	protected.Delete("/alert/:alertId", a.alertHandler.DeleteAlert)
+
+	protected.Get("/stream", a.quoteStreamHandler.Stream)
+	protected.Get("/stream/ws", a.quoteStreamHandler.UpgradeWebSocket, a.quoteStreamHandler.WebSocket())
+
+	admin := protected.Group("/admin")
+	admin.Use(auth.AdminMiddleware(config.AppConfig.AdminEmails))
+
+	admin.Post("/asset", a.assetCatalogHandler.CreateAsset)
+	admin.Put("/asset/:assetId", a.assetHandler.UpdateAsset)
+	admin.Post("/asset/:assetId/delist", a.assetHandler.DelistAsset)
+	admin.Post("/asset/:assetId/relist", a.assetHandler.RelistAsset)
+	admin.Put("/asset/:assetId/metadata", a.assetHandler.UpdateAssetMetadata)
+	admin.Get("/asset/quote-failures", a.assetHandler.GetQuoteFailures)
+	admin.Post("/asset/:assetId/resume-quotes", a.assetHandler.ResumeQuotes)
+
+	admin.Get("/asset-request", a.assetCatalogHandler.GetAssetRequests)
+	admin.Post("/asset-request/:requestId/approve", a.assetCatalogHandler.ApproveAssetRequest)
+	admin.Post("/asset-request/:requestId/reject", a.assetCatalogHandler.RejectAssetRequest)
+	admin.Post("/asset/:assetId/constituents", a.assetHandler.LoadConstituents)
+
+	admin.Get("/market-list", a.marketListHandler.GetMarketLists)
+	admin.Post("/market-list", a.marketListHandler.CreateMarketList)
+	admin.Put("/market-list/:listId", a.marketListHandler.UpdateMarketList)
+	admin.Delete("/market-list/:listId", a.marketListHandler.DeleteMarketList)
+	admin.Put("/market-list/:listId/assets", a.marketListHandler.SetListAssets)
+
+	admin.Get("/quote-coverage", a.quoteBackfillHandler.GetCoverage)
+	admin.Post("/quote-coverage/scan", a.quoteBackfillHandler.ScanGaps)
+	admin.Get("/backfill-job", a.quoteBackfillHandler.GetJobs)
+
+	admin.Get("/ingestion/metrics", a.assetHandler.GetIngestionMetrics)
+	admin.Get("/cache/metrics", a.cacheMetricsHandler.GetMetrics)
+
+	admin.Get("/quote-anomaly", a.assetHandler.GetQuoteAnomalies)
+	admin.Post("/quote-anomaly/:anomalyId/release", a.assetHandler.ReleaseQuoteAnomaly)
+	admin.Post("/quote-anomaly/:anomalyId/discard", a.assetHandler.DiscardQuoteAnomaly)
+
+	admin.Get("/job", a.jobHandler.GetJobs)
+	admin.Get("/job/:name/run", a.jobHandler.GetRuns)
+	admin.Post("/job/:name/trigger", a.jobHandler.TriggerJob)
+	admin.Post("/job/:name/pause", a.jobHandler.PauseJob)
+	admin.Post("/job/:name/resume", a.jobHandler.ResumeJob)
+	admin.Get("/job-run/:runId", a.jobHandler.GetRun)
 }
 
 func (a *App) setupScheduler() {
-	a.scheduler.Add("daily quote data insert", 2, 22, 0, func() {
-		err := a.assetQuoteFeederService.ScrapeAllAssets(goyhfin.OneDay, goyhfin.OneHour)
-		if err != nil {
-			// Consider using a proper logging package here
-			println("error running ScrapeAllAssets:", err.Error())
+	err := a.scheduler.Add(scheduler.Job{
+		Name:         "daily-quote-insert",
+		Cron:         config.AppConfig.DailyQuoteCron,
+		Timezone:     config.AppConfig.SchedulerTimezone,
+		CatchUp:      config.AppConfig.DailyQuoteCatchUp,
+		Retries:      config.AppConfig.JobRetries,
+		RetryBackoff: config.AppConfig.JobRetryBackoff,
+		Run: func(ctx context.Context) error {
+			now := time.Now()
+			var runErr error
+			results, err := a.assetQuoteFeederService.ScrapeAllAssets(ctx, now.AddDate(0, 0, -1), now, quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay)
+			for _, result := range results {
+				scheduler.ReportItem(ctx, result.Symbol+" "+result.Interval, result.Err)
+			}
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				runErr = fmt.Errorf("quotes: %w", err)
+			} else if failed := assetquotefeeder.FailedResults(results); len(results) > 0 && len(failed) == len(results) {
+				// single failing symbols are left to the gap scanner, a retry is only worth it when all failed
+				runErr = fmt.Errorf("quotes: all %d scrapes failed, last error: %w", len(failed), failed[len(failed)-1].Err)
+			}
+			a.quoteBackfillService.ScanAndBackfill(ctx, config.AppConfig.BackfillBatchSize)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return runErr
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule jobs: %v", err)
+	}
+
+	err = a.scheduler.Add(scheduler.Job{
+		Name:         "quote-retention",
+		Cron:         config.AppConfig.QuoteRetentionCron,
+		Timezone:     config.AppConfig.SchedulerTimezone,
+		Retries:      config.AppConfig.JobRetries,
+		RetryBackoff: config.AppConfig.JobRetryBackoff,
+		Run: func(ctx context.Context) error {
+			results, err := a.quoteRetentionService.Apply(ctx, time.Now())
+			for _, result := range results {
+				scheduler.ReportItem(ctx, result.Interval, nil)
+			}
+			return err
+		},
+	})
+	if err != nil {
+		log.Fatalf("Failed to schedule jobs: %v", err)
+	}
+}
+
+// setupShutdown orders the shutdown: clients and producers stop before the queued quotes
+// are drained, and the database is closed last
+func (a *App) setupShutdown() {
+	a.lifecycle.OnShutdown("quote stream", func(ctx context.Context) error {
+		a.quoteStreamService.Stop()
+		return nil
+	})
+	a.lifecycle.OnShutdown("http server", a.fiberApp.ShutdownWithContext)
+	a.lifecycle.OnShutdown("scheduler", a.scheduler.Stop)
+	a.lifecycle.OnShutdown("live quotes", a.liveQuoteService.Stop)
+	a.lifecycle.OnShutdown("initial quote load", func(ctx context.Context) error {
+		if a.stopInitialLoad != nil {
+			a.stopInitialLoad()
+		}
+		return lifecycle.Wait(ctx, a.initialLoad.Wait)
+	})
+	a.lifecycle.OnShutdown("asset backfills", a.assetCatalogService.Stop)
+	a.lifecycle.OnShutdown("quote feeder", func(ctx context.Context) error {
+		if err := a.assetQuoteFeederService.Stop(ctx); err != nil {
+			return err
+		}
+		a.feederStopped = true
+		return nil
+	})
+	a.lifecycle.OnShutdown("quote ingestion", func(ctx context.Context) error {
+		// closing the channel while the feeder still sends would panic
+		if !a.feederStopped {
+			return errors.New("quote feeder is still running, queued quotes are not drained")
+		}
+		close(a.quoteChan)
+		if a.quoteIngestion == nil {
+			return nil
 		}
+		return lifecycle.Wait(ctx, a.quoteIngestion.Wait)
+	})
+	a.lifecycle.OnShutdown("price alerts", a.alertService.Stop)
+	a.lifecycle.OnShutdown("database", func(ctx context.Context) error {
+		return a.db.Close()
 	})
 }
 
+// startInitialLoad loads the initial quotes in the background, the shutdown cancels it
+// before stopping the feeder
+func (a *App) startInitialLoad() {
+	ctx, cancel := context.WithCancel(context.Background())
+	a.stopInitialLoad = cancel
+	a.initialLoad.Add(1)
+	go func() {
+		defer a.initialLoad.Done()
+		if err := a.assetQuoteFeederService.InsertInitialData(ctx); err != nil {
+			log.Printf("Initial quote load stopped: %v", err)
+		}
+	}()
+}
+
+// Run serves until SIGINT or SIGTERM, then shuts the app down gracefully
 func (a *App) Run() error {
-	a.assetQuoteFeederService.InsertInitialData()
+	a.lifecycle.ListenForSignals()
+	a.quoteIngestion = a.assetService.StartQuoteIngestion(asset.IngestionConfig{
+		Workers:       config.AppConfig.QuoteIngestWorkers,
+		BatchSize:     config.AppConfig.QuoteIngestBatchSize,
+		FlushInterval: config.AppConfig.QuoteIngestFlushInterval,
+	})
+	a.startInitialLoad()
 	a.scheduler.Start()
-	log.Printf("Starting server on port %s", config.AppConfig.ServerPort)
-	return a.fiberApp.Listen(":" + config.AppConfig.ServerPort)
+	a.liveQuoteService.Start()
+	a.quoteStreamService.Start()
+	a.alertService.Start()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Starting server on port %s", config.AppConfig.ServerPort)
+		serverErr <- a.fiberApp.Listen(":" + config.AppConfig.ServerPort)
+	}()
+
+	err := a.lifecycle.WaitForSignal(serverErr)
+	if err != nil {
+		log.Printf("Server stopped: %v", err)
+	}
+	if shutdownErr := a.lifecycle.Shutdown(config.AppConfig.ShutdownTimeout); err == nil {
+		err = shutdownErr
+	}
+	return err
 }
 
 func createFiberApp() *fiber.App {
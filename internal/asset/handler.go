@@ -1,11 +1,16 @@
 package asset
 
 import (
+	"bytes"
 	"database/sql"
+	"errors"
+	"io"
 	"strconv"
+	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
 )
 
 type Handler struct {
@@ -44,15 +49,160 @@ func (h *Handler) GetAsset(c *fiber.Ctx) error {
 	return c.JSON(asset)
 }
 
-func (h *Handler) GetMarketOverview(c *fiber.Ctx) error {
-	resp, err := h.service.GetMarketOverview()
+func (h *Handler) UpdateAsset(c *fiber.Ctx) error {
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	var request UpdateAssetRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if err := request.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	asset, err := h.service.UpdateAsset(int64(assetId), request)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update asset"})
+	}
+
+	return c.JSON(asset)
+}
+
+func (h *Handler) GetQuoteFailures(c *fiber.Ctx) error {
+	assets, err := h.service.GetQuoteFailures()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch quote failures"})
+	}
+	return c.JSON(assets)
+}
+
+func (h *Handler) ResumeQuotes(c *fiber.Ctx) error {
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	asset, err := h.service.ResumeQuotes(int64(assetId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+		}
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resume asset quotes"})
+	}
+	return c.JSON(asset)
+}
+
+func (h *Handler) DelistAsset(c *fiber.Ctx) error {
+	return h.setDelisted(c, true)
+}
+
+func (h *Handler) RelistAsset(c *fiber.Ctx) error {
+	return h.setDelisted(c, false)
+}
+
+func (h *Handler) setDelisted(c *fiber.Ctx, delisted bool) error {
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	var asset *Asset
+	if delisted {
+		asset, err = h.service.DelistAsset(int64(assetId))
+	} else {
+		asset, err = h.service.RelistAsset(int64(assetId))
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update asset listing"})
+	}
+
+	return c.JSON(asset)
+}
+
+func (h *Handler) UpdateAssetMetadata(c *fiber.Ctx) error {
+	assetId, err := c.ParamsInt("assetId")
 	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	var request UpdateAssetMetadataRequest
+	if err := c.BodyParser(&request); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if err := request.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	asset, err := h.service.UpdateAssetMetadata(int64(assetId), request)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+		}
 		log.Error(err)
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Failed to fetch market overview",
-		})
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update asset metadata"})
 	}
-	return c.JSON(resp)
+
+	return c.JSON(asset)
+}
+
+func (h *Handler) GetConstituents(c *fiber.Ctx) error {
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	constituents, err := h.service.GetConstituents(int64(assetId))
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch constituents"})
+	}
+
+	return c.JSON(fiber.Map{"constituents": constituents})
+}
+
+// LoadConstituents accepts the csv either as a multipart file named "file" or as the raw request body
+func (h *Handler) LoadConstituents(c *fiber.Ctx) error {
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	var reader io.Reader = bytes.NewReader(c.Body())
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Could not read uploaded file"})
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	count, err := h.service.LoadConstituentsCSV(int64(assetId), reader)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+		}
+		if errors.Is(err, InvalidConstituentCSVErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to load constituents"})
+	}
+
+	return c.JSON(fiber.Map{"loaded": count})
 }
 
 func (h *Handler) SearchAssets(c *fiber.Ctx) error {
@@ -84,3 +234,89 @@ func (h *Handler) SearchAssets(c *fiber.Ctx) error {
         "total_pages": (totalCount + limit - 1) / limit,
     })
 }
+
+func (h *Handler) GetQuoteAnomalies(c *fiber.Ctx) error {
+	anomalies, err := h.service.GetQuoteAnomalies(c.Query("status", AnomalyStatusQuarantined))
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get quote anomalies"})
+	}
+	return c.JSON(anomalies)
+}
+
+func (h *Handler) ReleaseQuoteAnomaly(c *fiber.Ctx) error {
+	return h.resolveQuoteAnomaly(c, h.service.ReleaseQuoteAnomaly)
+}
+
+func (h *Handler) DiscardQuoteAnomaly(c *fiber.Ctx) error {
+	return h.resolveQuoteAnomaly(c, h.service.DiscardQuoteAnomaly)
+}
+
+func (h *Handler) resolveQuoteAnomaly(c *fiber.Ctx, resolve func(int64) (*QuoteAnomaly, error)) error {
+	anomalyId, err := c.ParamsInt("anomalyId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Anomaly ID"})
+	}
+
+	anomaly, err := resolve(int64(anomalyId))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Quote anomaly not found"})
+		}
+		if err == QuoteAnomalyNotQuarantinedErr {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to resolve quote anomaly"})
+	}
+
+	return c.JSON(anomaly)
+}
+
+func (h *Handler) GetCandles(c *fiber.Ctx) error {
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	from, err := parseTimeQuery(c.Query("from"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid from, use YYYY-MM-DD or RFC3339"})
+	}
+	to, err := parseTimeQuery(c.Query("to"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid to, use YYYY-MM-DD or RFC3339"})
+	}
+	if !from.IsZero() && !to.IsZero() && to.Before(from) {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "to must be after from"})
+	}
+
+	candles, err := h.service.GetCandles(int64(assetId), c.Query("interval", quoteprovider.IntervalOneDay), from, to)
+	if err != nil {
+		if err == UnsupportedIntervalErr {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "interval must be 1h or 1d"})
+		}
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch candles"})
+	}
+
+	return c.JSON(candles)
+}
+
+// parseTimeQuery accepts a date or an RFC3339 time, an empty value is the zero time
+func parseTimeQuery(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func (h *Handler) GetIngestionMetrics(c *fiber.Ctx) error {
+	return c.JSON(h.service.GetIngestionMetrics())
+}
@@ -0,0 +1,226 @@
+package asset
+
+import (
+	"database/sql"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// IngestionConfig sizes the workers saving the quotes sent to the quote channel
+type IngestionConfig struct {
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+type IngestionMetrics struct {
+	QueueDepth         int     `json:"queue_depth"`
+	QueueCapacity      int     `json:"queue_capacity"`
+	Received           int64   `json:"received"`
+	Saved              int64   `json:"saved"`
+	Quarantined        int64   `json:"quarantined"`
+	Failed             int64   `json:"failed"`
+	Batches            int64   `json:"batches"`
+	LastBatchSize      int64   `json:"last_batch_size"`
+	LastBatchMillis    int64   `json:"last_batch_millis"`
+	SavedPerSecond     float64 `json:"saved_per_second"`
+	LastBatchPerSecond float64 `json:"last_batch_per_second"`
+}
+
+type ingestionMetrics struct {
+	startedAt       time.Time
+	received        atomic.Int64
+	saved           atomic.Int64
+	quarantined     atomic.Int64
+	failed          atomic.Int64
+	batches         atomic.Int64
+	lastBatchSize   atomic.Int64
+	lastBatchMicros atomic.Int64
+}
+
+// QuoteAck lets the sender of a group of quotes wait until the ingestion saved or quarantined
+// all of them, a nil QuoteAck ignores every call
+type QuoteAck struct {
+	wg  sync.WaitGroup
+	mu  sync.Mutex
+	err error
+}
+
+func NewQuoteAck() *QuoteAck {
+	return &QuoteAck{}
+}
+
+// Add counts n more quotes to wait for, it has to be called before they're sent
+func (a *QuoteAck) Add(n int) {
+	if a != nil {
+		a.wg.Add(n)
+	}
+}
+
+func (a *QuoteAck) done(err error) {
+	if a == nil {
+		return
+	}
+	if err != nil {
+		a.mu.Lock()
+		if a.err == nil {
+			a.err = err
+		}
+		a.mu.Unlock()
+	}
+	a.wg.Done()
+}
+
+// Wait blocks until every quote is handled and returns the first error saving them
+func (a *QuoteAck) Wait() error {
+	if a == nil {
+		return nil
+	}
+	a.wg.Wait()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.err
+}
+
+// StartQuoteIngestion runs the workers until the quote channel is closed. A full channel blocks
+// the feeder, so producers slow down to the pace the database accepts.
+func (s *Service) StartQuoteIngestion(config IngestionConfig) *sync.WaitGroup {
+	workers := max(config.Workers, 1)
+	flushInterval := config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = time.Second
+	}
+	s.metrics.startedAt = time.Now()
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.ingestQuotes(max(config.BatchSize, 1), flushInterval)
+		}()
+	}
+	log.Infof("Started %d quote ingestion workers", workers)
+	return &wg
+}
+
+// ingestQuotes saves a batch once it is full or the flush interval passed
+func (s *Service) ingestQuotes(batchSize int, flushInterval time.Duration) {
+	batch := make([]AssetQuoteChanData, 0, batchSize)
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case quote, ok := <-s.quoteReceiver:
+			if !ok {
+				s.flushQuotes(batch)
+				return
+			}
+			s.metrics.received.Add(1)
+			batch = append(batch, quote)
+			if len(batch) >= batchSize {
+				s.flushQuotes(batch)
+				batch = batch[:0]
+			}
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.flushQuotes(batch)
+				batch = batch[:0]
+			}
+		}
+	}
+}
+
+func (s *Service) flushQuotes(batch []AssetQuoteChanData) {
+	if len(batch) == 0 {
+		return
+	}
+
+	quotes := make([]AssetQuote, 0, len(batch))
+	accepted := make([]AssetQuoteChanData, 0, len(batch))
+	for _, quote := range batch {
+		if quote.Anomaly == nil && quote.Quote <= 0 {
+			quote.Anomaly = &QuoteAnomaly{Reason: AnomalyReasonNonPositive}
+		}
+		if quote.Anomaly != nil {
+			if err := s.quarantineQuote(quote); err != nil {
+				log.Errorf("Error quarantining quote of %s: %v", quote.Symbol, err)
+				s.metrics.failed.Add(1)
+				quote.Ack.done(err)
+				continue
+			}
+			s.metrics.quarantined.Add(1)
+			quote.Ack.done(nil)
+			continue
+		}
+		quotes = append(quotes, toAssetQuote(quote))
+		accepted = append(accepted, quote)
+	}
+	if len(quotes) == 0 {
+		return
+	}
+
+	start := time.Now()
+	if err := s.repo.SaveAssetQuotes(quotes); err != nil {
+		log.Errorf("Error saving %d quotes: %v", len(quotes), err)
+		s.metrics.failed.Add(int64(len(quotes)))
+		for _, quote := range accepted {
+			quote.Ack.done(err)
+		}
+		return
+	}
+	s.metrics.saved.Add(int64(len(quotes)))
+	s.metrics.batches.Add(1)
+	s.metrics.lastBatchSize.Store(int64(len(quotes)))
+	s.metrics.lastBatchMicros.Store(time.Since(start).Microseconds())
+
+	invalidated := make(map[int64]bool)
+	for _, quote := range accepted {
+		if !invalidated[quote.AssetId] {
+			s.invalidateQuotes(quote.AssetId)
+			invalidated[quote.AssetId] = true
+		}
+		s.notifyQuoteListeners(quote)
+		quote.Ack.done(nil)
+	}
+}
+
+func (s *Service) GetIngestionMetrics() IngestionMetrics {
+	metrics := IngestionMetrics{
+		QueueDepth:      len(s.quoteReceiver),
+		QueueCapacity:   cap(s.quoteReceiver),
+		Received:        s.metrics.received.Load(),
+		Saved:           s.metrics.saved.Load(),
+		Quarantined:     s.metrics.quarantined.Load(),
+		Failed:          s.metrics.failed.Load(),
+		Batches:         s.metrics.batches.Load(),
+		LastBatchSize:   s.metrics.lastBatchSize.Load(),
+		LastBatchMillis: s.metrics.lastBatchMicros.Load() / 1000,
+	}
+	if !s.metrics.startedAt.IsZero() {
+		if elapsed := time.Since(s.metrics.startedAt).Seconds(); elapsed > 0 {
+			metrics.SavedPerSecond = float64(metrics.Saved) / elapsed
+		}
+	}
+	if micros := s.metrics.lastBatchMicros.Load(); micros > 0 {
+		metrics.LastBatchPerSecond = float64(metrics.LastBatchSize) / (float64(micros) / 1e6)
+	}
+	return metrics
+}
+
+func toAssetQuote(quote AssetQuoteChanData) AssetQuote {
+	return AssetQuote{
+		AssetId:   quote.AssetId,
+		Interval:  quoteInterval(quote.Interval),
+		Open:      ToNullPrice(quote.Open),
+		High:      ToNullPrice(quote.High),
+		Low:       ToNullPrice(quote.Low),
+		Quote:     quote.Quote,
+		Volume:    sql.NullFloat64{Float64: quote.Volume, Valid: true},
+		QuoteTime: quote.QuoteTime,
+	}
+}
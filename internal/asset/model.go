@@ -2,35 +2,171 @@ package asset
 
 import (
 	"database/sql"
+	"errors"
+	"strings"
 	"time"
 )
 
+var AssetExistsErr error = errors.New("asset already exists")
+var UnsupportedIntervalErr error = errors.New("unsupported quote interval")
+var NoPreviousTradingDayQuoteErr error = errors.New("no previous trading day quote found")
+var QuoteAnomalyNotQuarantinedErr error = errors.New("quote anomaly is not quarantined")
+
+// SnapshotInterval marks quotes built from an intraday snapshot instead of a bar
+const SnapshotInterval = "live"
+
+// week52Period is how far back a 52 week range reaches
+const week52Period = 52 * 7 * 24 * time.Hour
+
+const (
+	AnomalyReasonDiscrepancy = "source_discrepancy"
+	AnomalyReasonNonPositive = "non_positive_quote"
+
+	AnomalyStatusQuarantined = "quarantined"
+	AnomalyStatusReleased    = "released"
+	AnomalyStatusDiscarded   = "discarded"
+)
+
 type Asset struct {
 	Id          int64          `db:"id" json:"id"`
 	Name        string         `db:"name" json:"name"`
 	Symbol      string         `db:"symbol" json:"symbol"`
 	Description sql.NullString `db:"description" json:"description"`
+	Sector      sql.NullString `db:"sector" json:"sector"`
+	Industry    sql.NullString `db:"industry" json:"industry"`
+	AssetClass  sql.NullString `db:"asset_class" json:"asset_class"`
+	Country     sql.NullString `db:"country" json:"country"`
+	Currency    sql.NullString `db:"currency" json:"currency"`
+	Exchange    sql.NullString `db:"exchange" json:"exchange"`
+	DelistedAt  sql.NullTime   `db:"delisted_at" json:"delisted_at"`
+
+	// QuoteFailureCount counts the consecutive failed scrapes, once it reaches the threshold
+	// the asset is suspended from quote updates until an admin resumes it
+	QuoteFailureCount  int            `db:"quote_failure_count" json:"quote_failure_count"`
+	LastQuoteError     sql.NullString `db:"last_quote_error" json:"last_quote_error"`
+	LastQuoteFailureAt sql.NullTime   `db:"last_quote_failure_at" json:"last_quote_failure_at"`
+	QuoteSuspendedAt   sql.NullTime   `db:"quote_suspended_at" json:"quote_suspended_at"`
 }
 
 type SimpleAssetDTO struct {
-	Id     int64  `db:"id" json:"id"`
-	Name   string `db:"name" json:"name"`
-	Symbol string `db:"symbol" json:"symbol"`
+	Id       int64  `db:"id" json:"id"`
+	Name     string `db:"name" json:"name"`
+	Symbol   string `db:"symbol" json:"symbol"`
+	Currency string `db:"currency" json:"currency,omitempty"`
+	Exchange string `db:"exchange" json:"exchange,omitempty"`
 }
 
+// AssetQuote is an OHLCV bar of an asset, Quote is its close
 type AssetQuote struct {
-	Id        int64     `db:"id" json:"id"`
-	AssetId   int64     `db:"asset_id" json:"asset_id"`
-	Quote     float64   `db:"quote" json:"quote"`
-	QuoteTime time.Time `db:"quote_time" json:"quote_time"`
-	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	Id        int64           `db:"id" json:"id"`
+	AssetId   int64           `db:"asset_id" json:"asset_id"`
+	Interval  string          `db:"interval" json:"interval"`
+	Open      sql.NullFloat64 `db:"open" json:"open"`
+	High      sql.NullFloat64 `db:"high" json:"high"`
+	Low       sql.NullFloat64 `db:"low" json:"low"`
+	Quote     float64         `db:"quote" json:"quote"`
+	Volume    sql.NullFloat64 `db:"volume" json:"volume"`
+	QuoteTime time.Time       `db:"quote_time" json:"quote_time"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+// QuoteSnapshot is an intraday price, kept apart from the bars so it doesn't end up in the history
+type QuoteSnapshot struct {
+	Id        int64           `db:"id" json:"id"`
+	AssetId   int64           `db:"asset_id" json:"asset_id"`
+	Symbol    string          `db:"-" json:"-"`
+	Price     float64         `db:"price" json:"price"`
+	Open      sql.NullFloat64 `db:"open" json:"open"`
+	High      sql.NullFloat64 `db:"high" json:"high"`
+	Low       sql.NullFloat64 `db:"low" json:"low"`
+	Volume    sql.NullFloat64 `db:"volume" json:"volume"`
+	Provider  string          `db:"provider" json:"provider"`
+	QuoteTime time.Time       `db:"quote_time" json:"quote_time"`
+	CreatedAt time.Time       `db:"created_at" json:"created_at"`
+}
+
+func (s QuoteSnapshot) toQuote() AssetQuote {
+	return AssetQuote{
+		AssetId:   s.AssetId,
+		Interval:  SnapshotInterval,
+		Open:      s.Open,
+		High:      s.High,
+		Low:       s.Low,
+		Quote:     s.Price,
+		Volume:    s.Volume,
+		QuoteTime: s.QuoteTime,
+		CreatedAt: s.CreatedAt,
+	}
+}
+
+// PriceRange is the lowest and highest price of an asset over a period
+type PriceRange struct {
+	AssetId int64   `db:"asset_id" json:"asset_id"`
+	Low     float64 `db:"low" json:"low"`
+	High    float64 `db:"high" json:"high"`
+}
+
+// PreviousCloseRequest is an asset whose quote of the trading day before Time is looked up
+type PreviousCloseRequest struct {
+	AssetId  int64
+	Exchange string
+	Time     time.Time
+}
+
+type Candle struct {
+	Time   time.Time `db:"quote_time" json:"time"`
+	Open   float64   `db:"open" json:"open"`
+	High   float64   `db:"high" json:"high"`
+	Low    float64   `db:"low" json:"low"`
+	Close  float64   `db:"quote" json:"close"`
+	Volume float64   `db:"volume" json:"volume"`
+}
+
+type CandlesResponse struct {
+	AssetId  int64     `json:"asset_id"`
+	Symbol   string    `json:"symbol"`
+	Interval string    `json:"interval"`
+	From     time.Time `json:"from"`
+	To       time.Time `json:"to"`
+	Candles  []Candle  `json:"candles"`
 }
 
 type AssetQuoteChanData struct {
 	Symbol    string
 	AssetId   int64
+	Interval  string
+	Open      float64
+	High      float64
+	Low       float64
 	Quote     float64
+	Volume    float64
 	QuoteTime time.Time
+	Provider  string
+	// Anomaly is set when the quote looks suspicious, it is quarantined instead of saved
+	Anomaly *QuoteAnomaly
+	// Ack, when set, is told once the quote is saved or quarantined
+	Ack *QuoteAck
+}
+
+// QuoteAnomaly is a quote held back from asset_quote until an admin releases or discards it
+type QuoteAnomaly struct {
+	Id                int64           `db:"id" json:"id"`
+	AssetId           int64           `db:"asset_id" json:"asset_id"`
+	QuoteTime         time.Time       `db:"quote_time" json:"quote_time"`
+	Open              sql.NullFloat64 `db:"open" json:"open"`
+	High              sql.NullFloat64 `db:"high" json:"high"`
+	Low               sql.NullFloat64 `db:"low" json:"low"`
+	Quote             float64         `db:"quote" json:"quote"`
+	Volume            sql.NullFloat64 `db:"volume" json:"volume"`
+	Provider          string          `db:"provider" json:"provider"`
+	ReferenceProvider sql.NullString  `db:"reference_provider" json:"reference_provider"`
+	ReferenceQuote    sql.NullFloat64 `db:"reference_quote" json:"reference_quote"`
+	Deviation         sql.NullFloat64 `db:"deviation" json:"deviation"`
+	Interval          string          `db:"interval" json:"interval"`
+	Reason            string          `db:"reason" json:"reason"`
+	Status            string          `db:"status" json:"status"`
+	ResolvedAt        sql.NullTime    `db:"resolved_at" json:"resolved_at"`
+	CreatedAt         time.Time       `db:"created_at" json:"created_at"`
 }
 
 type MarketGrowthListResponse struct {
@@ -41,3 +177,43 @@ type MarketGrowthListResponse struct {
 	Change float64 `json:"change"`
 	Amount float64 `json:"amount"`
 }
+
+type AssetConstituent struct {
+	Id                 int64          `db:"id" json:"id"`
+	AssetId            int64          `db:"asset_id" json:"asset_id"`
+	ConstituentAssetId sql.NullInt64  `db:"constituent_asset_id" json:"constituent_asset_id"`
+	Symbol             string         `db:"symbol" json:"symbol"`
+	Name               sql.NullString `db:"name" json:"name"`
+	Weight             float64        `db:"weight" json:"weight"`
+	Sector             sql.NullString `db:"sector" json:"sector"`
+	Country            sql.NullString `db:"country" json:"country"`
+	CreatedAt          time.Time      `db:"created_at" json:"created_at"`
+}
+
+type UpdateAssetRequest struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+func (r *UpdateAssetRequest) validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+type UpdateAssetMetadataRequest struct {
+	Sector     string `json:"sector"`
+	Industry   string `json:"industry"`
+	AssetClass string `json:"asset_class"`
+	Country    string `json:"country"`
+	Currency   string `json:"currency"`
+	Exchange   string `json:"exchange"`
+}
+
+func (r *UpdateAssetMetadataRequest) validate() error {
+	if r.Currency != "" && len(r.Currency) != 3 {
+		return errors.New("currency must be a 3 letter ISO code")
+	}
+	return nil
+}
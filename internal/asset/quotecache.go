@@ -0,0 +1,41 @@
+package asset
+
+import (
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/pkg/cache"
+)
+
+// previousCloseKey identifies the previous close of an asset by the end of its trading day
+type previousCloseKey struct {
+	assetId int64
+	dayEnd  int64
+}
+
+// quoteCache keeps the latest and previous close quotes read by the portfolio and market lists,
+// the ingestion path drops the entries of an asset when its quotes change
+type quoteCache struct {
+	latest        *cache.Cache[int64, AssetQuote]
+	previousClose *cache.Cache[previousCloseKey, AssetQuote]
+}
+
+func newQuoteCache(ttl time.Duration) quoteCache {
+	return quoteCache{
+		latest:        cache.New[int64, AssetQuote](ttl),
+		previousClose: cache.New[previousCloseKey, AssetQuote](ttl),
+	}
+}
+
+func (s *Service) invalidateQuotes(assetId int64) {
+	s.quoteCache.latest.Delete(assetId)
+	s.quoteCache.previousClose.DeleteFunc(func(key previousCloseKey, _ AssetQuote) bool {
+		return key.assetId == assetId
+	})
+}
+
+func (s *Service) GetCacheMetrics() map[string]cache.Stats {
+	return map[string]cache.Stats{
+		"latest_quote":   s.quoteCache.latest.Stats(),
+		"previous_close": s.quoteCache.previousClose.Stats(),
+	}
+}
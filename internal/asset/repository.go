@@ -1,12 +1,15 @@
 package asset
 
 import (
+	"database/sql"
 	"fmt"
+	"sort"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
 	"github.com/lib/pq"
 )
 
@@ -33,6 +36,91 @@ func (r *Repository) GetAssets() ([]SimpleAssetDTO, error) {
 	return assets, nil
 }
 
+func (r *Repository) GetActiveAssets() ([]SimpleAssetDTO, error) {
+	query := `
+        SELECT id, name, symbol, COALESCE(exchange, '') AS exchange
+        FROM asset
+        WHERE delisted_at IS NULL AND quote_suspended_at IS NULL
+    `
+	var assets []SimpleAssetDTO
+	err := r.db.Select(&assets, query)
+	if err != nil {
+		log.Errorf("Error fetching active assets: %v", err)
+		return nil, err
+	}
+
+	return assets, nil
+}
+
+// RecordQuoteFailure counts a failed scrape and suspends the asset once the count reaches
+// threshold, a threshold of zero never suspends
+func (r *Repository) RecordQuoteFailure(assetId int64, quoteErr string, threshold int) (*Asset, error) {
+	query := `
+		UPDATE asset
+		SET quote_failure_count = quote_failure_count + 1,
+			last_quote_error = $2,
+			last_quote_failure_at = CURRENT_TIMESTAMP,
+			quote_suspended_at = CASE
+				WHEN $3 > 0 AND quote_failure_count + 1 >= $3 THEN COALESCE(quote_suspended_at, CURRENT_TIMESTAMP)
+				ELSE quote_suspended_at
+			END
+		WHERE id = $1
+		RETURNING *
+	`
+	var asset Asset
+	err := r.db.Get(&asset, query, assetId, quoteErr, threshold)
+	if err != nil {
+		return nil, err
+	}
+	return &asset, nil
+}
+
+func (r *Repository) ResetQuoteFailures(assetIds []int64) error {
+	query := `
+		UPDATE asset
+		SET quote_failure_count = 0, last_quote_error = NULL
+		WHERE id = ANY($1) AND quote_failure_count > 0
+	`
+	_, err := r.db.Exec(query, pq.Array(assetIds))
+	return err
+}
+
+// GetQuoteFailures returns the assets failing to scrape, the suspended ones first
+func (r *Repository) GetQuoteFailures() ([]Asset, error) {
+	query := `
+		SELECT *
+		FROM asset
+		WHERE quote_failure_count > 0 OR quote_suspended_at IS NOT NULL
+		ORDER BY quote_suspended_at IS NULL, quote_failure_count DESC, symbol
+	`
+	var assets []Asset
+	err := r.db.Select(&assets, query)
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (r *Repository) ResumeQuotes(assetId int64) error {
+	query := `
+		UPDATE asset
+		SET quote_suspended_at = NULL, quote_failure_count = 0, last_quote_error = NULL
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(query, assetId)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
 func (r *Repository) GetAsset(assetId int64) (*Asset, error) {
 	query := `
         SELECT *
@@ -40,7 +128,7 @@ func (r *Repository) GetAsset(assetId int64) (*Asset, error) {
         WHERE id = $1
     `
 	var asset Asset
-	err := r.db.Select(&asset, query, assetId)
+	err := r.db.Get(&asset, query, assetId)
 	if err != nil {
 		log.Errorf("Error fetching asset: %v", err)
 		return nil, err
@@ -77,59 +165,501 @@ func (r *Repository) GetAssetBySymbolList(symbols []string) ([]Asset, error) {
 	return assets, nil
 }
 
+func (r *Repository) GetAssetsByIds(assetIds []int64) ([]Asset, error) {
+	query := `
+		SELECT *
+		FROM asset
+		WHERE id = ANY($1)
+	`
+	var assets []Asset
+	err := r.db.Select(&assets, query, pq.Array(assetIds))
+	if err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (r *Repository) CreateAsset(asset *Asset) (*Asset, error) {
+	query := `
+		INSERT INTO asset (name, symbol, description, sector, industry, asset_class, country, currency, exchange)
+		VALUES (:name, :symbol, :description, :sector, :industry, :asset_class, :country, :currency, :exchange)
+		ON CONFLICT (symbol) DO NOTHING
+		RETURNING id
+	`
+	rows, err := r.db.NamedQuery(query, asset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	if !rows.Next() {
+		return nil, AssetExistsErr
+	}
+	if err := rows.Scan(&asset.Id); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+func (r *Repository) UpdateAsset(asset *Asset) error {
+	query := `
+		UPDATE asset
+		SET name = :name,
+			description = :description
+		WHERE id = :id
+	`
+	return r.execAffectingOne(query, asset)
+}
+
+func (r *Repository) SetDelisted(assetId int64, delisted bool) error {
+	query := `
+		UPDATE asset
+		SET delisted_at = CASE WHEN $2 THEN COALESCE(delisted_at, CURRENT_TIMESTAMP) END
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(query, assetId, delisted)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// execAffectingOne runs a named update and returns sql.ErrNoRows when nothing matched
+func (r *Repository) execAffectingOne(query string, arg interface{}) error {
+	result, err := r.db.NamedExec(query, arg)
+	if err != nil {
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+func (r *Repository) UpdateAssetMetadata(asset *Asset) error {
+	query := `
+		UPDATE asset
+		SET sector = :sector,
+			industry = :industry,
+			asset_class = :asset_class,
+			country = :country,
+			currency = :currency,
+			exchange = :exchange
+		WHERE id = :id
+	`
+	return r.execAffectingOne(query, asset)
+}
+
+func (r *Repository) GetConstituents(assetIds []int64) ([]AssetConstituent, error) {
+	query := `
+		SELECT
+			c.id,
+			c.asset_id,
+			c.constituent_asset_id,
+			c.symbol,
+			COALESCE(c.name, a.name) AS name,
+			c.weight,
+			COALESCE(c.sector, a.sector) AS sector,
+			COALESCE(c.country, a.country) AS country,
+			c.created_at
+		FROM asset_constituent c
+		LEFT JOIN asset a ON a.id = c.constituent_asset_id
+		WHERE c.asset_id = ANY($1)
+		ORDER BY c.asset_id, c.weight DESC
+	`
+	var constituents []AssetConstituent
+	err := r.db.Select(&constituents, query, pq.Array(assetIds))
+	if err != nil {
+		return nil, err
+	}
+	return constituents, nil
+}
+
+func (r *Repository) ReplaceConstituents(assetId int64, constituents []AssetConstituent) error {
+	query := `
+		INSERT INTO asset_constituent (asset_id, constituent_asset_id, symbol, name, weight, sector, country)
+		VALUES (:asset_id, (SELECT id FROM asset WHERE symbol = :symbol), :symbol, :name, :weight, :sector, :country)
+	`
+
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Will be ignored if the tx has been committed later
+
+	_, err = tx.Exec("DELETE FROM asset_constituent WHERE asset_id = $1", assetId)
+	if err != nil {
+		return err
+	}
+
+	for _, constituent := range constituents {
+		constituent.AssetId = assetId
+		if _, err := tx.NamedExec(query, constituent); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// quoteColumns are the columns asset_quote and asset_quote_archive share, listed so a union
+// of both doesn't depend on their column order
+const quoteColumns = `id, asset_id, interval, open, high, low, quote, volume, quote_time, created_at`
+
+// quoteHistory selects the hourly bars of asset $1, and before its first hourly bar the daily bars,
+// live or archived, so readers don't notice the hourly bars the retention rolled up.
+// $2 and $3 are the hourly and daily intervals.
+const quoteHistory = `
+        SELECT * FROM (
+            SELECT ` + quoteColumns + ` FROM asset_quote WHERE asset_id = $1 AND interval IN ($2, $3)
+            UNION ALL
+            SELECT ` + quoteColumns + ` FROM asset_quote_archive WHERE asset_id = $1 AND interval IN ($2, $3)
+        ) history
+        WHERE interval = $2 OR quote_time < COALESCE(
+            (SELECT MIN(quote_time) FROM asset_quote WHERE asset_id = $1 AND interval = $2),
+            'infinity'
+        )
+`
+
 func (r *Repository) GetAssetQuoteAtTime(assetId int64, t time.Time) (*AssetQuote, error) {
 	query := `
         SELECT *
-        FROM asset_quote
-        WHERE asset_id = $1 AND quote_time <= $2
+        FROM (` + quoteHistory + `) quotes
+        WHERE quote_time <= $4
         ORDER BY quote_time DESC
         LIMIT 1
     `
 	var quote AssetQuote
-	err := r.db.Get(&quote, query, assetId, t)
+	err := r.db.Get(&quote, query, assetId, quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay, t)
 	if err != nil {
 		return nil, err
 	}
 	return &quote, nil
 }
 
+// GetAssetQuotesAtTimes looks up the quote GetAssetQuoteAtTime would return for each asset and time
+// pair in a single query. The result is keyed by the index of the pair, pairs without a quote are missing.
+func (r *Repository) GetAssetQuotesAtTimes(assetIds []int64, times []time.Time) (map[int]AssetQuote, error) {
+	// hourly bars win over daily ones, which only fill in before the first hourly bar
+	query := `
+        SELECT ids.ordinal, q.*
+        FROM unnest($1::bigint[], $2::timestamptz[]) WITH ORDINALITY AS ids(asset_id, at, ordinal)
+        CROSS JOIN LATERAL (
+            SELECT * FROM (
+                SELECT ` + quoteColumns + ` FROM asset_quote
+                WHERE asset_id = ids.asset_id AND interval IN ($3, $4) AND quote_time <= ids.at
+                UNION ALL
+                SELECT ` + quoteColumns + ` FROM asset_quote_archive
+                WHERE asset_id = ids.asset_id AND interval IN ($3, $4) AND quote_time <= ids.at
+            ) history
+            ORDER BY interval = $3 DESC, quote_time DESC
+            LIMIT 1
+        ) q
+    `
+	formattedTimes := make([]string, len(times))
+	for i, t := range times {
+		formattedTimes[i] = t.Format(time.RFC3339Nano)
+	}
+
+	var rows []struct {
+		Ordinal int `db:"ordinal"`
+		AssetQuote
+	}
+	err := r.db.Select(&rows, query, pq.Array(assetIds), pq.Array(formattedTimes), quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := make(map[int]AssetQuote, len(rows))
+	for _, row := range rows {
+		quotes[row.Ordinal-1] = row.AssetQuote
+	}
+	return quotes, nil
+}
+
 func (r *Repository) GetAssetQuotesForPeriod(assetId int64, startTime, endTime time.Time) ([]AssetQuote, error) {
 	query := `
         SELECT *
-        FROM asset_quote
-        WHERE asset_id = $1 AND quote_time BETWEEN $2 AND $3
+        FROM (` + quoteHistory + `) quotes
+        WHERE quote_time BETWEEN $4 AND $5
         ORDER BY quote_time ASC
     `
 	var quotes []AssetQuote
-	err := r.db.Select(&quotes, query, assetId, startTime, endTime)
+	err := r.db.Select(&quotes, query, assetId, quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay, startTime, endTime)
 	if err != nil {
 		return nil, err
 	}
 	return quotes, nil
 }
 
-func (r *Repository) SaveAssetQuote(assetQuote AssetQuote) error {
+// GetHeldAssets returns the active assets with an open position in any portfolio, and the fx rates
+func (r *Repository) GetHeldAssets() ([]SimpleAssetDTO, error) {
 	query := `
-			INSERT INTO asset_quote (asset_id, quote, quote_time)
-			VALUES (:asset_id, :quote, :quote_time)
-			ON CONFLICT (asset_id, quote_time)
-			DO UPDATE SET quote = :quote
+        SELECT ast.id, ast.name, ast.symbol, COALESCE(ast.currency, 'USD') AS currency, COALESCE(ast.exchange, '') AS exchange
+        FROM asset ast
+        WHERE ast.delisted_at IS NULL AND ast.quote_suspended_at IS NULL AND (
+            ast.asset_class = 'fx' OR ast.id IN (
+                SELECT a.asset_id
+                FROM allocation a
+                JOIN transaction t ON t.allocation_id = a.id
+                GROUP BY a.asset_id
+                HAVING SUM(CASE WHEN t.side = 0 THEN t.quantity ELSE -t.quantity END) > 0
+            )
+        )
+    `
+	var assets []SimpleAssetDTO
+	err := r.db.Select(&assets, query)
+	if err != nil {
+		log.Errorf("Error fetching held assets: %v", err)
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (r *Repository) SaveQuoteSnapshot(snapshot QuoteSnapshot) error {
+	query := `
+		INSERT INTO asset_quote_snapshot (asset_id, price, open, high, low, volume, provider, quote_time)
+		VALUES (:asset_id, :price, :open, :high, :low, :volume, :provider, :quote_time)
+		ON CONFLICT (asset_id, quote_time)
+		DO UPDATE SET price = :price, open = :open, high = :high, low = :low, volume = :volume, provider = :provider
 	`
+	_, err := r.db.NamedExec(query, snapshot)
+	return err
+}
+
+func (r *Repository) GetLatestQuoteSnapshot(assetId int64) (*QuoteSnapshot, error) {
+	query := `
+        SELECT *
+        FROM asset_quote_snapshot
+        WHERE asset_id = $1
+        ORDER BY quote_time DESC
+        LIMIT 1
+    `
+	var snapshot QuoteSnapshot
+	err := r.db.Get(&snapshot, query, assetId)
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// GetPriceRanges returns the lowest low and highest high of the hourly and daily bars of the assets
+// from from until before to, live or archived. Assets without bars in the period are missing.
+func (r *Repository) GetPriceRanges(assetIds []int64, from, to time.Time) ([]PriceRange, error) {
+	query := `
+        SELECT asset_id, MIN(COALESCE(low, quote)) AS low, MAX(COALESCE(high, quote)) AS high
+        FROM (
+            SELECT asset_id, low, high, quote FROM asset_quote
+            WHERE asset_id = ANY($1) AND interval IN ($2, $3) AND quote_time >= $4 AND quote_time < $5
+            UNION ALL
+            SELECT asset_id, low, high, quote FROM asset_quote_archive
+            WHERE asset_id = ANY($1) AND interval IN ($2, $3) AND quote_time >= $4 AND quote_time < $5
+        ) history
+        GROUP BY asset_id
+    `
+	var ranges []PriceRange
+	err := r.db.Select(&ranges, query, pq.Array(assetIds), quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay, from, to)
+	if err != nil {
+		return nil, err
+	}
+	return ranges, nil
+}
+
+// GetLatestQuoteSnapshots returns the latest snapshot of each asset having one
+func (r *Repository) GetLatestQuoteSnapshots(assetIds []int64) ([]QuoteSnapshot, error) {
+	query := `
+        SELECT DISTINCT ON (asset_id) *
+        FROM asset_quote_snapshot
+        WHERE asset_id = ANY($1)
+        ORDER BY asset_id, quote_time DESC
+    `
+	var snapshots []QuoteSnapshot
+	err := r.db.Select(&snapshots, query, pq.Array(assetIds))
+	if err != nil {
+		return nil, err
+	}
+	return snapshots, nil
+}
+
+// GetCandles returns the bars of the interval, bars saved with only a close use it for every price
+func (r *Repository) GetCandles(assetId int64, interval string, startTime, endTime time.Time) ([]Candle, error) {
+	query := `
+        SELECT
+            quote_time,
+            COALESCE(open, quote) AS open,
+            COALESCE(high, quote) AS high,
+            COALESCE(low, quote) AS low,
+            quote,
+            COALESCE(volume, 0) AS volume
+        FROM (
+            SELECT ` + quoteColumns + ` FROM asset_quote WHERE asset_id = $1 AND interval = $2 AND quote_time BETWEEN $3 AND $4
+            UNION ALL
+            SELECT ` + quoteColumns + ` FROM asset_quote_archive WHERE asset_id = $1 AND interval = $2 AND quote_time BETWEEN $3 AND $4
+        ) quotes
+        ORDER BY quote_time ASC
+    `
+	candles := []Candle{}
+	err := r.db.Select(&candles, query, assetId, interval, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	return candles, nil
+}
+
+// quoteInsertChunk keeps a statement under the 65535 parameters postgres accepts
+const quoteInsertChunk = 1000
+
+// SaveAssetQuotes upserts the quotes with multi-row inserts in a single transaction
+func (r *Repository) SaveAssetQuotes(quotes []AssetQuote) error {
+	quotes = uniqueQuotes(quotes)
 
-	// Begin a transaction
 	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback() // Will be ignored if the tx has been committed later
 
-	// Insert or update the assetQuote
-	_, err = tx.NamedExec(query, assetQuote)
+	for start := 0; start < len(quotes); start += quoteInsertChunk {
+		end := min(start+quoteInsertChunk, len(quotes))
+		chunk := quotes[start:end]
+
+		values := make([]string, 0, len(chunk))
+		args := make([]interface{}, 0, len(chunk)*8)
+		for i, q := range chunk {
+			n := i * 8
+			values = append(values, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d, $%d)", n+1, n+2, n+3, n+4, n+5, n+6, n+7, n+8))
+			args = append(args, q.AssetId, q.Interval, q.Open, q.High, q.Low, q.Quote, q.Volume, q.QuoteTime)
+		}
+
+		query := `
+			INSERT INTO asset_quote (asset_id, interval, open, high, low, quote, volume, quote_time)
+			VALUES ` + strings.Join(values, ", ") + `
+			ON CONFLICT (asset_id, interval, quote_time)
+			DO UPDATE SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low, quote = EXCLUDED.quote, volume = EXCLUDED.volume
+		`
+		if _, err := tx.Exec(query, args...); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// uniqueQuotes keeps the last quote of every key, as an upsert can't touch a row twice, and
+// sorts them so concurrent batches lock rows in the same order
+func uniqueQuotes(quotes []AssetQuote) []AssetQuote {
+	type quoteKey struct {
+		assetId   int64
+		interval  string
+		quoteTime int64
+	}
+	positions := make(map[quoteKey]int, len(quotes))
+	unique := make([]AssetQuote, 0, len(quotes))
+	for _, q := range quotes {
+		key := quoteKey{q.AssetId, q.Interval, q.QuoteTime.UnixMicro()}
+		if i, ok := positions[key]; ok {
+			unique[i] = q
+			continue
+		}
+		positions[key] = len(unique)
+		unique = append(unique, q)
+	}
+
+	sort.Slice(unique, func(i, j int) bool {
+		a, b := unique[i], unique[j]
+		if a.AssetId != b.AssetId {
+			return a.AssetId < b.AssetId
+		}
+		if a.Interval != b.Interval {
+			return a.Interval < b.Interval
+		}
+		return a.QuoteTime.Before(b.QuoteTime)
+	})
+	return unique
+}
+
+// SaveQuoteAnomaly quarantines the bar unless it already has an anomaly, so a re-scraped bar
+// neither repeats it nor reopens a resolved one
+func (r *Repository) SaveQuoteAnomaly(anomaly QuoteAnomaly) error {
+	query := `
+		INSERT INTO quote_anomaly (asset_id, interval, quote_time, open, high, low, quote, volume, provider, reference_provider, reference_quote, deviation, reason)
+		VALUES (:asset_id, :interval, :quote_time, :open, :high, :low, :quote, :volume, :provider, :reference_provider, :reference_quote, :deviation, :reason)
+		ON CONFLICT (asset_id, interval, quote_time) DO NOTHING
+	`
+	_, err := r.db.NamedExec(query, anomaly)
+	return err
+}
+
+func (r *Repository) GetQuoteAnomalies(status string) ([]QuoteAnomaly, error) {
+	query := `
+		SELECT *
+		FROM quote_anomaly
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at DESC
+	`
+	anomalies := []QuoteAnomaly{}
+	err := r.db.Select(&anomalies, query, status)
+	if err != nil {
+		return nil, err
+	}
+	return anomalies, nil
+}
+
+func (r *Repository) GetQuoteAnomaly(anomalyId int64) (*QuoteAnomaly, error) {
+	query := `SELECT * FROM quote_anomaly WHERE id = $1`
+	var anomaly QuoteAnomaly
+	err := r.db.Get(&anomaly, query, anomalyId)
+	if err != nil {
+		return nil, err
+	}
+	return &anomaly, nil
+}
+
+// ResolveQuoteAnomaly moves a quarantined anomaly to status, saving its quote when it is released
+func (r *Repository) ResolveQuoteAnomaly(anomalyId int64, status string) error {
+	tx, err := r.db.Beginx()
 	if err != nil {
 		return err
 	}
+	defer tx.Rollback()
+
+	var anomaly QuoteAnomaly
+	err = tx.Get(&anomaly, `
+		UPDATE quote_anomaly
+		SET status = $2, resolved_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'quarantined'
+		RETURNING *
+	`, anomalyId, status)
+	if err == sql.ErrNoRows {
+		return QuoteAnomalyNotQuarantinedErr
+	}
+	if err != nil {
+		return err
+	}
+
+	if status == AnomalyStatusReleased {
+		_, err = tx.Exec(`
+			INSERT INTO asset_quote (asset_id, interval, open, high, low, quote, volume, quote_time)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			ON CONFLICT (asset_id, interval, quote_time)
+			DO UPDATE SET open = EXCLUDED.open, high = EXCLUDED.high, low = EXCLUDED.low, quote = EXCLUDED.quote, volume = EXCLUDED.volume
+		`, anomaly.AssetId, anomaly.Interval, anomaly.Open, anomaly.High, anomaly.Low, anomaly.Quote, anomaly.Volume, anomaly.QuoteTime)
+		if err != nil {
+			return err
+		}
+	}
 
-	// Commit the transaction
 	return tx.Commit()
 }
 
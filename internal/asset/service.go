@@ -1,21 +1,38 @@
 package asset
 
 import (
+	"database/sql"
+	"encoding/csv"
 	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/internal/tradingcalendar"
 )
 
+var InvalidConstituentCSVErr error = errors.New("invalid constituent csv")
+
 type Service struct {
 	repo          *Repository
+	calendars     *tradingcalendar.Registry
 	quoteReceiver <-chan AssetQuoteChanData
+
+	quoteListeners []func(AssetQuoteChanData)
+	metrics        ingestionMetrics
+	quoteCache     quoteCache
 }
 
-func NewService(repo *Repository, quoteReceiver <-chan AssetQuoteChanData) *Service {
+func NewService(repo *Repository, calendars *tradingcalendar.Registry, quoteCacheTTL time.Duration, quoteReceiver <-chan AssetQuoteChanData) *Service {
 	return &Service{
 		repo:          repo,
+		calendars:     calendars,
 		quoteReceiver: quoteReceiver,
+		quoteCache:    newQuoteCache(quoteCacheTTL),
 	}
 }
 
@@ -23,6 +40,10 @@ func (s *Service) GetAssets() ([]SimpleAssetDTO, error) {
 	return s.repo.GetAssets()
 }
 
+func (s *Service) GetActiveAssets() ([]SimpleAssetDTO, error) {
+	return s.repo.GetActiveAssets()
+}
+
 func (s *Service) GetAsset(assetId int64) (*Asset, error) {
 	return s.repo.GetAsset(assetId)
 }
@@ -31,93 +52,580 @@ func (s *Service) GetAssetBySymbol(symbol string) (*Asset, error) {
 	return s.repo.GetAssetBySymbol(symbol)
 }
 
+func (s *Service) GetAssetsByIds(assetIds []int64) ([]Asset, error) {
+	return s.repo.GetAssetsByIds(assetIds)
+}
+
+func (s *Service) GetAssetsBySymbols(symbols []string) ([]Asset, error) {
+	return s.repo.GetAssetBySymbolList(symbols)
+}
+
+func (s *Service) CreateAsset(asset *Asset) (*Asset, error) {
+	asset.Symbol = strings.ToUpper(strings.TrimSpace(asset.Symbol))
+	return s.repo.CreateAsset(asset)
+}
+
+func (s *Service) UpdateAsset(assetId int64, request UpdateAssetRequest) (*Asset, error) {
+	asset, err := s.repo.GetAsset(assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	asset.Name = strings.TrimSpace(request.Name)
+	asset.Description = ToNullString(request.Description)
+
+	if err := s.repo.UpdateAsset(asset); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+// DelistAsset stops quote updates for the asset, its quote history is kept
+func (s *Service) DelistAsset(assetId int64) (*Asset, error) {
+	if err := s.repo.SetDelisted(assetId, true); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAsset(assetId)
+}
+
+func (s *Service) RelistAsset(assetId int64) (*Asset, error) {
+	if err := s.repo.SetDelisted(assetId, false); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAsset(assetId)
+}
+
+// RecordQuoteFailure counts a failed scrape of the asset, see Repository.RecordQuoteFailure
+func (s *Service) RecordQuoteFailure(assetId int64, quoteErr error, threshold int) (*Asset, error) {
+	return s.repo.RecordQuoteFailure(assetId, quoteErr.Error(), threshold)
+}
+
+func (s *Service) ResetQuoteFailures(assetIds []int64) error {
+	if len(assetIds) == 0 {
+		return nil
+	}
+	return s.repo.ResetQuoteFailures(assetIds)
+}
+
+func (s *Service) GetQuoteFailures() ([]Asset, error) {
+	return s.repo.GetQuoteFailures()
+}
+
+// ResumeQuotes lifts the suspension of an asset that failed to scrape
+func (s *Service) ResumeQuotes(assetId int64) (*Asset, error) {
+	if err := s.repo.ResumeQuotes(assetId); err != nil {
+		return nil, err
+	}
+	return s.repo.GetAsset(assetId)
+}
+
+func (s *Service) UpdateAssetMetadata(assetId int64, request UpdateAssetMetadataRequest) (*Asset, error) {
+	asset, err := s.repo.GetAsset(assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	asset.Sector = ToNullString(request.Sector)
+	asset.Industry = ToNullString(request.Industry)
+	asset.AssetClass = ToNullString(strings.ToLower(request.AssetClass))
+	asset.Country = ToNullString(strings.ToUpper(request.Country))
+	asset.Currency = ToNullString(strings.ToUpper(request.Currency))
+	asset.Exchange = ToNullString(strings.ToUpper(request.Exchange))
+
+	if err := s.repo.UpdateAssetMetadata(asset); err != nil {
+		return nil, err
+	}
+	return asset, nil
+}
+
+func (s *Service) GetConstituents(assetIds ...int64) ([]AssetConstituent, error) {
+	return s.repo.GetConstituents(assetIds)
+}
+
+// LoadConstituentsCSV replaces the holdings of a fund with the ones in the csv.
+// The csv needs a header row with at least the symbol and weight columns,
+// name, sector and country columns are optional.
+func (s *Service) LoadConstituentsCSV(assetId int64, reader io.Reader) (int, error) {
+	if _, err := s.repo.GetAsset(assetId); err != nil {
+		return 0, err
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		return 0, fmt.Errorf("%w: could not read header: %v", InvalidConstituentCSVErr, err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	symbolCol, hasSymbol := columns["symbol"]
+	weightCol, hasWeight := columns["weight"]
+	if !hasSymbol || !hasWeight {
+		return 0, fmt.Errorf("%w: symbol and weight columns are required", InvalidConstituentCSVErr)
+	}
+
+	column := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return record[i]
+		}
+		return ""
+	}
+
+	var constituents []AssetConstituent
+	seen := make(map[string]bool)
+	totalWeight := 0.0
+	for line := 2; ; line++ {
+		record, err := csvReader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%w: line %d: %v", InvalidConstituentCSVErr, line, err)
+		}
+
+		symbol := strings.ToUpper(strings.TrimSpace(record[symbolCol]))
+		if symbol == "" {
+			return 0, fmt.Errorf("%w: line %d: symbol is required", InvalidConstituentCSVErr, line)
+		}
+		if seen[symbol] {
+			return 0, fmt.Errorf("%w: line %d: duplicate symbol %s", InvalidConstituentCSVErr, line, symbol)
+		}
+		seen[symbol] = true
+
+		weight, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(record[weightCol]), "%"), 64)
+		if err != nil || weight < 0 || weight > 100 {
+			return 0, fmt.Errorf("%w: line %d: invalid weight %q", InvalidConstituentCSVErr, line, record[weightCol])
+		}
+		totalWeight += weight
+
+		constituents = append(constituents, AssetConstituent{
+			Symbol:  symbol,
+			Name:    ToNullString(column(record, "name")),
+			Weight:  weight,
+			Sector:  ToNullString(column(record, "sector")),
+			Country: ToNullString(strings.ToUpper(column(record, "country"))),
+		})
+	}
+
+	// Allow for rounding in the published weights
+	if totalWeight > 100.5 {
+		return 0, fmt.Errorf("%w: weights add up to %.2f%%", InvalidConstituentCSVErr, totalWeight)
+	}
+
+	if err := s.repo.ReplaceConstituents(assetId, constituents); err != nil {
+		return 0, err
+	}
+	return len(constituents), nil
+}
+
 func (s *Service) GetAssetQuoteAtTime(assetId int64, t time.Time) (*AssetQuote, error) {
 	return s.repo.GetAssetQuoteAtTime(assetId, t)
 }
 
+// GetAssetQuotesAtTimes is the batched GetAssetQuoteAtTime, keyed by the index of the asset and time pair
+func (s *Service) GetAssetQuotesAtTimes(assetIds []int64, times []time.Time) (map[int]AssetQuote, error) {
+	if len(assetIds) == 0 {
+		return map[int]AssetQuote{}, nil
+	}
+	return s.repo.GetAssetQuotesAtTimes(assetIds, times)
+}
+
 func (s *Service) GetAssetQuotesForPeriod(assetId int64, startTime, endTime time.Time) ([]AssetQuote, error) {
 	return s.repo.GetAssetQuotesForPeriod(assetId, startTime, endTime)
 }
 
+// GetLatestQuote returns the latest bar, or the latest intraday snapshot when it is newer
 func (s *Service) GetLatestQuote(assetId int64) (*AssetQuote, error) {
-	return s.GetAssetQuoteAtTime(assetId, time.Now())
+	if quote, ok := s.quoteCache.latest.Get(assetId); ok {
+		return &quote, nil
+	}
+
+	generation := s.quoteCache.latest.Generation()
+	quote, err := s.getLatestQuote(assetId)
+	if err != nil {
+		return nil, err
+	}
+	s.quoteCache.latest.Set(assetId, *quote, generation)
+	return quote, nil
 }
 
-func (s *Service) SaveAssetQuote(assetQuoteData AssetQuoteChanData) error {
+func (s *Service) getLatestQuote(assetId int64) (*AssetQuote, error) {
+	quote, err := s.GetAssetQuoteAtTime(assetId, time.Now())
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
 
-	assetQuote := AssetQuote{
-		AssetId:   assetQuoteData.AssetId,
-		Quote:     assetQuoteData.Quote,
-		QuoteTime: assetQuoteData.QuoteTime,
+	snapshot, snapshotErr := s.repo.GetLatestQuoteSnapshot(assetId)
+	if snapshotErr != nil {
+		if snapshotErr != sql.ErrNoRows {
+			log.Errorf("Error fetching quote snapshot of asset %d: %v", assetId, snapshotErr)
+		}
+		return quote, err
+	}
+	if quote != nil && !snapshot.QuoteTime.After(quote.QuoteTime) {
+		return quote, nil
 	}
 
-	return s.repo.SaveAssetQuote(assetQuote)
+	snapshotQuote := snapshot.toQuote()
+	return &snapshotQuote, nil
 }
 
-func (s *Service) AssetQuoteChanDataConsumer() {
-	for quoteData := range s.quoteReceiver {
-		err := s.SaveAssetQuote(quoteData)
-		if err != nil {
-			log.Error("Error consuming quote data.", quoteData, err)
+// GetLatestQuotes is the batched GetLatestQuote, keyed by asset id. Assets without quotes are missing.
+func (s *Service) GetLatestQuotes(assetIds []int64) (map[int64]AssetQuote, error) {
+	quotes := make(map[int64]AssetQuote, len(assetIds))
+	var missing []int64
+	for _, assetId := range uniqueIds(assetIds) {
+		if quote, ok := s.quoteCache.latest.Get(assetId); ok {
+			quotes[assetId] = quote
+		} else {
+			missing = append(missing, assetId)
+		}
+	}
+	if len(missing) == 0 {
+		return quotes, nil
+	}
+
+	generation := s.quoteCache.latest.Generation()
+	now := time.Now()
+	times := make([]time.Time, len(missing))
+	for i := range missing {
+		times[i] = now
+	}
+	bars, err := s.repo.GetAssetQuotesAtTimes(missing, times)
+	if err != nil {
+		return nil, err
+	}
+	for i, assetId := range missing {
+		if bar, ok := bars[i]; ok {
+			quotes[assetId] = bar
+		}
+	}
+
+	snapshots, err := s.repo.GetLatestQuoteSnapshots(missing)
+	if err != nil {
+		log.Errorf("Error fetching quote snapshots: %v", err)
+	}
+	for _, snapshot := range snapshots {
+		if bar, ok := quotes[snapshot.AssetId]; ok && !snapshot.QuoteTime.After(bar.QuoteTime) {
+			continue
+		}
+		quotes[snapshot.AssetId] = snapshot.toQuote()
+	}
+
+	for _, assetId := range missing {
+		if quote, ok := quotes[assetId]; ok {
+			s.quoteCache.latest.Set(assetId, quote, generation)
 		}
 	}
+	return quotes, nil
+}
+
+func (s *Service) GetHeldAssets() ([]SimpleAssetDTO, error) {
+	return s.repo.GetHeldAssets()
+}
+
+func (s *Service) SaveQuoteSnapshot(snapshot QuoteSnapshot) error {
+	if err := s.repo.SaveQuoteSnapshot(snapshot); err != nil {
+		return err
+	}
+	s.invalidateQuotes(snapshot.AssetId)
+	s.notifyQuoteListeners(AssetQuoteChanData{
+		Symbol:    snapshot.Symbol,
+		AssetId:   snapshot.AssetId,
+		Interval:  SnapshotInterval,
+		Quote:     snapshot.Price,
+		QuoteTime: snapshot.QuoteTime,
+		Provider:  snapshot.Provider,
+	})
+	return nil
+}
+
+// AddQuoteListener registers a function called with every saved quote and snapshot,
+// listeners run on the ingestion path so they must not block. Listeners are added before
+// the ingestion starts.
+func (s *Service) AddQuoteListener(listener func(AssetQuoteChanData)) {
+	s.quoteListeners = append(s.quoteListeners, listener)
+}
+
+func (s *Service) notifyQuoteListeners(quote AssetQuoteChanData) {
+	for _, listener := range s.quoteListeners {
+		listener(quote)
+	}
 }
 
-func (s *Service) GetPreviousTradingDayQuote(assetId int64, currentTime time.Time) (*AssetQuote, error) {
-	checkTime := currentTime.AddDate(0, 0, -1)
+// GetCandles returns the bars of the asset for charting, the period defaults to the last 3 months
+// of daily bars or the last week of hourly bars
+func (s *Service) GetCandles(assetId int64, interval string, from, to time.Time) (*CandlesResponse, error) {
+	var defaultPeriod time.Duration
+	switch interval {
+	case quoteprovider.IntervalOneDay:
+		defaultPeriod = 92 * 24 * time.Hour
+	case quoteprovider.IntervalOneHour:
+		defaultPeriod = 7 * 24 * time.Hour
+	default:
+		return nil, UnsupportedIntervalErr
+	}
 
-	for i := 0; i < 10; i++ { // Check up to 10 days back to be safe
-		quote, err := s.GetAssetQuoteAtTime(assetId, checkTime)
-		if err == nil {
-			return quote, nil
+	asset, err := s.repo.GetAsset(assetId)
+	if err != nil {
+		return nil, err
+	}
+
+	if to.IsZero() {
+		to = time.Now()
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultPeriod)
+	}
+
+	candles, err := s.repo.GetCandles(assetId, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CandlesResponse{
+		AssetId:  asset.Id,
+		Symbol:   asset.Symbol,
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Candles:  candles,
+	}, nil
+}
+
+func (s *Service) quarantineQuote(assetQuoteData AssetQuoteChanData) error {
+	anomaly := *assetQuoteData.Anomaly
+	anomaly.AssetId = assetQuoteData.AssetId
+	anomaly.Open = ToNullPrice(assetQuoteData.Open)
+	anomaly.High = ToNullPrice(assetQuoteData.High)
+	anomaly.Low = ToNullPrice(assetQuoteData.Low)
+	anomaly.Quote = assetQuoteData.Quote
+	anomaly.Volume = sql.NullFloat64{Float64: assetQuoteData.Volume, Valid: true}
+	anomaly.QuoteTime = assetQuoteData.QuoteTime
+	anomaly.Interval = quoteInterval(assetQuoteData.Interval)
+	anomaly.Provider = assetQuoteData.Provider
+	if anomaly.Provider == "" {
+		anomaly.Provider = "unknown"
+	}
+
+	log.Warnf("Quarantined %s quote %v at %v: %s", assetQuoteData.Symbol, anomaly.Quote, anomaly.QuoteTime, anomaly.Reason)
+	return s.repo.SaveQuoteAnomaly(anomaly)
+}
+
+func (s *Service) GetQuoteAnomalies(status string) ([]QuoteAnomaly, error) {
+	return s.repo.GetQuoteAnomalies(status)
+}
+
+// ReleaseQuoteAnomaly writes the quarantined quote to asset_quote
+func (s *Service) ReleaseQuoteAnomaly(anomalyId int64) (*QuoteAnomaly, error) {
+	return s.resolveQuoteAnomaly(anomalyId, AnomalyStatusReleased)
+}
+
+func (s *Service) DiscardQuoteAnomaly(anomalyId int64) (*QuoteAnomaly, error) {
+	return s.resolveQuoteAnomaly(anomalyId, AnomalyStatusDiscarded)
+}
+
+func (s *Service) resolveQuoteAnomaly(anomalyId int64, status string) (*QuoteAnomaly, error) {
+	anomaly, err := s.repo.GetQuoteAnomaly(anomalyId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.ResolveQuoteAnomaly(anomalyId, status); err != nil {
+		return nil, err
+	}
+	// a released quote is written to the quotes
+	s.invalidateQuotes(anomaly.AssetId)
+	return s.repo.GetQuoteAnomaly(anomalyId)
+}
+
+// GetPreviousTradingDayQuote returns the last quote of the exchange's trading day before the one of currentTime
+func (s *Service) GetPreviousTradingDayQuote(assetId int64, exchange string, currentTime time.Time) (*AssetQuote, error) {
+	calendar := s.calendars.For(exchange)
+	session, ok := calendar.PreviousSession(currentTime)
+	if !ok {
+		return nil, NoPreviousTradingDayQuoteErr
+	}
+
+	dayEnd := sessionDayEnd(session, calendar.Location())
+	key := previousCloseKey{assetId: assetId, dayEnd: dayEnd.Unix()}
+	if quote, ok := s.quoteCache.previousClose.Get(key); ok {
+		return &quote, nil
+	}
+
+	generation := s.quoteCache.previousClose.Generation()
+	quote, err := s.GetAssetQuoteAtTime(assetId, dayEnd.Add(-time.Microsecond))
+	if err == sql.ErrNoRows {
+		return nil, NoPreviousTradingDayQuoteErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.quoteCache.previousClose.Set(key, *quote, generation)
+	return quote, nil
+}
+
+// GetPreviousTradingDayQuotes is the batched GetPreviousTradingDayQuote, keyed by asset id.
+// Assets without a previous trading day quote are missing.
+func (s *Service) GetPreviousTradingDayQuotes(assets []PreviousCloseRequest) (map[int64]AssetQuote, error) {
+	quotes := make(map[int64]AssetQuote, len(assets))
+	var missingKeys []previousCloseKey
+	var missingIds []int64
+	var missingTimes []time.Time
+	seen := make(map[int64]bool, len(assets))
+	for _, asset := range assets {
+		if seen[asset.AssetId] {
+			continue
+		}
+		seen[asset.AssetId] = true
+
+		calendar := s.calendars.For(asset.Exchange)
+		session, ok := calendar.PreviousSession(asset.Time)
+		if !ok {
+			continue
 		}
+		dayEnd := sessionDayEnd(session, calendar.Location())
+		key := previousCloseKey{assetId: asset.AssetId, dayEnd: dayEnd.Unix()}
+		if quote, ok := s.quoteCache.previousClose.Get(key); ok {
+			quotes[asset.AssetId] = quote
+			continue
+		}
+		missingKeys = append(missingKeys, key)
+		missingIds = append(missingIds, asset.AssetId)
+		missingTimes = append(missingTimes, dayEnd.Add(-time.Microsecond))
+	}
+	if len(missingIds) == 0 {
+		return quotes, nil
+	}
 
-		// If no quote found, move to the previous day
-		checkTime = checkTime.AddDate(0, 0, -1)
+	generation := s.quoteCache.previousClose.Generation()
+	bars, err := s.repo.GetAssetQuotesAtTimes(missingIds, missingTimes)
+	if err != nil {
+		return nil, err
+	}
+	for i, key := range missingKeys {
+		if bar, ok := bars[i]; ok {
+			quotes[key.assetId] = bar
+			s.quoteCache.previousClose.Set(key, bar, generation)
+		}
+	}
+	return quotes, nil
+}
+
+// GetPriceRanges returns the price range of the assets from from until before to, keyed by asset id
+func (s *Service) GetPriceRanges(assetIds []int64, from, to time.Time) (map[int64]PriceRange, error) {
+	ranges := make(map[int64]PriceRange, len(assetIds))
+	if len(assetIds) == 0 {
+		return ranges, nil
+	}
+
+	rows, err := s.repo.GetPriceRanges(assetIds, from, to)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		ranges[row.AssetId] = row
 	}
+	return ranges, nil
+}
+
+// Get52WeekRanges returns the price range of the assets over the 52 weeks before the UTC day
+// of now, keyed by asset id. Today is left out so a new high or low stands out against it.
+func (s *Service) Get52WeekRanges(assetIds []int64, now time.Time) (map[int64]PriceRange, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+	return s.GetPriceRanges(assetIds, today.Add(-week52Period), today)
+}
 
-	return nil, errors.New("no previous trading day quote found within the last 10 days")
+// sessionDayEnd is the end of the session's day, quotes of bars closing after the session
+// close still belong to that trading day
+func sessionDayEnd(session tradingcalendar.Session, location *time.Location) time.Time {
+	open := session.Open.In(location)
+	return time.Date(open.Year(), open.Month(), open.Day()+1, 0, 0, 0, 0, location)
+}
+
+func uniqueIds(ids []int64) []int64 {
+	seen := make(map[int64]bool, len(ids))
+	unique := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
 }
 
-func (s *Service) GetMarketOverview() ([]MarketGrowthListResponse, error) {
-	assetSymbols := []string{"VOO", "AAPL", "GOOGL", "MSFT", "AMZN", "META"}
-	assets, err := s.repo.GetAssetBySymbolList(assetSymbols)
+// GetDailyChanges returns the change of the assets since the close of their previous trading day,
+// keyed by asset id. Assets missing the latest or the previous close quote are left out.
+func (s *Service) GetDailyChanges(assets []SimpleAssetDTO) (map[int64]MarketGrowthListResponse, error) {
+	assetIds := make([]int64, len(assets))
+	for i, asset := range assets {
+		assetIds[i] = asset.Id
+	}
+	latestQuotes, err := s.GetLatestQuotes(assetIds)
 	if err != nil {
 		return nil, err
 	}
 
-	response := make([]MarketGrowthListResponse, 0, len(assets))
+	previousRequests := make([]PreviousCloseRequest, 0, len(latestQuotes))
 	for _, asset := range assets {
-		// Get latest quote
-		latestQuote, err := s.GetLatestQuote(asset.Id)
-		if err != nil {
-			return nil, err
+		if latestQuote, ok := latestQuotes[asset.Id]; ok {
+			previousRequests = append(previousRequests, PreviousCloseRequest{AssetId: asset.Id, Exchange: asset.Exchange, Time: latestQuote.QuoteTime})
 		}
+	}
+	previousQuotes, err := s.GetPreviousTradingDayQuotes(previousRequests)
+	if err != nil {
+		return nil, err
+	}
 
-		// Get previous trading day quote
-		previousTradingDayQuote, err := s.GetPreviousTradingDayQuote(asset.Id, latestQuote.QuoteTime)
-		if err != nil {
-			return nil, err
+	changes := make(map[int64]MarketGrowthListResponse, len(previousQuotes))
+	for _, asset := range assets {
+		latestQuote, hasLatest := latestQuotes[asset.Id]
+		previousQuote, hasPrevious := previousQuotes[asset.Id]
+		if !hasLatest || !hasPrevious {
+			continue
 		}
 
-		// Calculate daily change percentage for this asset
-		assetChange := 0.0
-		if previousTradingDayQuote.Quote != 0 {
-			assetChange = ((latestQuote.Quote - previousTradingDayQuote.Quote) / previousTradingDayQuote.Quote) * 100
+		change := 0.0
+		if previousQuote.Quote != 0 {
+			change = ((latestQuote.Quote - previousQuote.Quote) / previousQuote.Quote) * 100
 		}
-		portfolioResponse := MarketGrowthListResponse{
+		changes[asset.Id] = MarketGrowthListResponse{
 			Id:     asset.Id,
 			Symbol: asset.Symbol,
 			Name:   asset.Name,
-			Change: assetChange,
+			Change: change,
 			Amount: latestQuote.Quote,
 		}
-		response = append(response, portfolioResponse)
 	}
-
-	return response, nil
+	return changes, nil
 }
 
 func (s *Service) SearchAssets(query string, limit, offset int) ([]SimpleAssetDTO, int, error) {
 	return s.repo.SearchAssets(query, limit, offset)
 }
+
+// IsMarketOpen reports whether the exchange has a session running at t
+func (s *Service) IsMarketOpen(exchange string, t time.Time) bool {
+	return s.calendars.For(exchange).IsOpen(t)
+}
+
+// ToNullPrice stores missing prices, which providers report as zero, as null
+func ToNullPrice(value float64) sql.NullFloat64 {
+	return sql.NullFloat64{Float64: value, Valid: value != 0}
+}
+
+func quoteInterval(interval string) string {
+	if interval == "" {
+		return quoteprovider.IntervalOneHour
+	}
+	return interval
+}
+
+// ToNullString trims the value and stores an empty one as null
+func ToNullString(value string) sql.NullString {
+	value = strings.TrimSpace(value)
+	return sql.NullString{String: value, Valid: value != ""}
+}
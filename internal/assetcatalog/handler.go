@@ -0,0 +1,138 @@
+package assetcatalog
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) CreateAsset(c *fiber.Ctx) error {
+	var req CreateAssetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	if err := req.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	createdAsset, err := h.service.CreateAsset(req)
+	if err != nil {
+		if err == asset.AssetExistsErr {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, SymbolLookupErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create asset"})
+	}
+
+	return c.Status(fiber.StatusCreated).JSON(createdAsset)
+}
+
+func (h *Handler) RequestAsset(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	var req CreateAssetRequestRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Symbol == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "symbol is required"})
+	}
+
+	request, err := h.service.RequestAsset(userId, req.Symbol)
+	if err != nil {
+		if err == asset.AssetExistsErr {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Asset is already available"})
+		}
+		if errors.Is(err, SymbolLookupErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to request asset"})
+	}
+
+	return c.JSON(request)
+}
+
+func (h *Handler) GetUserAssetRequests(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	requests, err := h.service.GetUserAssetRequests(userId)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch asset requests"})
+	}
+
+	return c.JSON(fiber.Map{"requests": requests})
+}
+
+func (h *Handler) GetAssetRequests(c *fiber.Ctx) error {
+	status := c.Query("status", StatusPending)
+	if status == "all" {
+		status = ""
+	}
+
+	requests, err := h.service.GetAssetRequests(status)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch asset requests"})
+	}
+
+	return c.JSON(fiber.Map{"requests": requests})
+}
+
+func (h *Handler) ApproveAssetRequest(c *fiber.Ctx) error {
+	return h.reviewAssetRequest(c, true)
+}
+
+func (h *Handler) RejectAssetRequest(c *fiber.Ctx) error {
+	return h.reviewAssetRequest(c, false)
+}
+
+func (h *Handler) reviewAssetRequest(c *fiber.Ctx, approve bool) error {
+	requestId, err := c.ParamsInt("requestId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request ID"})
+	}
+
+	var req ReviewAssetRequestRequest
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	var request *AssetRequest
+	if approve {
+		request, err = h.service.ApproveAssetRequest(int64(requestId), req.Note)
+	} else {
+		request, err = h.service.RejectAssetRequest(int64(requestId), req.Note)
+	}
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset request not found"})
+		}
+		if err == AssetRequestNotPendingErr {
+			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+		}
+		if errors.Is(err, SymbolLookupErr) {
+			return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+		}
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to review asset request"})
+	}
+
+	return c.JSON(request)
+}
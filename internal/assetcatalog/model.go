@@ -0,0 +1,61 @@
+package assetcatalog
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+)
+
+var SymbolLookupErr error = errors.New("symbol could not be found at the quote provider")
+var AssetRequestNotPendingErr error = errors.New("asset request is not pending")
+
+const (
+	StatusPending  = "pending"
+	StatusApproved = "approved"
+	StatusRejected = "rejected"
+)
+
+type AssetRequest struct {
+	Id         int64          `db:"id" json:"id"`
+	UserId     int64          `db:"user_id" json:"user_id"`
+	Symbol     string         `db:"symbol" json:"symbol"`
+	Status     string         `db:"status" json:"status"`
+	Note       sql.NullString `db:"note" json:"note"`
+	AssetId    sql.NullInt64  `db:"asset_id" json:"asset_id"`
+	ReviewedAt sql.NullTime   `db:"reviewed_at" json:"reviewed_at"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+type CreateAssetRequestRequest struct {
+	Symbol string `json:"symbol"`
+}
+
+type ReviewAssetRequestRequest struct {
+	Note string `json:"note"`
+}
+
+type CreateAssetRequest struct {
+	Symbol      string `json:"symbol"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Sector      string `json:"sector"`
+	Industry    string `json:"industry"`
+	AssetClass  string `json:"asset_class"`
+	Country     string `json:"country"`
+	Currency    string `json:"currency"`
+	Exchange    string `json:"exchange"`
+	// SkipLookup creates the asset without checking it against the quote provider
+	SkipLookup bool `json:"skip_lookup"`
+}
+
+func (r *CreateAssetRequest) validate() error {
+	if strings.TrimSpace(r.Symbol) == "" {
+		return errors.New("symbol is required")
+	}
+	if r.Currency != "" && len(r.Currency) != 3 {
+		return errors.New("currency must be a 3 letter ISO code")
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package assetcatalog
+
+import (
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetAssetRequest(requestId int64) (*AssetRequest, error) {
+	query := `
+		SELECT *
+		FROM asset_request
+		WHERE id = $1
+	`
+	var request AssetRequest
+	err := r.db.Get(&request, query, requestId)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *Repository) GetPendingAssetRequest(userId int64, symbol string) (*AssetRequest, error) {
+	query := `
+		SELECT *
+		FROM asset_request
+		WHERE user_id = $1 AND symbol = $2 AND status = 'pending'
+	`
+	var request AssetRequest
+	err := r.db.Get(&request, query, userId, symbol)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
+
+func (r *Repository) GetAssetRequestsByUser(userId int64) ([]AssetRequest, error) {
+	query := `
+		SELECT *
+		FROM asset_request
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+	`
+	requests := []AssetRequest{}
+	err := r.db.Select(&requests, query, userId)
+	if err != nil {
+		log.Errorf("Error fetching asset requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *Repository) GetAssetRequestsByStatus(status string) ([]AssetRequest, error) {
+	query := `
+		SELECT *
+		FROM asset_request
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at ASC
+	`
+	requests := []AssetRequest{}
+	err := r.db.Select(&requests, query, status)
+	if err != nil {
+		log.Errorf("Error fetching asset requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *Repository) SaveAssetRequest(request *AssetRequest) (*AssetRequest, error) {
+	query := `
+		INSERT INTO asset_request (user_id, symbol)
+		VALUES (:user_id, :symbol)
+		RETURNING id, status, created_at, updated_at
+	`
+	rows, err := r.db.NamedQuery(query, request)
+	if err != nil {
+		log.Errorf("Error saving asset request: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&request.Id, &request.Status, &request.CreatedAt, &request.UpdatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return request, nil
+}
+
+// ReviewPendingAssetRequests resolves every pending request for the symbol and returns them
+func (r *Repository) ReviewPendingAssetRequests(symbol, status, note string, assetId *int64) ([]AssetRequest, error) {
+	query := `
+		UPDATE asset_request
+		SET status = $2,
+			note = NULLIF($3, ''),
+			asset_id = $4,
+			reviewed_at = CURRENT_TIMESTAMP
+		WHERE symbol = $1 AND status = 'pending'
+		RETURNING *
+	`
+	var requests []AssetRequest
+	err := r.db.Select(&requests, query, symbol, status, note, assetId)
+	if err != nil {
+		log.Errorf("Error reviewing asset requests: %v", err)
+		return nil, err
+	}
+	return requests, nil
+}
+
+func (r *Repository) RejectAssetRequest(requestId int64, note string) (*AssetRequest, error) {
+	query := `
+		UPDATE asset_request
+		SET status = 'rejected',
+			note = NULLIF($2, ''),
+			reviewed_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'pending'
+		RETURNING *
+	`
+	var request AssetRequest
+	err := r.db.Get(&request, query, requestId, note)
+	if err != nil {
+		return nil, err
+	}
+	return &request, nil
+}
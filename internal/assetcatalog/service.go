@@ -0,0 +1,261 @@
+package assetcatalog
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/assetquotefeeder"
+	"github.com/karataydev/portfoliomanbackend/internal/notification"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/pkg/lifecycle"
+)
+
+type Service struct {
+	repo                    *Repository
+	assetService            *asset.Service
+	assetQuoteFeederService *assetquotefeeder.Service
+	notificationService     *notification.Service
+	autoApprove             bool
+
+	// ctx is cancelled by Stop, the running backfills are tracked so Stop can wait for them
+	ctx       context.Context
+	cancel    context.CancelFunc
+	mu        sync.Mutex
+	backfills sync.WaitGroup
+}
+
+func NewService(repo *Repository, assetService *asset.Service, assetQuoteFeederService *assetquotefeeder.Service, notificationService *notification.Service, autoApprove bool) *Service {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Service{
+		repo:                    repo,
+		assetService:            assetService,
+		assetQuoteFeederService: assetQuoteFeederService,
+		notificationService:     notificationService,
+		autoApprove:             autoApprove,
+		ctx:                     ctx,
+		cancel:                  cancel,
+	}
+}
+
+// Stop cancels the running backfills and waits for them to finish
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.cancel()
+	s.mu.Unlock()
+	return lifecycle.Wait(ctx, s.backfills.Wait)
+}
+
+// CreateAsset adds the asset to the catalog and starts backfilling its quote history.
+// Metadata missing from the request is looked up from the quote provider.
+func (s *Service) CreateAsset(req CreateAssetRequest) (*asset.Asset, error) {
+	createdAsset, err := s.createAsset(req)
+	if err != nil {
+		return nil, err
+	}
+
+	s.inBackground(func(ctx context.Context) {
+		s.backfill(ctx, createdAsset)
+	})
+
+	return createdAsset, nil
+}
+
+func (s *Service) createAsset(req CreateAssetRequest) (*asset.Asset, error) {
+	symbol := strings.ToUpper(strings.TrimSpace(req.Symbol))
+
+	if _, err := s.assetService.GetAssetBySymbol(symbol); err == nil {
+		return nil, asset.AssetExistsErr
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if !req.SkipLookup {
+		info, err := s.assetQuoteFeederService.LookupSymbol(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %v", SymbolLookupErr, symbol, err)
+		}
+		req.Currency = valueOr(req.Currency, info.Currency)
+		req.Exchange = valueOr(req.Exchange, info.Exchange)
+		req.AssetClass = valueOr(req.AssetClass, info.AssetClass)
+	}
+
+	newAsset := &asset.Asset{
+		Symbol:      symbol,
+		Name:        valueOr(strings.TrimSpace(req.Name), symbol),
+		Description: asset.ToNullString(req.Description),
+		Sector:      asset.ToNullString(req.Sector),
+		Industry:    asset.ToNullString(req.Industry),
+		AssetClass:  asset.ToNullString(strings.ToLower(req.AssetClass)),
+		Country:     asset.ToNullString(strings.ToUpper(req.Country)),
+		Currency:    asset.ToNullString(strings.ToUpper(valueOr(req.Currency, "USD"))),
+		Exchange:    asset.ToNullString(strings.ToUpper(req.Exchange)),
+	}
+
+	return s.assetService.CreateAsset(newAsset)
+}
+
+// RequestAsset queues a symbol missing from the catalog for approval,
+// the symbol has to be known by the quote provider.
+func (s *Service) RequestAsset(userId int64, symbol string) (*AssetRequest, error) {
+	symbol = strings.ToUpper(strings.TrimSpace(symbol))
+
+	if _, err := s.assetService.GetAssetBySymbol(symbol); err == nil {
+		return nil, asset.AssetExistsErr
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	// Requesting the same symbol twice returns the open request
+	if pending, err := s.repo.GetPendingAssetRequest(userId, symbol); err == nil {
+		return pending, nil
+	} else if err != sql.ErrNoRows {
+		return nil, err
+	}
+
+	if _, err := s.assetQuoteFeederService.LookupSymbol(symbol); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", SymbolLookupErr, symbol, err)
+	}
+
+	request, err := s.repo.SaveAssetRequest(&AssetRequest{UserId: userId, Symbol: symbol})
+	if err != nil {
+		return nil, err
+	}
+
+	if s.autoApprove {
+		return s.ApproveAssetRequest(request.Id, "auto approved")
+	}
+	return request, nil
+}
+
+func (s *Service) GetUserAssetRequests(userId int64) ([]AssetRequest, error) {
+	return s.repo.GetAssetRequestsByUser(userId)
+}
+
+func (s *Service) GetAssetRequests(status string) ([]AssetRequest, error) {
+	return s.repo.GetAssetRequestsByStatus(status)
+}
+
+// ApproveAssetRequest creates the asset and resolves every pending request for the same symbol.
+// The requesters are notified once the quote history of the asset is backfilled.
+func (s *Service) ApproveAssetRequest(requestId int64, note string) (*AssetRequest, error) {
+	request, err := s.repo.GetAssetRequest(requestId)
+	if err != nil {
+		return nil, err
+	}
+	if request.Status != StatusPending {
+		return nil, AssetRequestNotPendingErr
+	}
+
+	created := true
+	approvedAsset, err := s.createAsset(CreateAssetRequest{Symbol: request.Symbol})
+	if err == asset.AssetExistsErr {
+		created = false
+		approvedAsset, err = s.assetService.GetAssetBySymbol(request.Symbol)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	requests, err := s.repo.ReviewPendingAssetRequests(request.Symbol, StatusApproved, note, &approvedAsset.Id)
+	if err != nil {
+		return nil, err
+	}
+	for i, r := range requests {
+		if r.Id == requestId {
+			request = &requests[i]
+		}
+	}
+
+	s.inBackground(func(ctx context.Context) {
+		// an asset already in the catalog has its history, only a new one is backfilled first
+		message := fmt.Sprintf("%s (%s) was added to the catalog.", approvedAsset.Symbol, approvedAsset.Name)
+		if created && !s.backfill(ctx, approvedAsset) {
+			message = fmt.Sprintf("%s (%s) was added to the catalog, but loading its price history failed. It will be retried automatically.", approvedAsset.Symbol, approvedAsset.Name)
+		}
+		for _, r := range requests {
+			s.notify(r.UserId, notification.TypeAssetRequestApproved, fmt.Sprintf("%s is now available", r.Symbol), message)
+		}
+	})
+
+	return request, nil
+}
+
+func (s *Service) RejectAssetRequest(requestId int64, note string) (*AssetRequest, error) {
+	request, err := s.repo.RejectAssetRequest(requestId, note)
+	if err == sql.ErrNoRows {
+		if _, err := s.repo.GetAssetRequest(requestId); err != nil {
+			return nil, err
+		}
+		return nil, AssetRequestNotPendingErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	message := fmt.Sprintf("Your request to add %s was rejected.", request.Symbol)
+	if note != "" {
+		message += " " + note
+	}
+	s.notify(request.UserId, notification.TypeAssetRequestRejected, fmt.Sprintf("%s request rejected", request.Symbol), message)
+
+	return request, nil
+}
+
+// notify doesn't fail the review, a missed notification is only logged
+func (s *Service) notify(userId int64, notificationType, title, message string) {
+	if err := s.notificationService.Notify(userId, notificationType, title, message); err != nil {
+		log.Errorf("could not notify user %d: %v", userId, err)
+	}
+}
+
+// inBackground runs the task in a goroutine tracked by Stop. Once stopping, the task runs
+// right away with the cancelled context, so it skips its work but still finishes up.
+func (s *Service) inBackground(task func(ctx context.Context)) {
+	s.mu.Lock()
+	stopping := s.ctx.Err() != nil
+	if !stopping {
+		s.backfills.Add(1)
+	}
+	s.mu.Unlock()
+
+	if stopping {
+		task(s.ctx)
+		return
+	}
+	go func() {
+		defer s.backfills.Done()
+		task(s.ctx)
+	}()
+}
+
+// backfill loads a year of quotes of the asset and reports whether they were saved,
+// what it couldn't load is left to the gap scanner
+func (s *Service) backfill(ctx context.Context, a *asset.Asset) bool {
+	simpleAsset := asset.SimpleAssetDTO{Id: a.Id, Name: a.Name, Symbol: a.Symbol, Exchange: a.Exchange.String}
+	now := time.Now()
+	for _, interval := range []string{quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay} {
+		if err := ctx.Err(); err != nil {
+			log.Errorf("could not backfill %s quotes of %s: %v", interval, a.Symbol, err)
+			return false
+		}
+		if err := s.assetQuoteFeederService.ScrapeAssetAndWait(simpleAsset, now.AddDate(-1, 0, 0), now, interval); err != nil {
+			log.Errorf("could not backfill %s quotes of %s: %v", interval, a.Symbol, err)
+			return false
+		}
+	}
+	log.Infof("Backfilled quotes of %s", a.Symbol)
+	return true
+}
+
+func valueOr(value, defaultValue string) string {
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
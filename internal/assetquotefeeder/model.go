@@ -0,0 +1,62 @@
+package assetquotefeeder
+
+import (
+	"errors"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+var ShuttingDownErr error = errors.New("quote feeder is shutting down")
+
+// AssetStore is what the feeder needs of the assets, *asset.Service in the app
+type AssetStore interface {
+	GetActiveAssets() ([]asset.SimpleAssetDTO, error)
+	RecordQuoteFailure(assetId int64, quoteErr error, threshold int) (*asset.Asset, error)
+	ResetQuoteFailures(assetIds []int64) error
+}
+
+// ParamStore keeps whether the initial quote history was loaded, *param.Service in the app
+type ParamStore interface {
+	IsInitialDataInserted() (bool, error)
+	SetInitialDataInserted() error
+}
+
+// ValidationConfig controls when the feeder falls back to another provider and when it flags quotes
+type ValidationConfig struct {
+	// StaleAfter is how old the latest bar may be before the provider's data is considered stale
+	StaleAfter time.Duration
+	// Tolerance is the largest accepted relative difference between the closes of two providers
+	Tolerance float64
+}
+
+// ScrapeConfig controls multi asset scrapes
+type ScrapeConfig struct {
+	// Concurrency is how many assets are scraped at the same time
+	Concurrency int
+	// FailureThreshold is the number of consecutive failed scrapes after which an asset
+	// is suspended until an admin resumes it, zero never suspends
+	FailureThreshold int
+}
+
+// ScrapeResult is the outcome of one asset and interval in a multi asset scrape
+type ScrapeResult struct {
+	AssetId  int64
+	Symbol   string
+	Interval string
+	Err      error
+	// FailureCount is the consecutive failed runs of the asset including this one
+	FailureCount int
+	Suspended    bool
+}
+
+// FailedResults returns the results of the assets that could not be scraped
+func FailedResults(results []ScrapeResult) []ScrapeResult {
+	var failed []ScrapeResult
+	for _, result := range results {
+		if result.Err != nil {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
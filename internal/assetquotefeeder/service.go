@@ -1,76 +1,331 @@
 package assetquotefeeder
 
 import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/karataydev/portfoliomanbackend/internal/asset"
-	"github.com/karataydev/portfoliomanbackend/internal/param"
-	"github.com/svarlamov/goyhfin"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/internal/tradingcalendar"
+	"github.com/karataydev/portfoliomanbackend/pkg/lifecycle"
 )
 
 type Service struct {
-	assetService *asset.Service
-	paramService *param.Service
+	assetService AssetStore
+	paramService ParamStore
+	providers    *quoteprovider.Registry
+	calendars    *tradingcalendar.Registry
+	validation   ValidationConfig
+	scrape       ScrapeConfig
 	quoteChannel chan asset.AssetQuoteChanData
+
+	// stopping rejects new scrapes, inflight tracks the running ones so the channel is closed only after them
+	mu       sync.Mutex
+	stopping bool
+	inflight sync.WaitGroup
 }
 
-func NewService(assetService *asset.Service, paramService *param.Service, quoteChannel chan asset.AssetQuoteChanData) *Service {
+func NewService(assetService AssetStore, paramService ParamStore, providers *quoteprovider.Registry, calendars *tradingcalendar.Registry, validation ValidationConfig, scrape ScrapeConfig, quoteChannel chan asset.AssetQuoteChanData) *Service {
+	if scrape.Concurrency <= 0 {
+		scrape.Concurrency = 1
+	}
 	return &Service{
 		assetService: assetService,
 		paramService: paramService,
+		providers:    providers,
+		calendars:    calendars,
+		validation:   validation,
+		scrape:       scrape,
 		quoteChannel: quoteChannel,
 	}
 }
 
-func (s *Service) InsertInitialData() error {
+// InsertInitialData loads a year of quotes of every asset on the first start. A load stopped by ctx
+// or the feeder shutting down isn't marked done, so it runs again on the next start.
+func (s *Service) InsertInitialData(ctx context.Context) error {
 	is, err := s.paramService.IsInitialDataInserted()
 	if err != nil {
-		log.Fatalf("could not run IsInitialDataInserted: %v", err)
+		return fmt.Errorf("could not check the initial quotes: %w", err)
 	}
-	if !is {
-		if err := s.ScrapeAllAssets(goyhfin.OneYear, goyhfin.OneHour); err != nil {
-			log.Fatalf("could not run scrape asssets: %v", err)
-		}
-		s.paramService.SetInitialDataInserted()
-	} else {
+	if is {
 		log.Info("Asset quote already inserted...")
+		return nil
+	}
 
+	now := time.Now()
+	results, err := s.ScrapeAllAssets(ctx, now.AddDate(-1, 0, 0), now, quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay)
+	if err != nil {
+		return fmt.Errorf("could not scrape the initial quotes: %w", err)
 	}
-	return nil
+	// the gap scanner backfills the assets that failed
+	for _, failed := range FailedResults(results) {
+		log.Errorf("could not scrape initial %s quotes of %s: %v", failed.Interval, failed.Symbol, failed.Err)
+	}
+	return s.paramService.SetInitialDataInserted()
 }
 
-func (s *Service) ScrapeAllAssets(rangeStr string, intervalStr string) error {
-	assets, err := s.assetService.GetAssets()
+// ScrapeAllAssets fetches the quotes of every active asset whose exchange had a session in the period
+// for each of the intervals, a few assets at a time. A failing asset doesn't stop the others, its errors
+// are returned in the results and the run counts once towards its suspension, however many intervals failed.
+// Once ctx is done no further asset or interval is started, the run returns ctx's error and counts nothing.
+func (s *Service) ScrapeAllAssets(ctx context.Context, from, to time.Time, intervals ...string) ([]ScrapeResult, error) {
+	assets, err := s.assetService.GetActiveAssets()
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	var due []asset.SimpleAssetDTO
+	for _, a := range assets {
+		if s.calendars.For(a.Exchange).HasSessionBetween(from, to) {
+			due = append(due, a)
+		}
+	}
+
+	results := make([][]ScrapeResult, len(due))
+	sem := make(chan struct{}, s.scrape.Concurrency)
+	var wg sync.WaitGroup
+	for i, a := range due {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(i int, a asset.SimpleAssetDTO) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			for _, interval := range intervals {
+				if ctx.Err() != nil {
+					return
+				}
+				results[i] = append(results[i], ScrapeResult{AssetId: a.Id, Symbol: a.Symbol, Interval: interval, Err: s.ScrapeAsset(a, from, to, interval)})
+			}
+		}(i, a)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return flatten(results), err
 	}
 
-	for _, asset := range assets {
-		err = s.ScrapeAsset(asset, rangeStr, intervalStr)
+	var scraped [][]ScrapeResult
+	for _, assetResults := range results {
+		for _, result := range assetResults {
+			if result.Err == ShuttingDownErr {
+				return flatten(scraped), ShuttingDownErr
+			}
+		}
+		scraped = append(scraped, assetResults)
+	}
+
+	s.recordOutcomes(scraped)
+	return flatten(scraped), nil
+}
+
+// recordOutcomes resets the failure counts of the scraped assets and counts the failures, once per
+// asset whichever of its intervals failed. When every asset failed the provider is more likely down
+// than the assets, so nothing is counted.
+func (s *Service) recordOutcomes(results [][]ScrapeResult) {
+	var succeeded []int64
+	var failed [][]ScrapeResult
+	for _, assetResults := range results {
+		if len(assetResults) == 0 {
+			continue
+		}
+		if len(FailedResults(assetResults)) == 0 {
+			succeeded = append(succeeded, assetResults[0].AssetId)
+		} else {
+			failed = append(failed, assetResults)
+		}
+	}
+	if len(succeeded) == 0 {
+		if len(failed) > 0 {
+			log.Errorf("All %d asset scrapes failed, not counting them against the assets", len(failed))
+		}
+		return
+	}
+
+	for _, assetResults := range failed {
+		first := FailedResults(assetResults)[0]
+		for _, result := range FailedResults(assetResults) {
+			log.Warnf("could not scrape %s quotes of %s: %v", result.Interval, result.Symbol, result.Err)
+		}
+		a, err := s.assetService.RecordQuoteFailure(first.AssetId, first.Err, s.scrape.FailureThreshold)
 		if err != nil {
-			return err
+			log.Errorf("could not record quote failure of %s: %v", first.Symbol, err)
+			continue
+		}
+		for i := range assetResults {
+			assetResults[i].FailureCount = a.QuoteFailureCount
+			assetResults[i].Suspended = a.QuoteSuspendedAt.Valid
+		}
+		if a.QuoteSuspendedAt.Valid {
+			log.Warnf("Quotes of %s are suspended after %d consecutive failures", first.Symbol, a.QuoteFailureCount)
 		}
 	}
 
-	return nil
+	if err := s.assetService.ResetQuoteFailures(succeeded); err != nil {
+		log.Errorf("could not reset quote failures: %v", err)
+	}
+}
+
+func flatten(results [][]ScrapeResult) []ScrapeResult {
+	var flat []ScrapeResult
+	for _, assetResults := range results {
+		flat = append(flat, assetResults...)
+	}
+	return flat
+}
+
+// ScrapeAsset fetches the quotes of the asset and queues them for saving
+func (s *Service) ScrapeAsset(asset asset.SimpleAssetDTO, from, to time.Time, interval string) error {
+	return s.scrapeAsset(asset, from, to, interval, nil)
+}
+
+// ScrapeAssetAndWait fetches the quotes of the asset and waits until the ingestion saved them
+func (s *Service) ScrapeAssetAndWait(a asset.SimpleAssetDTO, from, to time.Time, interval string) error {
+	ack := asset.NewQuoteAck()
+	if err := s.scrapeAsset(a, from, to, interval, ack); err != nil {
+		return err
+	}
+	return ack.Wait()
 }
 
-func (s *Service) ScrapeAsset(asset asset.SimpleAssetDTO, rangeStr string, intervalStr string) error {
-	resp, err := goyhfin.GetTickerData(asset.Symbol, rangeStr, intervalStr, false)
+func (s *Service) scrapeAsset(asset asset.SimpleAssetDTO, from, to time.Time, interval string, ack *asset.QuoteAck) error {
+	if !s.begin() {
+		return ShuttingDownErr
+	}
+	defer s.inflight.Done()
+
+	chain := s.providers.Chain(asset.Symbol)
+	bars, source, err := s.fetchBars(chain, asset.Symbol, from, to, interval)
 	if err != nil {
 		return err
 	}
-	s.AssetResponseToChannel(asset.Id, resp)
+
+	anomalies := s.crossCheck(chain, source, asset.Symbol, bars, from, to, interval)
+	s.BarsToChannel(asset.Id, asset.Symbol, source, interval, bars, anomalies, ack)
 
 	return nil
 }
 
-func (s *Service) AssetResponseToChannel(assetId int64, resp goyhfin.ChartQueryResponse) {
-	for _, quote := range resp.Quotes {
+func (s *Service) begin() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopping {
+		return false
+	}
+	s.inflight.Add(1)
+	return true
+}
+
+// Stop rejects new scrapes and waits for the running ones to send their bars. Scrapes
+// cut short, like a multi asset run, are picked up later by the gap scanner.
+func (s *Service) Stop(ctx context.Context) error {
+	s.mu.Lock()
+	s.stopping = true
+	s.mu.Unlock()
+
+	return lifecycle.Wait(ctx, s.inflight.Wait)
+}
+
+// fetchBars tries the providers in order until one returns fresh data. When every provider is
+// stale the first stale data is used, as the market may simply have been closed.
+func (s *Service) fetchBars(chain []quoteprovider.QuoteProvider, symbol string, from, to time.Time, interval string) ([]quoteprovider.Bar, string, error) {
+	var staleBars []quoteprovider.Bar
+	var staleSource string
+	var lastErr error
+
+	for _, provider := range chain {
+		bars, err := provider.HistoricalBars(symbol, from, to, interval)
+		if err != nil {
+			log.Warnf("Quote provider %s failed for %s: %v", provider.Name(), symbol, err)
+			lastErr = err
+			continue
+		}
+		if quoteprovider.IsStale(bars, to, s.validation.StaleAfter) {
+			log.Warnf("Quote provider %s returned stale data for %s", provider.Name(), symbol)
+			if staleBars == nil && len(bars) > 0 {
+				staleBars, staleSource = bars, provider.Name()
+			}
+			continue
+		}
+		return bars, provider.Name(), nil
+	}
+
+	if staleBars != nil {
+		return staleBars, staleSource, nil
+	}
+	if lastErr == nil {
+		lastErr = quoteprovider.NoDataErr
+	}
+	return nil, "", lastErr
+}
+
+// crossCheck compares the bars with the first other provider that has data for the same period
+// and returns the anomalies keyed by quote time
+func (s *Service) crossCheck(chain []quoteprovider.QuoteProvider, source, symbol string, bars []quoteprovider.Bar, from, to time.Time, interval string) map[time.Time]*asset.QuoteAnomaly {
+	anomalies := make(map[time.Time]*asset.QuoteAnomaly)
+	if s.validation.Tolerance <= 0 {
+		return anomalies
+	}
+
+	for _, provider := range chain {
+		if provider.Name() == source {
+			continue
+		}
+		reference, err := provider.HistoricalBars(symbol, from, to, interval)
+		if err != nil || len(reference) == 0 {
+			continue
+		}
+
+		for _, discrepancy := range quoteprovider.CompareBars(bars, reference, interval, s.validation.Tolerance) {
+			anomalies[discrepancy.Bar.ClosesAt] = &asset.QuoteAnomaly{
+				ReferenceProvider: sql.NullString{String: provider.Name(), Valid: true},
+				ReferenceQuote:    sql.NullFloat64{Float64: discrepancy.ReferenceClose, Valid: true},
+				Deviation:         sql.NullFloat64{Float64: discrepancy.Deviation, Valid: true},
+				Reason:            asset.AnomalyReasonDiscrepancy,
+			}
+		}
+		break
+	}
+	return anomalies
+}
+
+// LookupSymbol checks the symbol against its quote provider and returns what it knows about it
+func (s *Service) LookupSymbol(symbol string) (*quoteprovider.SymbolInfo, error) {
+	return s.providers.For(symbol).LookupSymbol(symbol)
+}
+
+// BarsToChannel queues the bars for saving under the symbol of the asset, providers may
+// return it in another form or not at all. The ack, when given, is told once each bar is saved.
+func (s *Service) BarsToChannel(assetId int64, symbol, provider, interval string, bars []quoteprovider.Bar, anomalies map[time.Time]*asset.QuoteAnomaly, ack *asset.QuoteAck) {
+	ack.Add(len(bars))
+	for _, bar := range bars {
 		s.quoteChannel <- asset.AssetQuoteChanData{
-			Symbol:    resp.Symbol,
+			Symbol:    symbol,
 			AssetId:   assetId,
-			Quote:     quote.Close,
-			QuoteTime: quote.ClosesAt,
+			Interval:  interval,
+			Open:      bar.Open,
+			High:      bar.High,
+			Low:       bar.Low,
+			Quote:     bar.Close,
+			Volume:    bar.Volume,
+			QuoteTime: bar.ClosesAt,
+			Provider:  provider,
+			Anomaly:   anomalies[bar.ClosesAt],
+			Ack:       ack,
 		}
 	}
 }
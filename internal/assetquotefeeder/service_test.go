@@ -0,0 +1,276 @@
+package assetquotefeeder
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/internal/tradingcalendar"
+)
+
+// a monday, the fallback calendar trades weekdays around the clock
+var periodStart = time.Date(2026, 10, 12, 0, 0, 0, 0, time.UTC)
+
+// fakeAssets keeps the active assets and their failure counts in memory
+type fakeAssets struct {
+	mu       sync.Mutex
+	assets   []asset.SimpleAssetDTO
+	failures map[int64]int
+}
+
+func newFakeAssets(assets ...asset.SimpleAssetDTO) *fakeAssets {
+	return &fakeAssets{assets: assets, failures: make(map[int64]int)}
+}
+
+func (f *fakeAssets) GetActiveAssets() ([]asset.SimpleAssetDTO, error) {
+	return f.assets, nil
+}
+
+func (f *fakeAssets) RecordQuoteFailure(assetId int64, quoteErr error, threshold int) (*asset.Asset, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.failures[assetId]++
+	a := &asset.Asset{Id: assetId, QuoteFailureCount: f.failures[assetId]}
+	if threshold > 0 && a.QuoteFailureCount >= threshold {
+		a.QuoteSuspendedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	return a, nil
+}
+
+func (f *fakeAssets) ResetQuoteFailures(assetIds []int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, assetId := range assetIds {
+		delete(f.failures, assetId)
+	}
+	return nil
+}
+
+// newTestService builds a feeder over the providers, the first is the default and the others its fallbacks
+func newTestService(t *testing.T, assets AssetStore, validation ValidationConfig, providers ...*quoteprovider.FakeProvider) (*Service, chan asset.AssetQuoteChanData) {
+	t.Helper()
+	calendars, err := tradingcalendar.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := quoteprovider.NewRegistry(providers[0].Name(), nil)
+	var fallbacks []string
+	for i, provider := range providers {
+		registry.Register(provider)
+		if i > 0 {
+			fallbacks = append(fallbacks, provider.Name())
+		}
+	}
+	registry.SetFallbacks(fallbacks...)
+
+	quoteChannel := make(chan asset.AssetQuoteChanData, 1000)
+	service := NewService(assets, nil, registry, calendars, validation, ScrapeConfig{Concurrency: 2, FailureThreshold: 3}, quoteChannel)
+	return service, quoteChannel
+}
+
+// hourlyBars returns count bars closing every hour after from, all closing at price
+func hourlyBars(from time.Time, count int, price float64) []quoteprovider.Bar {
+	bars := make([]quoteprovider.Bar, count)
+	for i := range bars {
+		closesAt := from.Add(time.Duration(i+1) * time.Hour)
+		bars[i] = quoteprovider.Bar{OpensAt: closesAt.Add(-time.Hour), ClosesAt: closesAt, Open: price, High: price, Low: price, Close: price, Volume: 100}
+	}
+	return bars
+}
+
+func drain(quoteChannel chan asset.AssetQuoteChanData) []asset.AssetQuoteChanData {
+	var quotes []asset.AssetQuoteChanData
+	for {
+		select {
+		case quote := <-quoteChannel:
+			quotes = append(quotes, quote)
+		default:
+			return quotes
+		}
+	}
+}
+
+func TestScrapeAllAssetsScrapesEveryInterval(t *testing.T) {
+	from, to := periodStart, periodStart.Add(24*time.Hour)
+	provider := quoteprovider.NewFakeProvider("")
+	provider.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 100)...)
+	provider.AddBars("AAPL", quoteprovider.IntervalOneDay, quoteprovider.Bar{OpensAt: from, ClosesAt: to, Close: 100})
+	provider.AddBars("MSFT", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 200)...)
+
+	assets := newFakeAssets(
+		asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"},
+		asset.SimpleAssetDTO{Id: 2, Symbol: "MSFT"},
+		asset.SimpleAssetDTO{Id: 3, Symbol: "GONE"},
+	)
+	service, quoteChannel := newTestService(t, assets, ValidationConfig{StaleAfter: 48 * time.Hour}, provider)
+
+	results, err := service.ScrapeAllAssets(context.Background(), from, to, quoteprovider.IntervalOneHour, quoteprovider.IntervalOneDay)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 6 {
+		t.Fatalf("got %d results, want one per asset and interval", len(results))
+	}
+	for _, result := range results {
+		wantErr := result.Symbol == "GONE" || (result.Symbol == "MSFT" && result.Interval == quoteprovider.IntervalOneDay)
+		if (result.Err != nil) != wantErr {
+			t.Errorf("%s %s: got error %v", result.Symbol, result.Interval, result.Err)
+		}
+	}
+
+	counts := make(map[string]int)
+	for _, quote := range drain(quoteChannel) {
+		counts[quote.Symbol+" "+quote.Interval]++
+	}
+	if counts["AAPL 1h"] != 24 || counts["AAPL 1d"] != 1 || counts["MSFT 1h"] != 24 || counts["MSFT 1d"] != 0 {
+		t.Errorf("got quotes %v", counts)
+	}
+
+	// a run counts once against an asset, however many of its intervals failed
+	if assets.failures[2] != 1 || assets.failures[3] != 1 {
+		t.Errorf("got failures %v, want one for MSFT and GONE", assets.failures)
+	}
+	if _, ok := assets.failures[1]; ok {
+		t.Errorf("AAPL failures should be reset")
+	}
+}
+
+func TestScrapeAllAssetsDoesNotCountFailuresWhenEveryAssetFails(t *testing.T) {
+	from, to := periodStart, periodStart.Add(24*time.Hour)
+	provider := quoteprovider.NewFakeProvider("")
+	provider.SetError("AAPL", errors.New("provider down"))
+	provider.SetError("MSFT", errors.New("provider down"))
+
+	assets := newFakeAssets(asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"}, asset.SimpleAssetDTO{Id: 2, Symbol: "MSFT"})
+	service, _ := newTestService(t, assets, ValidationConfig{StaleAfter: 48 * time.Hour}, provider)
+
+	results, err := service.ScrapeAllAssets(context.Background(), from, to, quoteprovider.IntervalOneHour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed := FailedResults(results); len(failed) != 2 {
+		t.Fatalf("got %d failed results, want 2", len(failed))
+	}
+	if len(assets.failures) != 0 {
+		t.Errorf("got failures %v, want none counted", assets.failures)
+	}
+}
+
+func TestScrapeAssetFallsBackWhenProviderFails(t *testing.T) {
+	from, to := periodStart, periodStart.Add(24*time.Hour)
+	primary := quoteprovider.NewFakeProvider("primary")
+	primary.SetError("AAPL", errors.New("rate limited"))
+	backup := quoteprovider.NewFakeProvider("backup")
+	backup.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 100)...)
+
+	service, quoteChannel := newTestService(t, newFakeAssets(), ValidationConfig{StaleAfter: 48 * time.Hour}, primary, backup)
+
+	if err := service.ScrapeAsset(asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"}, from, to, quoteprovider.IntervalOneHour); err != nil {
+		t.Fatal(err)
+	}
+	quotes := drain(quoteChannel)
+	if len(quotes) != 24 {
+		t.Fatalf("got %d quotes, want 24", len(quotes))
+	}
+	for _, quote := range quotes {
+		if quote.Provider != "backup" {
+			t.Fatalf("got quote of %s, want backup", quote.Provider)
+		}
+	}
+}
+
+func TestScrapeAssetPrefersFreshFallbackOverStaleData(t *testing.T) {
+	from, to := periodStart, periodStart.Add(4*24*time.Hour)
+	primary := quoteprovider.NewFakeProvider("primary")
+	primary.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 100)...)
+	backup := quoteprovider.NewFakeProvider("backup")
+	backup.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 4*24, 100)...)
+
+	service, quoteChannel := newTestService(t, newFakeAssets(), ValidationConfig{StaleAfter: 24 * time.Hour}, primary, backup)
+
+	if err := service.ScrapeAsset(asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"}, from, to, quoteprovider.IntervalOneHour); err != nil {
+		t.Fatal(err)
+	}
+	quotes := drain(quoteChannel)
+	if len(quotes) != 4*24 || quotes[0].Provider != "backup" {
+		t.Fatalf("got %d quotes of %s, want the 96 fresh ones of backup", len(quotes), quotes[0].Provider)
+	}
+}
+
+func TestScrapeAssetUsesStaleDataWhenEveryProviderIsStale(t *testing.T) {
+	from, to := periodStart, periodStart.Add(4*24*time.Hour)
+	primary := quoteprovider.NewFakeProvider("primary")
+	primary.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 100)...)
+	backup := quoteprovider.NewFakeProvider("backup")
+	backup.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 12, 100)...)
+
+	service, quoteChannel := newTestService(t, newFakeAssets(), ValidationConfig{StaleAfter: 24 * time.Hour}, primary, backup)
+
+	if err := service.ScrapeAsset(asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"}, from, to, quoteprovider.IntervalOneHour); err != nil {
+		t.Fatal(err)
+	}
+	quotes := drain(quoteChannel)
+	if len(quotes) != 24 || quotes[0].Provider != "primary" {
+		t.Fatalf("got %d quotes of %s, want the 24 stale ones of primary", len(quotes), quotes[0].Provider)
+	}
+}
+
+func TestScrapeAssetQuarantinesBarsDisagreeingWithTheReference(t *testing.T) {
+	from, to := periodStart, periodStart.Add(24*time.Hour)
+	primary := quoteprovider.NewFakeProvider("primary")
+	primary.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 100)...)
+	reference := hourlyBars(from, 24, 101)
+	reference[5].Close = 120
+	backup := quoteprovider.NewFakeProvider("backup")
+	backup.AddBars("AAPL", quoteprovider.IntervalOneHour, reference...)
+
+	service, quoteChannel := newTestService(t, newFakeAssets(), ValidationConfig{StaleAfter: 48 * time.Hour, Tolerance: 0.05}, primary, backup)
+
+	if err := service.ScrapeAsset(asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"}, from, to, quoteprovider.IntervalOneHour); err != nil {
+		t.Fatal(err)
+	}
+	var anomalies []asset.AssetQuoteChanData
+	for _, quote := range drain(quoteChannel) {
+		if quote.Anomaly != nil {
+			anomalies = append(anomalies, quote)
+		}
+	}
+	if len(anomalies) != 1 {
+		t.Fatalf("got %d anomalies, want 1", len(anomalies))
+	}
+	anomaly := anomalies[0]
+	if !anomaly.QuoteTime.Equal(reference[5].ClosesAt) {
+		t.Errorf("got anomaly at %v, want %v", anomaly.QuoteTime, reference[5].ClosesAt)
+	}
+	if anomaly.Anomaly.ReferenceProvider.String != "backup" || anomaly.Anomaly.ReferenceQuote.Float64 != 120 {
+		t.Errorf("got reference %s %v, want backup 120", anomaly.Anomaly.ReferenceProvider.String, anomaly.Anomaly.ReferenceQuote.Float64)
+	}
+}
+
+func TestScrapeAllAssetsStopsWhenContextIsDone(t *testing.T) {
+	from, to := periodStart, periodStart.Add(24*time.Hour)
+	provider := quoteprovider.NewFakeProvider("")
+	provider.AddBars("AAPL", quoteprovider.IntervalOneHour, hourlyBars(from, 24, 100)...)
+
+	assets := newFakeAssets(asset.SimpleAssetDTO{Id: 1, Symbol: "AAPL"}, asset.SimpleAssetDTO{Id: 2, Symbol: "GONE"})
+	service, quoteChannel := newTestService(t, assets, ValidationConfig{StaleAfter: 48 * time.Hour}, provider)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := service.ScrapeAllAssets(ctx, from, to, quoteprovider.IntervalOneHour)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if quotes := drain(quoteChannel); len(quotes) != 0 {
+		t.Errorf("got %d quotes after the context was done", len(quotes))
+	}
+	if len(assets.failures) != 0 {
+		t.Errorf("got failures %v, want none counted", assets.failures)
+	}
+}
@@ -9,6 +9,10 @@ import (
 func JwtAuthMiddleware(tokenService *TokenService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		token := c.Get("Authorization")
+		// EventSource and WebSocket clients can't set headers, streams pass the token in the query
+		if token == "" && isStreamRequest(c) {
+			token = c.Query("access_token")
+		}
 		if token == "" {
 			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
 				"error": "Missing authorization token",
@@ -32,3 +36,25 @@ func JwtAuthMiddleware(tokenService *TokenService) fiber.Handler {
 		return c.Next()
 	}
 }
+
+func isStreamRequest(c *fiber.Ctx) bool {
+	return strings.Contains(c.Get("Accept"), "text/event-stream") || strings.EqualFold(c.Get("Upgrade"), "websocket")
+}
+
+func AdminMiddleware(adminEmails []string) fiber.Handler {
+	admins := make(map[string]bool, len(adminEmails))
+	for _, email := range adminEmails {
+		admins[strings.ToLower(email)] = true
+	}
+
+	return func(c *fiber.Ctx) error {
+		email, ok := c.Locals("userEmail").(string)
+		if !ok || !admins[strings.ToLower(email)] {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "Admin access required",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,30 @@
+package cachemetrics
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/karataydev/portfoliomanbackend/pkg/cache"
+)
+
+// Source is a service keeping in-process caches
+type Source interface {
+	GetCacheMetrics() map[string]cache.Stats
+}
+
+// Handler reports the hit and miss counters of the caches of every source
+type Handler struct {
+	sources []Source
+}
+
+func NewHandler(sources ...Source) *Handler {
+	return &Handler{sources: sources}
+}
+
+func (h *Handler) GetMetrics(c *fiber.Ctx) error {
+	metrics := make(map[string]cache.Stats)
+	for _, source := range h.sources {
+		for name, stats := range source.GetCacheMetrics() {
+			metrics[name] = stats
+		}
+	}
+	return c.JSON(metrics)
+}
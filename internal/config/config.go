@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2/log"
@@ -22,6 +23,77 @@ type Config struct {
 	PrivateKey     string
 	GoogleClientId string
 	TokenDuration  time.Duration
+	AdminEmails    []string
+	// AssetRequestAutoApprove adds requested assets without waiting for an admin
+	AssetRequestAutoApprove bool
+
+	// QuoteProvider is the default quote source, QuoteProviderOverrides maps symbols to other sources
+	QuoteProvider          string
+	QuoteProviderOverrides map[string]string
+	QuoteCSVDir            string
+	QuoteHTTPHistoryURL    string
+	QuoteHTTPLatestURL     string
+	QuoteHTTPLookupURL     string
+	QuoteHTTPAPIKey        string
+	// QuoteFakeProvider registers the in-memory "fake" provider, for development without network access
+	QuoteFakeProvider bool
+
+	// QuoteProviderRateLimits caps the requests per minute of a provider, e.g. yahoo=60
+	QuoteProviderRateLimits map[string]int
+
+	// QuoteFallbackProviders are tried in order when a provider fails or its data is older than QuoteStaleAfter
+	QuoteFallbackProviders []string
+	QuoteStaleAfter        time.Duration
+	// QuoteAnomalyTolerance is the relative close difference between providers above which quotes are quarantined
+	QuoteAnomalyTolerance float64
+
+	// BackfillLookbackDays is how far back quote gaps are looked for, BackfillBatchSize caps the jobs run per day
+	BackfillLookbackDays int
+	BackfillBatchSize    int
+	// BackfillMaxAttempts is how often a missing range is fetched before the scanner gives up on it
+	BackfillMaxAttempts int
+
+	// TradingCalendarDir holds calendar json files overriding the bundled ones
+	TradingCalendarDir string
+
+	// LiveQuotePollInterval is how often held assets are quoted during market hours, zero disables polling
+	LiveQuotePollInterval time.Duration
+
+	// QuoteQueueSize bounds the quotes waiting to be saved, the ingest workers save them in batches
+	QuoteQueueSize           int
+	QuoteIngestWorkers       int
+	QuoteIngestBatchSize     int
+	QuoteIngestFlushInterval time.Duration
+
+	// ShutdownTimeout bounds the graceful shutdown on SIGTERM
+	ShutdownTimeout time.Duration
+
+	// SchedulerTimezone is the time zone cron expressions of the jobs are evaluated in
+	SchedulerTimezone string
+	// DailyQuoteCron schedules the daily quote insert, DailyQuoteCatchUp runs it on start if a run was missed
+	DailyQuoteCron    string
+	DailyQuoteCatchUp bool
+	// SchedulerLease is how long a job run stays claimed by an instance without renewal
+	SchedulerLease time.Duration
+	// JobRetries is how often a failed job run is retried, starting after JobRetryBackoff and doubling
+	JobRetries      int
+	JobRetryBackoff time.Duration
+
+	// QuoteScrapeConcurrency is how many assets are scraped at once, QuoteFailureThreshold
+	// how many consecutive failed scrapes suspend an asset (0 never suspends)
+	QuoteScrapeConcurrency int
+	QuoteFailureThreshold  int
+
+	// QuoteRetentionDays is how many days bars of an interval are kept, e.g. 1h=90,1d=1825. Older bars are
+	// rolled up into their QuoteDownsample interval, e.g. 1h=1d, or archived when they have none.
+	QuoteRetentionDays map[string]int
+	QuoteDownsample    map[string]string
+	QuoteRetentionCron string
+
+	// QuoteCacheTTL bounds how long latest and previous close quotes are cached, ValuationCacheTTL
+	// the portfolio list valuations. Both are also dropped as soon as a quote of their assets arrives.
+	QuoteCacheTTL     time.Duration
+	ValuationCacheTTL time.Duration
 }
 
 var AppConfig Config
@@ -44,6 +116,56 @@ func Load() error {
 		PrivateKey:     getEnv("PRIVATE_KEY", ""),
 		GoogleClientId: getEnv("GOOGLE_CLIENT_ID", ""),
 		TokenDuration:  time.Duration(getEnvAsInt("TOKEN_DURATION_MINUTES", 60*24*30)) * time.Minute,
+		AdminEmails:    getEnvAsList("ADMIN_EMAILS", nil),
+
+		AssetRequestAutoApprove: getEnvAsBool("ASSET_REQUEST_AUTO_APPROVE", false),
+
+		QuoteProvider:          getEnv("QUOTE_PROVIDER", "yahoo"),
+		QuoteProviderOverrides: getEnvAsMap("QUOTE_PROVIDER_OVERRIDES", map[string]string{}),
+		QuoteCSVDir:            getEnv("QUOTE_CSV_DIR", "./data/quotes"),
+		QuoteHTTPHistoryURL:    getEnv("QUOTE_HTTP_HISTORY_URL", ""),
+		QuoteHTTPLatestURL:     getEnv("QUOTE_HTTP_LATEST_URL", ""),
+		QuoteHTTPLookupURL:     getEnv("QUOTE_HTTP_LOOKUP_URL", ""),
+		QuoteHTTPAPIKey:        getEnv("QUOTE_HTTP_API_KEY", ""),
+		QuoteFakeProvider:      getEnvAsBool("QUOTE_FAKE_PROVIDER", false),
+
+		QuoteProviderRateLimits: getEnvAsIntMap("QUOTE_PROVIDER_RATE_LIMITS", map[string]int{"yahoo": 60}),
+
+		QuoteFallbackProviders: getEnvAsList("QUOTE_FALLBACK_PROVIDERS", nil),
+		QuoteStaleAfter:        time.Duration(getEnvAsInt("QUOTE_STALE_AFTER_HOURS", 96)) * time.Hour,
+		QuoteAnomalyTolerance:  float64(getEnvAsInt("QUOTE_ANOMALY_TOLERANCE_PERCENT", 5)) / 100,
+
+		BackfillLookbackDays: getEnvAsInt("BACKFILL_LOOKBACK_DAYS", 365),
+		BackfillBatchSize:    getEnvAsInt("BACKFILL_BATCH_SIZE", 50),
+		BackfillMaxAttempts:  getEnvAsInt("BACKFILL_MAX_ATTEMPTS", 3),
+
+		TradingCalendarDir: getEnv("TRADING_CALENDAR_DIR", ""),
+
+		LiveQuotePollInterval: time.Duration(getEnvAsInt("LIVE_QUOTE_POLL_SECONDS", 60)) * time.Second,
+
+		QuoteQueueSize:           getEnvAsInt("QUOTE_QUEUE_SIZE", 10000),
+		QuoteIngestWorkers:       getEnvAsInt("QUOTE_INGEST_WORKERS", 2),
+		QuoteIngestBatchSize:     getEnvAsInt("QUOTE_INGEST_BATCH_SIZE", 500),
+		QuoteIngestFlushInterval: time.Duration(getEnvAsInt("QUOTE_INGEST_FLUSH_MS", 1000)) * time.Millisecond,
+
+		ShutdownTimeout: time.Duration(getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 30)) * time.Second,
+
+		SchedulerTimezone: getEnv("SCHEDULER_TIMEZONE", "Local"),
+		DailyQuoteCron:    getEnv("DAILY_QUOTE_CRON", "22 2 * * *"),
+		DailyQuoteCatchUp: getEnvAsBool("DAILY_QUOTE_CATCH_UP", true),
+		SchedulerLease:    time.Duration(getEnvAsInt("SCHEDULER_LEASE_SECONDS", 60)) * time.Second,
+		JobRetries:        getEnvAsInt("JOB_RETRIES", 2),
+		JobRetryBackoff:   time.Duration(getEnvAsInt("JOB_RETRY_BACKOFF_SECONDS", 60)) * time.Second,
+
+		QuoteScrapeConcurrency: getEnvAsInt("QUOTE_SCRAPE_CONCURRENCY", 4),
+		QuoteFailureThreshold:  getEnvAsInt("QUOTE_FAILURE_THRESHOLD", 5),
+
+		QuoteRetentionDays: getEnvAsIntMap("QUOTE_RETENTION_DAYS", map[string]int{"1h": 90, "1d": 1825}),
+		QuoteDownsample:    getEnvAsMap("QUOTE_DOWNSAMPLE", map[string]string{"1h": "1d"}),
+		QuoteRetentionCron: getEnv("QUOTE_RETENTION_CRON", "0 4 * * *"),
+
+		QuoteCacheTTL:     time.Duration(getEnvAsInt("QUOTE_CACHE_TTL_SECONDS", 300)) * time.Second,
+		ValuationCacheTTL: time.Duration(getEnvAsInt("VALUATION_CACHE_TTL_SECONDS", 60)) * time.Second,
 	}
 
 	log.Info("Configuration loaded successfully")
@@ -69,3 +191,61 @@ func getEnvAsInt(key string, defaultValue int) int {
 	}
 	return defaultValue
 }
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	valueStr := getEnv(key, "")
+	if value, err := strconv.ParseBool(valueStr); err == nil {
+		return value
+	}
+	return defaultValue
+}
+
+func getEnvAsList(key string, defaultValue []string) []string {
+	valueStr := getEnv(key, "")
+	if valueStr == "" {
+		return defaultValue
+	}
+	var values []string
+	for _, value := range strings.Split(valueStr, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvAsMap parses comma separated key=value pairs
+func getEnvAsMap(key string, defaultValue map[string]string) map[string]string {
+	values := getEnvAsList(key, nil)
+	if values == nil {
+		return defaultValue
+	}
+	result := make(map[string]string, len(values))
+	for _, pair := range values {
+		k, v, found := strings.Cut(pair, "=")
+		if !found {
+			log.Warnf("Ignoring malformed %s entry: %s", key, pair)
+			continue
+		}
+		result[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return result
+}
+
+// getEnvAsIntMap parses comma separated key=number pairs
+func getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	values := getEnvAsMap(key, nil)
+	if values == nil {
+		return defaultValue
+	}
+	result := make(map[string]int, len(values))
+	for k, v := range values {
+		value, err := strconv.Atoi(v)
+		if err != nil {
+			log.Warnf("Ignoring malformed %s entry: %s=%s", key, k, v)
+			continue
+		}
+		result[k] = value
+	}
+	return result
+}
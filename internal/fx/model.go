@@ -0,0 +1,48 @@
+package fx
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+// DefaultCurrency is the currency FX rates are quoted against
+const DefaultCurrency = "USD"
+
+var RateNotFoundErr error = errors.New("fx rate not found")
+var UnsupportedCurrencyErr error = errors.New("unsupported currency")
+
+// RateRequest is a rate to convert one unit of From into To at Time
+type RateRequest struct {
+	From string
+	To   string
+	Time time.Time
+}
+
+// Converter converts between two currencies using preloaded historical rates
+type Converter struct {
+	from []asset.AssetQuote
+	to   []asset.AssetQuote
+}
+
+// At returns the rate to convert one unit of the source currency at time t
+func (c *Converter) At(t time.Time) float64 {
+	return rateAt(c.from, t) / rateAt(c.to, t)
+}
+
+// rateAt returns the latest quote at or before t, or the earliest quote when t precedes the series.
+// A nil series is the default currency itself.
+func rateAt(quotes []asset.AssetQuote, t time.Time) float64 {
+	if quotes == nil {
+		return 1
+	}
+	i := sort.Search(len(quotes), func(i int) bool {
+		return quotes[i].QuoteTime.After(t)
+	})
+	if i == 0 {
+		return quotes[0].Quote
+	}
+	return quotes[i-1].Quote
+}
@@ -0,0 +1,210 @@
+package fx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+type Service struct {
+	assetService *asset.Service
+}
+
+func NewService(assetService *asset.Service) *Service {
+	return &Service{
+		assetService: assetService,
+	}
+}
+
+// Normalize upper cases the currency code and falls back to the default currency
+func Normalize(currency string) string {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return DefaultCurrency
+	}
+	return currency
+}
+
+func (s *Service) IsSupported(currency string) bool {
+	currency = Normalize(currency)
+	if currency == DefaultCurrency {
+		return true
+	}
+	_, err := s.assetService.GetAssetBySymbol(fxSymbol(currency))
+	return err == nil
+}
+
+// Rate returns the rate to convert one unit of from into to at time t
+func (s *Service) Rate(from, to string, t time.Time) (float64, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return 1, nil
+	}
+
+	fromRate, err := s.usdRate(from, t)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := s.usdRate(to, t)
+	if err != nil {
+		return 0, err
+	}
+	return fromRate / toRate, nil
+}
+
+// Rates is the batched Rate, it returns the rates in the order of the requests
+// and looks up the quotes of all of them in one query.
+func (s *Service) Rates(requests []RateRequest) ([]float64, error) {
+	type usdRateKey struct {
+		currency string
+		time     int64
+	}
+
+	fxAssetIds := make(map[string]int64)
+	ordinals := make(map[usdRateKey]int)
+	var keys []usdRateKey
+	var assetIds []int64
+	var times []time.Time
+	addUsdRate := func(currency string, t time.Time) error {
+		if currency == DefaultCurrency {
+			return nil
+		}
+		key := usdRateKey{currency: currency, time: t.UnixNano()}
+		if _, ok := ordinals[key]; ok {
+			return nil
+		}
+
+		fxAssetId, ok := fxAssetIds[currency]
+		if !ok {
+			fxAsset, err := s.assetService.GetAssetBySymbol(fxSymbol(currency))
+			if err != nil {
+				return fmt.Errorf("%w: %s", UnsupportedCurrencyErr, currency)
+			}
+			fxAssetId = fxAsset.Id
+			fxAssetIds[currency] = fxAssetId
+		}
+
+		ordinals[key] = len(keys)
+		keys = append(keys, key)
+		assetIds = append(assetIds, fxAssetId)
+		times = append(times, t)
+		return nil
+	}
+
+	normalized := make([]RateRequest, len(requests))
+	for i, request := range requests {
+		request.From, request.To = Normalize(request.From), Normalize(request.To)
+		normalized[i] = request
+		if request.From == request.To {
+			continue
+		}
+		if err := addUsdRate(request.From, request.Time); err != nil {
+			return nil, err
+		}
+		if err := addUsdRate(request.To, request.Time); err != nil {
+			return nil, err
+		}
+	}
+
+	quotes, err := s.assetService.GetAssetQuotesAtTimes(assetIds, times)
+	if err != nil {
+		return nil, err
+	}
+	usdRate := func(currency string, t time.Time) (float64, error) {
+		if currency == DefaultCurrency {
+			return 1, nil
+		}
+		quote, ok := quotes[ordinals[usdRateKey{currency: currency, time: t.UnixNano()}]]
+		if !ok {
+			return 0, fmt.Errorf("%w: %s at %s", RateNotFoundErr, currency, t.Format(time.RFC3339))
+		}
+		return quote.Quote, nil
+	}
+
+	rates := make([]float64, len(normalized))
+	for i, request := range normalized {
+		if request.From == request.To {
+			rates[i] = 1
+			continue
+		}
+		fromRate, err := usdRate(request.From, request.Time)
+		if err != nil {
+			return nil, err
+		}
+		toRate, err := usdRate(request.To, request.Time)
+		if err != nil {
+			return nil, err
+		}
+		rates[i] = fromRate / toRate
+	}
+	return rates, nil
+}
+
+// NewConverter loads the rates between two currencies for a period,
+// so series can be converted without a query per timestamp.
+func (s *Service) NewConverter(from, to string, startTime, endTime time.Time) (*Converter, error) {
+	from, to = Normalize(from), Normalize(to)
+	if from == to {
+		return &Converter{}, nil
+	}
+
+	fromQuotes, err := s.usdSeries(from, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	toQuotes, err := s.usdSeries(to, startTime, endTime)
+	if err != nil {
+		return nil, err
+	}
+	return &Converter{from: fromQuotes, to: toQuotes}, nil
+}
+
+func (s *Service) usdRate(currency string, t time.Time) (float64, error) {
+	if currency == DefaultCurrency {
+		return 1, nil
+	}
+
+	fxAsset, err := s.assetService.GetAssetBySymbol(fxSymbol(currency))
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s", UnsupportedCurrencyErr, currency)
+	}
+
+	quote, err := s.assetService.GetAssetQuoteAtTime(fxAsset.Id, t)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %s at %s", RateNotFoundErr, currency, t.Format(time.RFC3339))
+	}
+	return quote.Quote, nil
+}
+
+func (s *Service) usdSeries(currency string, startTime, endTime time.Time) ([]asset.AssetQuote, error) {
+	if currency == DefaultCurrency {
+		return nil, nil
+	}
+
+	fxAsset, err := s.assetService.GetAssetBySymbol(fxSymbol(currency))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", UnsupportedCurrencyErr, currency)
+	}
+
+	// Look back a week so the start of the period has a rate even after weekends and holidays
+	quotes, err := s.assetService.GetAssetQuotesForPeriod(fxAsset.Id, startTime.AddDate(0, 0, -7), endTime)
+	if err != nil {
+		return nil, err
+	}
+	if len(quotes) == 0 {
+		return nil, fmt.Errorf("%w: %s", RateNotFoundErr, currency)
+	}
+	return quotes, nil
+}
+
+func fxSymbol(currency string) string {
+	return currency + DefaultCurrency + "=X"
+}
+
+// RateCurrency returns the currency whose USD rate the symbol quotes, false when it isn't an FX rate
+func RateCurrency(symbol string) (string, bool) {
+	currency, ok := strings.CutSuffix(strings.ToUpper(symbol), DefaultCurrency+"=X")
+	return currency, ok && currency != ""
+}
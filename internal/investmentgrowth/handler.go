@@ -22,7 +22,16 @@ func (h *Handler) CalculateInvestmentGrowth(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Symbol is required"})
 	}
 
-	growth, err := h.service.CalculateInvestmentGrowth(symbol)
+	currency := c.Query("currency")
+	if currency == "" {
+		baseCurrency, err := h.service.GetUserBaseCurrency(c.Locals("userId").(int64))
+		if err != nil {
+			return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch base currency"})
+		}
+		currency = baseCurrency
+	}
+
+	growth, err := h.service.CalculateInvestmentGrowth(symbol, currency)
 	if err != nil {
 		return c.Status(404).JSON(fiber.Map{"error": fmt.Sprintf("Failed to calculate growth for symbol %s: %v", symbol, err)})
 	}
@@ -5,9 +5,12 @@ import "time"
 type GrowthDataPoint struct {
 	Timestamp time.Time `json:"timestamp"`
 	Value     float64   `json:"value"`
+	// CurrencyEffect is the part of the value change caused by FX moves
+	CurrencyEffect float64 `json:"currencyEffect"`
 }
 
 type GrowthResult struct {
+	Currency       string            `json:"currency"`
 	WeekData       []GrowthDataPoint `json:"weekData"`
 	MonthData      []GrowthDataPoint `json:"monthData"`
 	ThreeMonthData []GrowthDataPoint `json:"threeMonthData"`
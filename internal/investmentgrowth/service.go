@@ -6,44 +6,53 @@ import (
 	"time"
 
 	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
 	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
 )
 
 type Service struct {
 	portfolioService *portfolio.Service
 	assetService     *asset.Service
+	fxService        *fx.Service
 }
 
-func NewService(portfolioService *portfolio.Service, assetService *asset.Service) *Service {
+func NewService(portfolioService *portfolio.Service, assetService *asset.Service, fxService *fx.Service) *Service {
 	return &Service{
 		portfolioService: portfolioService,
 		assetService:     assetService,
+		fxService:        fxService,
 	}
 }
 
-func (s *Service) CalculateInvestmentGrowth(symbol string) (*GrowthResult, error) {
+func (s *Service) GetUserBaseCurrency(userId int64) (string, error) {
+	return s.portfolioService.GetUserBaseCurrency(userId)
+}
+
+func (s *Service) CalculateInvestmentGrowth(symbol string, currency string) (*GrowthResult, error) {
+	currency = fx.Normalize(currency)
+
 	// First, try to get a portfolio with this symbol
 	portfolioInfo, err := s.portfolioService.GetPortfolioBySymbol(symbol)
 	if err == nil {
 		// If a portfolio is found, use the portfolio growth calculation
-		return s.CalculatePortfolioInvestmentGrowth(portfolioInfo.Id)
+		return s.CalculatePortfolioInvestmentGrowth(portfolioInfo.Id, currency)
 	}
 
 	// If not found as a portfolio, try to get an asset with this symbol
 	assetInfo, err := s.assetService.GetAssetBySymbol(symbol)
 	if err == nil {
 		// If an asset is found, use the asset growth calculation
-		return s.CalculateAssetInvestmentGrowth(assetInfo.Id)
+		return s.CalculateAssetInvestmentGrowth(assetInfo.Id, assetInfo.Currency.String, currency)
 	}
 
 	// If neither a portfolio nor an asset is found, return an error
 	return nil, fmt.Errorf("symbol %s not found as either portfolio or asset", symbol)
 }
 
-func (s *Service) CalculatePortfolioInvestmentGrowth(portfolioId int64) (*GrowthResult, error) {
+func (s *Service) CalculatePortfolioInvestmentGrowth(portfolioId int64, currency string) (*GrowthResult, error) {
 	initialInvestment := 1000.0
 
-	portfolio, err := s.portfolioService.GetPortfolioWithAllocations(portfolioId)
+	portfolio, err := s.portfolioService.GetPortfolioWithAllocations(portfolioId, currency)
 	if err != nil {
 		return nil, err
 	}
@@ -56,10 +65,10 @@ func (s *Service) CalculatePortfolioInvestmentGrowth(portfolioId int64) (*Growth
 		"year":       now.AddDate(-1, 0, 0),
 	}
 
-	result := &GrowthResult{}
+	result := &GrowthResult{Currency: currency}
 
 	for period, startDate := range periods {
-		growthData, err := s.calculateGrowthForPeriod(portfolio, initialInvestment, startDate, now, period)
+		growthData, err := s.calculateGrowthForPeriod(portfolio, currency, initialInvestment, startDate, now, period)
 		if err != nil {
 			return nil, err
 		}
@@ -79,14 +88,21 @@ func (s *Service) CalculatePortfolioInvestmentGrowth(portfolioId int64) (*Growth
 	return result, nil
 }
 
-func (s *Service) calculateGrowthForPeriod(portfolio *portfolio.PortfolioDTO, initialInvestment float64, startDate, endDate time.Time, period string) ([]GrowthDataPoint, error) {
+func (s *Service) calculateGrowthForPeriod(portfolio *portfolio.PortfolioDTO, currency string, initialInvestment float64, startDate, endDate time.Time, period string) ([]GrowthDataPoint, error) {
 	var allQuotes []asset.AssetQuote
+	converters := make(map[int64]*fx.Converter)
 	for _, allocation := range portfolio.Allocations {
 		quotes, err := s.assetService.GetAssetQuotesForPeriod(allocation.Asset.Id, startDate, endDate)
 		if err != nil {
 			return nil, err
 		}
 		allQuotes = append(allQuotes, quotes...)
+
+		converter, err := s.fxService.NewConverter(allocation.Asset.Currency, currency, startDate, endDate)
+		if err != nil {
+			return nil, err
+		}
+		converters[allocation.Asset.Id] = converter
 	}
 
 	// Sort all quotes by timestamp
@@ -113,22 +129,12 @@ func (s *Service) calculateGrowthForPeriod(portfolio *portfolio.PortfolioDTO, in
 		selectedQuotes = append(selectedQuotes, selectQuotesForPeriod(dayQuotes, period)...)
 	}
 
-	var growthData []GrowthDataPoint
-	if len(selectedQuotes) > 0 {
-		initialQuote := selectedQuotes[0].Quote
-		for _, quote := range selectedQuotes {
-			value := initialInvestment * (quote.Quote / initialQuote)
-			growthData = append(growthData, GrowthDataPoint{
-				Timestamp: quote.QuoteTime,
-				Value:     value,
-			})
-		}
-	}
-
-	return growthData, nil
+	return toGrowthData(selectedQuotes, initialInvestment, func(quote asset.AssetQuote) float64 {
+		return converters[quote.AssetId].At(quote.QuoteTime)
+	}), nil
 }
 
-func (s *Service) CalculateAssetInvestmentGrowth(assetId int64) (*GrowthResult, error) {
+func (s *Service) CalculateAssetInvestmentGrowth(assetId int64, assetCurrency, currency string) (*GrowthResult, error) {
 	initialInvestment := 1000.0
 
 	now := time.Now()
@@ -139,10 +145,10 @@ func (s *Service) CalculateAssetInvestmentGrowth(assetId int64) (*GrowthResult,
 		"year":       now.AddDate(-1, 0, 0),
 	}
 
-	result := &GrowthResult{}
+	result := &GrowthResult{Currency: currency}
 
 	for period, startDate := range periods {
-		growthData, err := s.calculateAssetGrowthForPeriod(assetId, initialInvestment, startDate, now, period)
+		growthData, err := s.calculateAssetGrowthForPeriod(assetId, assetCurrency, currency, initialInvestment, startDate, now, period)
 		if err != nil {
 			return nil, err
 		}
@@ -162,12 +168,17 @@ func (s *Service) CalculateAssetInvestmentGrowth(assetId int64) (*GrowthResult,
 	return result, nil
 }
 
-func (s *Service) calculateAssetGrowthForPeriod(assetId int64, initialInvestment float64, startDate, endDate time.Time, period string) ([]GrowthDataPoint, error) {
+func (s *Service) calculateAssetGrowthForPeriod(assetId int64, assetCurrency, currency string, initialInvestment float64, startDate, endDate time.Time, period string) ([]GrowthDataPoint, error) {
 	quotes, err := s.assetService.GetAssetQuotesForPeriod(assetId, startDate, endDate)
 	if err != nil {
 		return nil, err
 	}
 
+	converter, err := s.fxService.NewConverter(assetCurrency, currency, startDate, endDate)
+	if err != nil {
+		return nil, err
+	}
+
 	var selectedQuotes []asset.AssetQuote
 	var currentDay time.Time
 	var dayQuotes []asset.AssetQuote
@@ -187,19 +198,29 @@ func (s *Service) calculateAssetGrowthForPeriod(assetId int64, initialInvestment
 		selectedQuotes = append(selectedQuotes, selectQuotesForPeriod(dayQuotes, period)...)
 	}
 
+	return toGrowthData(selectedQuotes, initialInvestment, func(quote asset.AssetQuote) float64 {
+		return converter.At(quote.QuoteTime)
+	}), nil
+}
+
+// toGrowthData values the quotes in the requested currency, rate returns the FX rate at each quote's time
+func toGrowthData(selectedQuotes []asset.AssetQuote, initialInvestment float64, rate func(quote asset.AssetQuote) float64) []GrowthDataPoint {
 	var growthData []GrowthDataPoint
 	if len(selectedQuotes) > 0 {
 		initialQuote := selectedQuotes[0].Quote
+		initialRate := rate(selectedQuotes[0])
 		for _, quote := range selectedQuotes {
-			value := initialInvestment * (quote.Quote / initialQuote)
+			localValue := initialInvestment * (quote.Quote / initialQuote)
+			value := localValue * (rate(quote) / initialRate)
 			growthData = append(growthData, GrowthDataPoint{
-				Timestamp: quote.QuoteTime,
-				Value:     value,
+				Timestamp:      quote.QuoteTime,
+				Value:          value,
+				CurrencyEffect: value - localValue,
 			})
 		}
 	}
 
-	return growthData, nil
+	return growthData
 }
 
 func selectQuotesForPeriod(dayQuotes []asset.AssetQuote, period string) []asset.AssetQuote {
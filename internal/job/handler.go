@@ -0,0 +1,91 @@
+package job
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/pkg/scheduler"
+)
+
+const defaultRunLimit = 50
+
+// Handler exposes the scheduled jobs to admins
+type Handler struct {
+	scheduler *scheduler.Scheduler
+}
+
+func NewHandler(scheduler *scheduler.Scheduler) *Handler {
+	return &Handler{scheduler: scheduler}
+}
+
+func (h *Handler) GetJobs(c *fiber.Ctx) error {
+	jobs, err := h.scheduler.Jobs()
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get jobs"})
+	}
+	return c.JSON(jobs)
+}
+
+func (h *Handler) GetRuns(c *fiber.Ctx) error {
+	limit := c.QueryInt("limit", defaultRunLimit)
+	if limit <= 0 {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid limit"})
+	}
+
+	runs, err := h.scheduler.Runs(c.Params("name"), limit)
+	if err == scheduler.JobNotFoundErr {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get job runs"})
+	}
+	return c.JSON(runs)
+}
+
+func (h *Handler) GetRun(c *fiber.Ctx) error {
+	runId, err := c.ParamsInt("runId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Run ID"})
+	}
+
+	run, err := h.scheduler.Run(int64(runId))
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Job run not found"})
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get job run"})
+	}
+	return c.JSON(run)
+}
+
+func (h *Handler) TriggerJob(c *fiber.Ctx) error {
+	return h.jobAction(c, h.scheduler.Trigger, fiber.StatusAccepted)
+}
+
+func (h *Handler) PauseJob(c *fiber.Ctx) error {
+	return h.jobAction(c, h.scheduler.Pause, fiber.StatusOK)
+}
+
+func (h *Handler) ResumeJob(c *fiber.Ctx) error {
+	return h.jobAction(c, h.scheduler.Resume, fiber.StatusOK)
+}
+
+func (h *Handler) jobAction(c *fiber.Ctx, action func(name string) error, status int) error {
+	name := c.Params("name")
+	err := action(name)
+	switch err {
+	case nil:
+		return c.Status(status).JSON(fiber.Map{"job": name})
+	case scheduler.JobNotFoundErr:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case scheduler.JobRunningErr:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update job"})
+	}
+}
@@ -0,0 +1,120 @@
+package livequote
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/pkg/lifecycle"
+)
+
+// Service polls the latest quotes of held assets while their markets are open
+type Service struct {
+	assetService *asset.Service
+	providers    *quoteprovider.Registry
+	pollInterval time.Duration
+	stop         chan struct{}
+	stopped      chan struct{}
+}
+
+func NewService(assetService *asset.Service, providers *quoteprovider.Registry, pollInterval time.Duration) *Service {
+	return &Service{
+		assetService: assetService,
+		providers:    providers,
+		pollInterval: pollInterval,
+		stop:         make(chan struct{}),
+		stopped:      make(chan struct{}),
+	}
+}
+
+// Start polls every poll interval in the background, a zero interval disables polling
+func (s *Service) Start() {
+	if s.pollInterval <= 0 {
+		log.Info("Live quote polling is disabled")
+		close(s.stopped)
+		return
+	}
+	go s.run()
+}
+
+// Stop waits for the running poll to finish
+func (s *Service) Stop(ctx context.Context) error {
+	close(s.stop)
+	return lifecycle.Wait(ctx, func() { <-s.stopped })
+}
+
+func (s *Service) run() {
+	defer close(s.stopped)
+
+	// a tick arriving while a slow poll still runs is dropped, so polls never overlap
+	ticker := time.NewTicker(s.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.stop:
+			return
+		case <-ticker.C:
+			if err := s.Poll(); err != nil {
+				log.Errorf("Error polling live quotes: %v", err)
+			}
+		}
+	}
+}
+
+// Poll saves a snapshot of every held asset whose exchange is in session
+func (s *Service) Poll() error {
+	assets, err := s.assetService.GetHeldAssets()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, a := range assets {
+		if !s.assetService.IsMarketOpen(a.Exchange, now) {
+			continue
+		}
+
+		bar, provider, err := s.latestQuote(a.Symbol)
+		if err != nil {
+			log.Warnf("Could not poll live quote of %s: %v", a.Symbol, err)
+			continue
+		}
+
+		err = s.assetService.SaveQuoteSnapshot(asset.QuoteSnapshot{
+			AssetId:   a.Id,
+			Symbol:    a.Symbol,
+			Price:     bar.Close,
+			Open:      asset.ToNullPrice(bar.Open),
+			High:      asset.ToNullPrice(bar.High),
+			Low:       asset.ToNullPrice(bar.Low),
+			Volume:    sql.NullFloat64{Float64: bar.Volume, Valid: true},
+			Provider:  provider,
+			QuoteTime: bar.ClosesAt,
+		})
+		if err != nil {
+			log.Errorf("Error saving live quote of %s: %v", a.Symbol, err)
+		}
+	}
+	return nil
+}
+
+// latestQuote asks the providers of the symbol in order until one has a usable price
+func (s *Service) latestQuote(symbol string) (*quoteprovider.Bar, string, error) {
+	var lastErr error = quoteprovider.NoDataErr
+	for _, provider := range s.providers.Chain(symbol) {
+		bar, err := provider.LatestQuote(symbol)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if bar.Close <= 0 {
+			continue
+		}
+		return bar, provider.Name(), nil
+	}
+	return nil, "", lastErr
+}
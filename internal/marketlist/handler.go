@@ -0,0 +1,142 @@
+package marketlist
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// GetMarketOverview serves the assets of the lists as a flat array, the response of
+// /asset/market-overview clients already rely on
+func (h *Handler) GetMarketOverview(c *fiber.Ctx) error {
+	resp, err := h.service.GetListedAssetChanges()
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch market overview",
+		})
+	}
+	return c.JSON(resp)
+}
+
+func (h *Handler) GetOverview(c *fiber.Ctx) error {
+	moversLimit := c.QueryInt("movers", defaultMoversLimit)
+	if moversLimit < 0 || moversLimit > maxMoversLimit {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid movers limit"})
+	}
+
+	resp, err := h.service.GetOverview(moversLimit)
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch market overview",
+		})
+	}
+	return c.JSON(resp)
+}
+
+func (h *Handler) GetMarketLists(c *fiber.Ctx) error {
+	lists, err := h.service.GetMarketLists()
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch market lists"})
+	}
+	return c.JSON(lists)
+}
+
+func (h *Handler) CreateMarketList(c *fiber.Ctx) error {
+	var req MarketListRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	list, err := h.service.CreateMarketList(req)
+	if err == MarketListExistsErr {
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to create market list"})
+	}
+	return c.Status(fiber.StatusCreated).JSON(list)
+}
+
+func (h *Handler) UpdateMarketList(c *fiber.Ctx) error {
+	listId, err := c.ParamsInt("listId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid List ID"})
+	}
+
+	var req UpdateMarketListRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	list, err := h.service.UpdateMarketList(int64(listId), req)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Market list not found"})
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update market list"})
+	}
+	return c.JSON(list)
+}
+
+func (h *Handler) DeleteMarketList(c *fiber.Ctx) error {
+	listId, err := c.ParamsInt("listId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid List ID"})
+	}
+
+	err = h.service.DeleteMarketList(int64(listId))
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Market list not found"})
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to delete market list"})
+	}
+	return c.JSON(fiber.Map{"message": "Market list deleted"})
+}
+
+func (h *Handler) SetListAssets(c *fiber.Ctx) error {
+	listId, err := c.ParamsInt("listId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid List ID"})
+	}
+
+	var req SetAssetsRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	list, err := h.service.SetListAssets(int64(listId), req.Symbols)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Market list not found"})
+	}
+	if errors.Is(err, UnknownSymbolErr) {
+		return c.Status(fiber.StatusUnprocessableEntity).JSON(fiber.Map{"error": err.Error()})
+	}
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to update market list assets"})
+	}
+	return c.JSON(list)
+}
@@ -0,0 +1,86 @@
+package marketlist
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+var MarketListExistsErr error = errors.New("market list already exists")
+var UnknownSymbolErr error = errors.New("unknown symbol")
+
+const (
+	defaultMoversLimit = 5
+	maxMoversLimit     = 50
+)
+
+var slugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// MarketList is an admin managed list of assets shown on the market overview
+type MarketList struct {
+	Id        int64                  `db:"id" json:"id"`
+	Slug      string                 `db:"slug" json:"slug"`
+	Name      string                 `db:"name" json:"name"`
+	Position  int                    `db:"position" json:"position"`
+	Assets    []asset.SimpleAssetDTO `db:"-" json:"assets"`
+	CreatedAt time.Time              `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time              `db:"updated_at" json:"updated_at"`
+}
+
+// listAsset is an asset of a list in the order it's shown
+type listAsset struct {
+	MarketListId int64 `db:"market_list_id"`
+	asset.SimpleAssetDTO
+}
+
+type MarketListRequest struct {
+	Slug     string `json:"slug"`
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+func (r *MarketListRequest) validate() error {
+	r.Slug = strings.ToLower(strings.TrimSpace(r.Slug))
+	r.Name = strings.TrimSpace(r.Name)
+	if !slugPattern.MatchString(r.Slug) {
+		return errors.New("slug must be lower case words separated by dashes")
+	}
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// UpdateMarketListRequest changes how a list is shown, its slug stays as clients may refer to it
+type UpdateMarketListRequest struct {
+	Name     string `json:"name"`
+	Position int    `json:"position"`
+}
+
+func (r *UpdateMarketListRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	return nil
+}
+
+// SetAssetsRequest replaces the assets of a list, they're shown in the order of the symbols
+type SetAssetsRequest struct {
+	Symbols []string `json:"symbols"`
+}
+
+type MarketListOverview struct {
+	Slug   string                           `json:"slug"`
+	Name   string                           `json:"name"`
+	Assets []asset.MarketGrowthListResponse `json:"assets"`
+}
+
+type OverviewResponse struct {
+	Lists   []MarketListOverview             `json:"lists"`
+	Gainers []asset.MarketGrowthListResponse `json:"gainers"`
+	Losers  []asset.MarketGrowthListResponse `json:"losers"`
+}
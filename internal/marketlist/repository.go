@@ -0,0 +1,144 @@
+package marketlist
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetMarketLists() ([]MarketList, error) {
+	query := `
+		SELECT *
+		FROM market_list
+		ORDER BY position, id
+	`
+	lists := []MarketList{}
+	err := r.db.Select(&lists, query)
+	if err != nil {
+		log.Errorf("Error fetching market lists: %v", err)
+		return nil, err
+	}
+	return lists, nil
+}
+
+func (r *Repository) GetMarketList(listId int64) (*MarketList, error) {
+	query := `
+		SELECT *
+		FROM market_list
+		WHERE id = $1
+	`
+	var list MarketList
+	err := r.db.Get(&list, query, listId)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+// GetListAssets returns the assets of the lists in their order, delisted assets are left out
+func (r *Repository) GetListAssets(listIds []int64) ([]listAsset, error) {
+	query := `
+		SELECT
+			la.market_list_id,
+			a.id,
+			a.name,
+			a.symbol,
+			COALESCE(a.currency, 'USD') AS currency,
+			COALESCE(a.exchange, '') AS exchange
+		FROM market_list_asset la
+		JOIN asset a ON a.id = la.asset_id
+		WHERE la.market_list_id = ANY($1) AND a.delisted_at IS NULL
+		ORDER BY la.market_list_id, la.position
+	`
+	var assets []listAsset
+	err := r.db.Select(&assets, query, pq.Array(listIds))
+	if err != nil {
+		log.Errorf("Error fetching market list assets: %v", err)
+		return nil, err
+	}
+	return assets, nil
+}
+
+func (r *Repository) CreateMarketList(request MarketListRequest) (*MarketList, error) {
+	query := `
+		INSERT INTO market_list (slug, name, position)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (slug) DO NOTHING
+		RETURNING *
+	`
+	var list MarketList
+	err := r.db.Get(&list, query, request.Slug, request.Name, request.Position)
+	if err == sql.ErrNoRows {
+		return nil, MarketListExistsErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *Repository) UpdateMarketList(listId int64, request UpdateMarketListRequest) (*MarketList, error) {
+	query := `
+		UPDATE market_list
+		SET name = $2, position = $3
+		WHERE id = $1
+		RETURNING *
+	`
+	var list MarketList
+	err := r.db.Get(&list, query, listId, request.Name, request.Position)
+	if err != nil {
+		return nil, err
+	}
+	return &list, nil
+}
+
+func (r *Repository) DeleteMarketList(listId int64) error {
+	result, err := r.db.Exec("DELETE FROM market_list WHERE id = $1", listId)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return sql.ErrNoRows
+	}
+	return nil
+}
+
+// SetListAssets replaces the assets of the list, their position is their index
+func (r *Repository) SetListAssets(listId int64, assetIds []int64) error {
+	tx, err := r.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback() // Will be ignored if the tx has been committed later
+
+	_, err = tx.Exec("DELETE FROM market_list_asset WHERE market_list_id = $1", listId)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO market_list_asset (market_list_id, asset_id, position)
+		SELECT $1, ids.asset_id, ids.position - 1
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS ids(asset_id, position)
+	`
+	_, err = tx.Exec(query, listId, pq.Array(assetIds))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
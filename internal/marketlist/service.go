@@ -0,0 +1,225 @@
+package marketlist
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+type Service struct {
+	repo         *Repository
+	assetService *asset.Service
+}
+
+func NewService(repo *Repository, assetService *asset.Service) *Service {
+	return &Service{
+		repo:         repo,
+		assetService: assetService,
+	}
+}
+
+// GetMarketLists returns the lists in the order they're shown, with their assets
+func (s *Service) GetMarketLists() ([]MarketList, error) {
+	lists, err := s.repo.GetMarketLists()
+	if err != nil {
+		return nil, err
+	}
+	if len(lists) == 0 {
+		return lists, nil
+	}
+
+	listIds := make([]int64, len(lists))
+	for i, list := range lists {
+		listIds[i] = list.Id
+	}
+	assets, err := s.repo.GetListAssets(listIds)
+	if err != nil {
+		return nil, err
+	}
+
+	assetsByList := make(map[int64][]asset.SimpleAssetDTO, len(lists))
+	for _, a := range assets {
+		assetsByList[a.MarketListId] = append(assetsByList[a.MarketListId], a.SimpleAssetDTO)
+	}
+	for i := range lists {
+		lists[i].Assets = assetsByList[lists[i].Id]
+		if lists[i].Assets == nil {
+			lists[i].Assets = []asset.SimpleAssetDTO{}
+		}
+	}
+	return lists, nil
+}
+
+func (s *Service) CreateMarketList(request MarketListRequest) (*MarketList, error) {
+	list, err := s.repo.CreateMarketList(request)
+	if err != nil {
+		return nil, err
+	}
+	list.Assets = []asset.SimpleAssetDTO{}
+	return list, nil
+}
+
+func (s *Service) UpdateMarketList(listId int64, request UpdateMarketListRequest) (*MarketList, error) {
+	if _, err := s.repo.UpdateMarketList(listId, request); err != nil {
+		return nil, err
+	}
+	return s.getMarketList(listId)
+}
+
+func (s *Service) DeleteMarketList(listId int64) error {
+	return s.repo.DeleteMarketList(listId)
+}
+
+// SetListAssets replaces the assets of the list with the assets of the symbols, in their order
+func (s *Service) SetListAssets(listId int64, symbols []string) (*MarketList, error) {
+	if _, err := s.repo.GetMarketList(listId); err != nil {
+		return nil, err
+	}
+
+	var normalized []string
+	seen := make(map[string]bool, len(symbols))
+	for _, symbol := range symbols {
+		symbol = strings.ToUpper(strings.TrimSpace(symbol))
+		if symbol != "" && !seen[symbol] {
+			seen[symbol] = true
+			normalized = append(normalized, symbol)
+		}
+	}
+
+	assets, err := s.assetService.GetAssetsBySymbols(normalized)
+	if err != nil {
+		return nil, err
+	}
+	assetIdsBySymbol := make(map[string]int64, len(assets))
+	for _, a := range assets {
+		assetIdsBySymbol[a.Symbol] = a.Id
+	}
+
+	assetIds := make([]int64, 0, len(normalized))
+	var unknown []string
+	for _, symbol := range normalized {
+		assetId, ok := assetIdsBySymbol[symbol]
+		if !ok {
+			unknown = append(unknown, symbol)
+			continue
+		}
+		assetIds = append(assetIds, assetId)
+	}
+	if len(unknown) > 0 {
+		return nil, fmt.Errorf("%w: %s", UnknownSymbolErr, strings.Join(unknown, ", "))
+	}
+
+	if err := s.repo.SetListAssets(listId, assetIds); err != nil {
+		return nil, err
+	}
+	return s.getMarketList(listId)
+}
+
+func (s *Service) getMarketList(listId int64) (*MarketList, error) {
+	list, err := s.repo.GetMarketList(listId)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := s.repo.GetListAssets([]int64{listId})
+	if err != nil {
+		return nil, err
+	}
+	list.Assets = make([]asset.SimpleAssetDTO, 0, len(assets))
+	for _, a := range assets {
+		list.Assets = append(list.Assets, a.SimpleAssetDTO)
+	}
+	return list, nil
+}
+
+// GetListedAssetChanges returns the daily change of the assets of every list as one list, in the
+// order of the lists, each asset once. It's the shape the market overview had before the lists.
+func (s *Service) GetListedAssetChanges() ([]asset.MarketGrowthListResponse, error) {
+	lists, err := s.GetMarketLists()
+	if err != nil {
+		return nil, err
+	}
+
+	var assets []asset.SimpleAssetDTO
+	seen := make(map[int64]bool)
+	for _, list := range lists {
+		for _, a := range list.Assets {
+			if !seen[a.Id] {
+				seen[a.Id] = true
+				assets = append(assets, a)
+			}
+		}
+	}
+	changes, err := s.assetService.GetDailyChanges(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]asset.MarketGrowthListResponse, 0, len(assets))
+	for _, a := range assets {
+		if change, ok := changes[a.Id]; ok {
+			response = append(response, change)
+		}
+	}
+	return response, nil
+}
+
+// GetOverview returns the daily change of the assets of every list and the top gainers and
+// losers among the active assets. Assets missing quotes are left out instead of failing the overview.
+func (s *Service) GetOverview(moversLimit int) (*OverviewResponse, error) {
+	lists, err := s.GetMarketLists()
+	if err != nil {
+		return nil, err
+	}
+	universe, err := s.assetService.GetActiveAssets()
+	if err != nil {
+		return nil, err
+	}
+
+	// list assets may be suspended, so they're looked up along with the universe
+	assets := universe
+	for _, list := range lists {
+		assets = append(assets, list.Assets...)
+	}
+	changes, err := s.assetService.GetDailyChanges(assets)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &OverviewResponse{
+		Lists:   make([]MarketListOverview, 0, len(lists)),
+		Gainers: []asset.MarketGrowthListResponse{},
+		Losers:  []asset.MarketGrowthListResponse{},
+	}
+	for _, list := range lists {
+		overview := MarketListOverview{
+			Slug:   list.Slug,
+			Name:   list.Name,
+			Assets: make([]asset.MarketGrowthListResponse, 0, len(list.Assets)),
+		}
+		for _, a := range list.Assets {
+			if change, ok := changes[a.Id]; ok {
+				overview.Assets = append(overview.Assets, change)
+			}
+		}
+		response.Lists = append(response.Lists, overview)
+	}
+
+	movers := make([]asset.MarketGrowthListResponse, 0, len(universe))
+	for _, a := range universe {
+		if change, ok := changes[a.Id]; ok {
+			movers = append(movers, change)
+		}
+	}
+	sort.Slice(movers, func(i, j int) bool {
+		return movers[i].Change > movers[j].Change
+	})
+	for i := 0; i < len(movers) && len(response.Gainers) < moversLimit && movers[i].Change > 0; i++ {
+		response.Gainers = append(response.Gainers, movers[i])
+	}
+	for i := len(movers) - 1; i >= 0 && len(response.Losers) < moversLimit && movers[i].Change < 0; i-- {
+		response.Losers = append(response.Losers, movers[i])
+	}
+	return response, nil
+}
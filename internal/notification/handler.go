@@ -0,0 +1,46 @@
+package notification
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetNotifications(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	unreadOnly := c.QueryBool("unread", false)
+
+	notifications, err := h.service.GetByUser(userId, unreadOnly)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch notifications"})
+	}
+
+	return c.JSON(fiber.Map{"notifications": notifications})
+}
+
+func (h *Handler) MarkRead(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	var req struct {
+		Ids []int64 `json:"ids"`
+	}
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&req); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+		}
+	}
+
+	if err := h.service.MarkRead(userId, req.Ids...); err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to mark notifications as read"})
+	}
+
+	return c.JSON(fiber.Map{"message": "Notifications marked as read"})
+}
@@ -0,0 +1,21 @@
+package notification
+
+import (
+	"database/sql"
+	"time"
+)
+
+const (
+	TypeAssetRequestApproved = "asset_request_approved"
+	TypeAssetRequestRejected = "asset_request_rejected"
+)
+
+type Notification struct {
+	Id        int64          `db:"id" json:"id"`
+	UserId    int64          `db:"user_id" json:"user_id"`
+	Type      string         `db:"type" json:"type"`
+	Title     string         `db:"title" json:"title"`
+	Message   sql.NullString `db:"message" json:"message"`
+	IsRead    bool           `db:"is_read" json:"is_read"`
+	CreatedAt time.Time      `db:"created_at" json:"created_at"`
+}
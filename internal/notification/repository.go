@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) Save(n *Notification) (*Notification, error) {
+	query := `
+		INSERT INTO notification (user_id, type, title, message)
+		VALUES (:user_id, :type, :title, :message)
+		RETURNING id, created_at
+	`
+	rows, err := r.db.NamedQuery(query, n)
+	if err != nil {
+		log.Errorf("Error saving notification: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	if rows.Next() {
+		if err := rows.Scan(&n.Id, &n.CreatedAt); err != nil {
+			return nil, err
+		}
+	}
+	return n, nil
+}
+
+func (r *Repository) GetByUser(userId int64, unreadOnly bool) ([]Notification, error) {
+	query := `
+		SELECT *
+		FROM notification
+		WHERE user_id = $1 AND (NOT $2 OR NOT is_read)
+		ORDER BY created_at DESC
+		LIMIT 100
+	`
+	notifications := []Notification{}
+	err := r.db.Select(&notifications, query, userId, unreadOnly)
+	if err != nil {
+		log.Errorf("Error fetching notifications: %v", err)
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func (r *Repository) MarkRead(userId int64, notificationIds []int64) error {
+	query := `
+		UPDATE notification
+		SET is_read = TRUE
+		WHERE user_id = $1 AND (COALESCE(cardinality($2::bigint[]), 0) = 0 OR id = ANY($2))
+	`
+	_, err := r.db.Exec(query, userId, pq.Array(notificationIds))
+	return err
+}
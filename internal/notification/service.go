@@ -0,0 +1,30 @@
+package notification
+
+import "database/sql"
+
+type Service struct {
+	repo *Repository
+}
+
+func NewService(repo *Repository) *Service {
+	return &Service{repo: repo}
+}
+
+func (s *Service) Notify(userId int64, notificationType, title, message string) error {
+	_, err := s.repo.Save(&Notification{
+		UserId:  userId,
+		Type:    notificationType,
+		Title:   title,
+		Message: sql.NullString{String: message, Valid: message != ""},
+	})
+	return err
+}
+
+func (s *Service) GetByUser(userId int64, unreadOnly bool) ([]Notification, error) {
+	return s.repo.GetByUser(userId, unreadOnly)
+}
+
+// MarkRead marks the given notifications as read, or all of them when no ids are given
+func (s *Service) MarkRead(userId int64, notificationIds ...int64) error {
+	return s.repo.MarkRead(userId, notificationIds)
+}
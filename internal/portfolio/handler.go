@@ -2,10 +2,12 @@ package portfolio
 
 import (
 	"database/sql"
+	"errors"
 	"fmt"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
 )
 
 type Handler struct {
@@ -40,17 +42,33 @@ func (h *Handler) GetPortfolioWithAllocations(c *fiber.Ctx) error {
 		return c.Status(400).JSON(fiber.Map{"error": "Invalid portfolio ID"})
 	}
 
-	portfolio, err := h.service.GetPortfolioWithAllocations(int64(portfolioId))
+	currency, err := h.currency(c)
+	if err != nil {
+		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch base currency"})
+	}
+
+	portfolio, err := h.service.GetPortfolioWithAllocations(int64(portfolioId), currency)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return c.Status(404).JSON(fiber.Map{"error": "Portfolio not found"})
 		}
+		if errors.Is(err, fx.UnsupportedCurrencyErr) {
+			return c.Status(400).JSON(fiber.Map{"error": err.Error()})
+		}
 		return c.Status(500).JSON(fiber.Map{"error": "Failed to fetch portfolio"})
 	}
 
 	return c.JSON(portfolio)
 }
 
+// currency returns the currency requested in the query, falling back to the user's base currency
+func (h *Handler) currency(c *fiber.Ctx) (string, error) {
+	if currency := c.Query("currency"); currency != "" {
+		return fx.Normalize(currency), nil
+	}
+	return h.service.GetUserBaseCurrency(c.Locals("userId").(int64))
+}
+
 func (h *Handler) AddTransactionToPortfolio(c *fiber.Ctx) error {
 	var request AddTransactionRequest
 	if err := c.BodyParser(&request); err != nil {
@@ -65,7 +83,14 @@ func (h *Handler) AddTransactionToPortfolio(c *fiber.Ctx) error {
 		})
 	}
 
-	portfolio, err := h.service.AddTransactionToPortfolio(request)
+	currency, err := h.currency(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to fetch base currency",
+		})
+	}
+
+	portfolio, err := h.service.AddTransactionToPortfolio(request, currency)
 	if err != nil {
 		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
 			"error": err.Error(),
@@ -37,15 +37,18 @@ type PortfolioFollow struct {
 
 type AllocationDTO struct {
 	Id                int64                `db:"id" json:"id"`
+	PortfolioId       int64                `db:"portfolio_id" json:"-"`
 	Asset             asset.SimpleAssetDTO `json:"asset"`
 	TargetPercentage  float64              `db:"target_percentage" json:"target_percentage"`
 	Amount            float64              `db:"-" json:"amount"`
 	CurrentPercentage float64              `db:"-" json:"current_percentage"`
 	UnrealizedPL      float64              `db:"-" json:"unrealized_pl"`
+	CurrencyEffect    float64              `db:"-" json:"currency_effect"`
 }
 
 type PortfolioDTO struct {
 	Portfolio
+	Currency    string          `db:"-" json:"currency"`
 	Allocations []AllocationDTO `json:"allocations"`
 }
 
@@ -54,6 +57,7 @@ type AddTransactionRequest struct {
 	Symbol      string                `json:"symbol"`
 	Quantity    float64               `json:"quantity"`
 	AvgPrice    float64               `json:"avg_price"`
+	Currency    string                `json:"currency"`
 	Side        transaction.OrderSide `json:"side"`
 }
 
@@ -81,6 +85,10 @@ type PortfolioListResponse struct {
 	Change float64 `json:"change"`
 	Owner  string  `json:"owner"`
 	Amount float64 `json:"amount"`
+
+	Currency string `json:"currency"`
+	// CurrencyEffect is the part of the daily change caused by FX moves, in percentage points
+	CurrencyEffect float64 `json:"currency_effect"`
 }
 
 type CreatePortfolioRequest struct {
@@ -94,3 +102,12 @@ type AllocationRequest struct {
 	AssetId          int64   `json:"asset_id"`
 	TargetPercentage float64 `json:"target_percentage"`
 }
+
+// dailyQuote holds the latest and previous trading day quotes of an asset
+// together with the FX rates to the requested currency at both times
+type dailyQuote struct {
+	latest       float64
+	previous     float64
+	latestRate   float64
+	previousRate float64
+}
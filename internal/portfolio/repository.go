@@ -3,6 +3,7 @@ package portfolio
 import (
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/lib/pq"
 )
 
 type Repository struct {
@@ -64,7 +65,9 @@ func (r *Repository) GetAllocations(portfolioId int64) ([]AllocationDTO, error)
             a.target_percentage,
             ast.id AS "asset.id",
             ast.name AS "asset.name",
-            ast.symbol AS "asset.symbol"
+            ast.symbol AS "asset.symbol",
+            COALESCE(ast.currency, 'USD') AS "asset.currency",
+            COALESCE(ast.exchange, '') AS "asset.exchange"
         FROM allocation a
         JOIN asset ast ON a.asset_id = ast.id
         WHERE a.portfolio_id = $1
@@ -95,23 +98,56 @@ func (r *Repository) GetPortfolioWithAllocations(portfolioId int64) (*PortfolioD
 	return portfolio, nil
 }
 
-func (r *Repository) GetPortfolioByUserIdWithAllocations(userId int64) ([]PortfolioDTO, error) {
-	portfolios, err := r.GetPortfolioByUser(userId)
+// GetPortfoliosWithAllocations loads the portfolios and their allocations in two queries,
+// missing portfolios are left out
+func (r *Repository) GetPortfoliosWithAllocations(portfolioIds []int64) ([]PortfolioDTO, error) {
+	query := `
+        SELECT * FROM portfolio
+        WHERE id = ANY($1)
+    `
+	var portfolios []PortfolioDTO
+	err := r.db.Select(&portfolios, query, pq.Array(portfolioIds))
 	if err != nil {
+		log.Errorf("Error fetching portfolios: %v", err)
 		return nil, err
 	}
+	return r.withAllocations(portfolios)
+}
 
-	var dtos []PortfolioDTO
-	for _, val := range *portfolios {
-		allocations, err := r.GetAllocations(val.Id)
-		if err != nil {
-			return nil, err
-		}
-		val.Allocations = allocations
-		dtos = append(dtos, val)
+// GetAllocationsByPortfolioIds loads the allocations of several portfolios in one query
+func (r *Repository) GetAllocationsByPortfolioIds(portfolioIds []int64) ([]AllocationDTO, error) {
+	query := `
+        SELECT
+            a.id,
+            a.portfolio_id,
+            a.target_percentage,
+            ast.id AS "asset.id",
+            ast.name AS "asset.name",
+            ast.symbol AS "asset.symbol",
+            COALESCE(ast.currency, 'USD') AS "asset.currency",
+            COALESCE(ast.exchange, '') AS "asset.exchange"
+        FROM allocation a
+        JOIN asset ast ON a.asset_id = ast.id
+        WHERE a.portfolio_id = ANY($1)
+        ORDER BY a.portfolio_id, a.id
+    `
+
+	var allocations []AllocationDTO
+	err := r.db.Select(&allocations, query, pq.Array(portfolioIds))
+	if err != nil {
+		log.Errorf("Error fetching allocations: %v", err)
+		return nil, err
 	}
 
-	return dtos, nil
+	return allocations, nil
+}
+
+func (r *Repository) GetPortfolioByUserIdWithAllocations(userId int64) ([]PortfolioDTO, error) {
+	portfolios, err := r.GetPortfolioByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	return r.withAllocations(*portfolios)
 }
 
 func (r *Repository) GetFollowedPortfolios(userId int64) ([]PortfolioDTO, error) {
@@ -134,18 +170,32 @@ func (r *Repository) GetFollowedPortfoliosWithAllocations(userId int64) ([]Portf
 	if err != nil {
 		return nil, err
 	}
+	return r.withAllocations(portfolios)
+}
 
-	var dtos []PortfolioDTO
-	for _, val := range portfolios {
-		allocations, err := r.GetAllocations(val.Id)
-		if err != nil {
-			return nil, err
-		}
-		val.Allocations = allocations
-		dtos = append(dtos, val)
+// withAllocations attaches the allocations of the portfolios, loaded together
+func (r *Repository) withAllocations(portfolios []PortfolioDTO) ([]PortfolioDTO, error) {
+	if len(portfolios) == 0 {
+		return nil, nil
+	}
+
+	portfolioIds := make([]int64, len(portfolios))
+	for i, portfolio := range portfolios {
+		portfolioIds[i] = portfolio.Id
+	}
+	allocations, err := r.GetAllocationsByPortfolioIds(portfolioIds)
+	if err != nil {
+		return nil, err
 	}
 
-	return dtos, nil
+	allocationsByPortfolio := make(map[int64][]AllocationDTO, len(portfolios))
+	for _, allocation := range allocations {
+		allocationsByPortfolio[allocation.PortfolioId] = append(allocationsByPortfolio[allocation.PortfolioId], allocation)
+	}
+	for i := range portfolios {
+		portfolios[i].Allocations = allocationsByPortfolio[portfolios[i].Id]
+	}
+	return portfolios, nil
 }
 
 func (r *Repository) FollowPortfolio(userID, portfolioID int64) error {
@@ -4,26 +4,40 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/gofiber/fiber/v2/log"
 	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
 	"github.com/karataydev/portfoliomanbackend/internal/transaction"
+	"github.com/karataydev/portfoliomanbackend/internal/user"
+	"github.com/karataydev/portfoliomanbackend/pkg/cache"
 )
 
 type Service struct {
 	repo               *Repository
 	transactionService *transaction.Service
 	assetService       *asset.Service
+	userService        *user.Service
+	fxService          *fx.Service
+	valuations         *cache.Cache[valuationKey, valuation]
 }
 
-func NewService(repo *Repository, transactionService *transaction.Service, assetService *asset.Service) *Service {
+func NewService(repo *Repository, transactionService *transaction.Service, assetService *asset.Service, userService *user.Service, fxService *fx.Service, valuationCacheTTL time.Duration) *Service {
 	return &Service{
 		repo:               repo,
 		transactionService: transactionService,
 		assetService:       assetService,
+		userService:        userService,
+		fxService:          fxService,
+		valuations:         newValuationCache(valuationCacheTTL),
 	}
 }
 
+func (s *Service) GetUserBaseCurrency(userId int64) (string, error) {
+	return s.userService.GetBaseCurrency(userId)
+}
+
 func (s *Service) GetPortfolio(portfolioId int64) (*PortfolioDTO, error) {
 	return s.repo.GetPortfolio(portfolioId)
 }
@@ -36,11 +50,13 @@ func (s *Service) GetPortfolioBySymbol(symbol string) (*Portfolio, error) {
 	return s.repo.GetPortfolioBySymbol(symbol)
 }
 
-func (s *Service) GetPortfolioWithAllocations(portfolioId int64) (*PortfolioDTO, error) {
+func (s *Service) GetPortfolioWithAllocations(portfolioId int64, currency string) (*PortfolioDTO, error) {
 	portfolio, err := s.repo.GetPortfolioWithAllocations(portfolioId)
 	if err != nil {
 		return nil, err
 	}
+	currency = fx.Normalize(currency)
+	portfolio.Currency = currency
 
 	var allocationIds []int64
 	var assetIds []int64
@@ -49,7 +65,7 @@ func (s *Service) GetPortfolioWithAllocations(portfolioId int64) (*PortfolioDTO,
 		assetIds = append(assetIds, allocation.Asset.Id)
 	}
 
-	amountMap, err := s.transactionService.CalculateAmountsAndPL(allocationIds, assetIds)
+	amountMap, err := s.transactionService.CalculateAmountsAndPL(allocationIds, assetIds, currency)
 	if err != nil {
 		return nil, err
 	}
@@ -63,6 +79,7 @@ func (s *Service) GetPortfolioWithAllocations(portfolioId int64) (*PortfolioDTO,
 		amount := amountMap[portfolio.Allocations[i].Id]
 		portfolio.Allocations[i].Amount = amount.CurrentAmount
 		portfolio.Allocations[i].UnrealizedPL = amount.UnrealizedPL
+		portfolio.Allocations[i].CurrencyEffect = amount.CurrencyEffect
 
 		if sumAmount != 0 {
 			percentage := (amount.CurrentAmount / sumAmount) * 100
@@ -77,16 +94,18 @@ func (s *Service) GetPortfolioWithAllocations(portfolioId int64) (*PortfolioDTO,
 	return portfolio, nil
 }
 
-func (s *Service) AddTransactionToPortfolio(request AddTransactionRequest) (*PortfolioDTO, error) {
-	portfolio, err := s.GetPortfolioWithAllocations(request.PortfolioId)
+func (s *Service) AddTransactionToPortfolio(request AddTransactionRequest, currency string) (*PortfolioDTO, error) {
+	portfolio, err := s.GetPortfolioWithAllocations(request.PortfolioId, currency)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get portfolio: %w", err)
 	}
 
 	var allocationId int64 = -1
+	var assetCurrency string
 	for _, allocation := range portfolio.Allocations {
 		if allocation.Asset.Symbol == request.Symbol {
 			allocationId = allocation.Id
+			assetCurrency = allocation.Asset.Currency
 			break
 		}
 	}
@@ -95,83 +114,224 @@ func (s *Service) AddTransactionToPortfolio(request AddTransactionRequest) (*Por
 		return nil, errors.New("symbol does not exist in portfolio allocations")
 	}
 
+	// Prices are in the asset's currency unless stated otherwise
+	transactionCurrency := assetCurrency
+	if request.Currency != "" {
+		transactionCurrency = fx.Normalize(request.Currency)
+		if !s.fxService.IsSupported(transactionCurrency) {
+			return nil, fmt.Errorf("%w: %s", fx.UnsupportedCurrencyErr, transactionCurrency)
+		}
+	}
+
 	newTransaction := &transaction.Transaction{
 		AllocationId: allocationId,
 		Side:         request.Side,
 		Quantity:     request.Quantity,
 		Price:        request.AvgPrice,
+		Currency:     transactionCurrency,
 	}
 
 	_, err = s.transactionService.Save(newTransaction)
 	if err != nil {
 		return nil, fmt.Errorf("failed to save transaction: %w", err)
 	}
+	s.invalidatePortfolio(request.PortfolioId)
 
-	return s.GetPortfolioWithAllocations(request.PortfolioId)
+	return s.GetPortfolioWithAllocations(request.PortfolioId, currency)
 }
 
 func (s *Service) GetPortfolioListByUser(userId int64) ([]PortfolioListResponse, error) {
+	currency, err := s.GetUserBaseCurrency(userId)
+	if err != nil {
+		return nil, err
+	}
+
 	portfolios, err := s.repo.GetPortfolioByUserIdWithAllocations(userId)
 	if err != nil {
 		return nil, err
 	}
 
-	response := make([]PortfolioListResponse, 0, len(portfolios))
+	return s.cachedValuations(portfolios, currency, false, s.valuePortfolios)
+}
+
+// GetPortfoliosWithAllocations returns the portfolios with their allocations, missing ones are left out
+func (s *Service) GetPortfoliosWithAllocations(portfolioIds []int64) ([]PortfolioDTO, error) {
+	return s.repo.GetPortfoliosWithAllocations(portfolioIds)
+}
 
+// GetPortfolioValuation returns the list entry of the portfolio, loaded with its allocations,
+// in the currency. A followed portfolio is weighted by its target allocation and has no amount.
+func (s *Service) GetPortfolioValuation(portfolio PortfolioDTO, currency string, followed bool) (*PortfolioListResponse, error) {
+	value := s.valuePortfolios
+	if followed {
+		value = s.valueFollowedPortfolios
+	}
+	entries, err := s.cachedValuations([]PortfolioDTO{portfolio}, currency, followed, value)
+	if err != nil {
+		return nil, err
+	}
+	return &entries[0], nil
+}
+
+// cachedValuations returns the memoized list entries of the portfolios, the missing
+// ones are calculated together and kept
+func (s *Service) cachedValuations(portfolios []PortfolioDTO, currency string, followed bool, value func([]PortfolioDTO, string) (map[int64]PortfolioListResponse, error)) ([]PortfolioListResponse, error) {
+	entries := make(map[int64]PortfolioListResponse, len(portfolios))
+	var missing []PortfolioDTO
 	for _, portfolio := range portfolios {
-		var allocationIds []int64
-		var assetIds []int64
-		for _, allocation := range portfolio.Allocations {
-			allocationIds = append(allocationIds, allocation.Id)
-			assetIds = append(assetIds, allocation.Asset.Id)
+		key := valuationKey{portfolioId: portfolio.Id, currency: currency, followed: followed}
+		if cached, ok := s.valuations.Get(key); ok {
+			entries[portfolio.Id] = cached.response
+		} else {
+			missing = append(missing, portfolio)
 		}
+	}
 
-		amountMap, err := s.transactionService.CalculateAmountsAndPL(allocationIds, assetIds)
+	if len(missing) > 0 {
+		generation := s.valuations.Generation()
+		calculated, err := value(missing, currency)
 		if err != nil {
 			return nil, err
 		}
+		for _, portfolio := range missing {
+			entry := calculated[portfolio.Id]
+			key := valuationKey{portfolioId: portfolio.Id, currency: currency, followed: followed}
+			s.valuations.Set(key, valuation{
+				response:   entry,
+				assetIds:   allocationAssetIds(portfolio.Allocations),
+				currencies: valuationCurrencies(currency, portfolio.Allocations),
+			}, generation)
+			entries[portfolio.Id] = entry
+		}
+	}
 
-		sumAmount := 0.0
-		sumPreviousDayAmount := 0.0
+	response := make([]PortfolioListResponse, 0, len(portfolios))
+	for _, portfolio := range portfolios {
+		response = append(response, entries[portfolio.Id])
+	}
+	return response, nil
+}
+
+// valuePortfolios calculates the value of the holdings and their daily change of every portfolio
+func (s *Service) valuePortfolios(portfolios []PortfolioDTO, currency string) (map[int64]PortfolioListResponse, error) {
+	var allocationIds []int64
+	var assetIds []int64
+	var assets []asset.SimpleAssetDTO
+	for _, portfolio := range portfolios {
 		for _, allocation := range portfolio.Allocations {
-			amount := amountMap[allocation.Id]
-			sumAmount += amount.CurrentAmount
-
-			// Get latest quote
-			latestQuote, err := s.assetService.GetLatestQuote(allocation.Asset.Id)
-			if err != nil {
-				return nil, err
-			}
-
-			// Get previous trading day quote
-			previousTradingDayQuote, err := s.assetService.GetPreviousTradingDayQuote(allocation.Asset.Id, latestQuote.QuoteTime)
-			if err != nil {
-				return nil, err
-			}
-
-			// Calculate previous trading day amount
-			previousDayAmount := (amount.CurrentAmount / latestQuote.Quote) * previousTradingDayQuote.Quote
-			sumPreviousDayAmount += previousDayAmount
+			allocationIds = append(allocationIds, allocation.Id)
+			assetIds = append(assetIds, allocation.Asset.Id)
+			assets = append(assets, allocation.Asset)
 		}
+	}
+
+	amountMap, err := s.transactionService.CalculateAmountsAndPL(allocationIds, assetIds, currency)
+	if err != nil {
+		return nil, err
+	}
+	quotes, err := s.getDailyQuotes(assets, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make(map[int64]PortfolioListResponse, len(portfolios))
+	for _, portfolio := range portfolios {
+		response[portfolio.Id] = valuePortfolio(portfolio, currency, amountMap, quotes)
+	}
+	return response, nil
+}
+
+// valuePortfolio calculates the value of the holdings and their daily change
+func valuePortfolio(portfolio PortfolioDTO, currency string, amountMap map[int64]transaction.AmountAndPLResult, quotes map[int64]dailyQuote) PortfolioListResponse {
+	sumAmount := 0.0
+	sumPreviousDayAmount := 0.0
+	sumPreviousDayLocalAmount := 0.0
+	for _, allocation := range portfolio.Allocations {
+		amount := amountMap[allocation.Id]
+		sumAmount += amount.CurrentAmount
+
+		quote := quotes[allocation.Asset.Id]
+
+		// Calculate previous trading day amount, with and without the FX move
+		previousDayLocalAmount := (amount.CurrentAmount / quote.latest) * quote.previous
+		sumPreviousDayLocalAmount += previousDayLocalAmount
+		sumPreviousDayAmount += previousDayLocalAmount * (quote.previousRate / quote.latestRate)
+	}
+
+	// Calculate daily change percentage
+	dailyChange := 0.0
+	if sumPreviousDayAmount != 0 {
+		dailyChange = ((sumAmount - sumPreviousDayAmount) / sumPreviousDayAmount) * 100
+	}
+	localChange := 0.0
+	if sumPreviousDayLocalAmount != 0 {
+		localChange = ((sumAmount - sumPreviousDayLocalAmount) / sumPreviousDayLocalAmount) * 100
+	}
+
+	return PortfolioListResponse{
+		Id:             portfolio.Id,
+		Symbol:         portfolio.Symbol,
+		Name:           portfolio.Name,
+		Change:         dailyChange,
+		Owner:          "",
+		Amount:         sumAmount,
+		Currency:       currency,
+		CurrencyEffect: dailyChange - localChange,
+	}
+}
+
+// getDailyQuotes loads the latest and previous trading day quotes of the assets with the FX rates at both times,
+// keyed by asset id
+func (s *Service) getDailyQuotes(assets []asset.SimpleAssetDTO, currency string) (map[int64]dailyQuote, error) {
+	assetIds := make([]int64, len(assets))
+	for i, a := range assets {
+		assetIds[i] = a.Id
+	}
+	latestQuotes, err := s.assetService.GetLatestQuotes(assetIds)
+	if err != nil {
+		return nil, err
+	}
 
-		// Calculate daily change percentage
-		dailyChange := 0.0
-		if sumPreviousDayAmount != 0 {
-			dailyChange = ((sumAmount - sumPreviousDayAmount) / sumPreviousDayAmount) * 100
+	previousRequests := make([]asset.PreviousCloseRequest, 0, len(assets))
+	for _, a := range assets {
+		latestQuote, ok := latestQuotes[a.Id]
+		if !ok {
+			return nil, sql.ErrNoRows
 		}
+		previousRequests = append(previousRequests, asset.PreviousCloseRequest{AssetId: a.Id, Exchange: a.Exchange, Time: latestQuote.QuoteTime})
+	}
+	previousQuotes, err := s.assetService.GetPreviousTradingDayQuotes(previousRequests)
+	if err != nil {
+		return nil, err
+	}
 
-		portfolioResponse := PortfolioListResponse{
-			Id:     portfolio.Id,
-			Symbol: portfolio.Symbol,
-			Name:   portfolio.Name,
-			Change: dailyChange,
-			Owner:  "",
-			Amount: sumAmount,
+	// the latest and previous rate of every asset, in the order of the assets
+	rateRequests := make([]fx.RateRequest, 0, 2*len(assets))
+	for _, a := range assets {
+		previousQuote, ok := previousQuotes[a.Id]
+		if !ok {
+			return nil, asset.NoPreviousTradingDayQuoteErr
 		}
+		rateRequests = append(rateRequests,
+			fx.RateRequest{From: a.Currency, To: currency, Time: latestQuotes[a.Id].QuoteTime},
+			fx.RateRequest{From: a.Currency, To: currency, Time: previousQuote.QuoteTime},
+		)
+	}
+	rates, err := s.fxService.Rates(rateRequests)
+	if err != nil {
+		return nil, err
+	}
 
-		response = append(response, portfolioResponse)
+	quotes := make(map[int64]dailyQuote, len(assets))
+	for i, a := range assets {
+		quotes[a.Id] = dailyQuote{
+			latest:       latestQuotes[a.Id].Quote,
+			previous:     previousQuotes[a.Id].Quote,
+			latestRate:   rates[2*i],
+			previousRate: rates[2*i+1],
+		}
 	}
-	return response, nil
+	return quotes, nil
 }
 
 func (s *Service) FollowPortfolio(userID, portfolioID int64) error {
@@ -215,58 +375,77 @@ func (s *Service) UnfollowPortfolio(userID, portfolioID int64) error {
 }
 
 func (s *Service) GetFollowedPortfolioList(userId int64) ([]PortfolioListResponse, error) {
+	currency, err := s.GetUserBaseCurrency(userId)
+	if err != nil {
+		return nil, err
+	}
+
 	portfolios, err := s.repo.GetFollowedPortfoliosWithAllocations(userId)
 	if err != nil {
 		return nil, err
 	}
-	response := make([]PortfolioListResponse, 0, len(portfolios))
+	return s.cachedValuations(portfolios, currency, true, s.valueFollowedPortfolios)
+}
 
+// valueFollowedPortfolios calculates the daily change of the target allocations, without holdings
+func (s *Service) valueFollowedPortfolios(portfolios []PortfolioDTO, currency string) (map[int64]PortfolioListResponse, error) {
+	var assets []asset.SimpleAssetDTO
 	for _, portfolio := range portfolios {
-		var totalChange float64
-		var totalPercentage float64
-
 		for _, allocation := range portfolio.Allocations {
-			// Get latest quote
-			latestQuote, err := s.assetService.GetLatestQuote(allocation.Asset.Id)
-			if err != nil {
-				return nil, err
-			}
-
-			// Get previous trading day quote
-			previousTradingDayQuote, err := s.assetService.GetPreviousTradingDayQuote(allocation.Asset.Id, latestQuote.QuoteTime)
-			if err != nil {
-				return nil, err
-			}
-
-			// Calculate daily change percentage for this asset
-			assetChange := 0.0
-			if previousTradingDayQuote.Quote != 0 {
-				assetChange = ((latestQuote.Quote - previousTradingDayQuote.Quote) / previousTradingDayQuote.Quote) * 100
-			}
-
-			// Weight the change by the target percentage
-			weightedChange := assetChange * (allocation.TargetPercentage / 100)
-			totalChange += weightedChange
-			totalPercentage += allocation.TargetPercentage
+			assets = append(assets, allocation.Asset)
 		}
+	}
+	quotes, err := s.getDailyQuotes(assets, currency)
+	if err != nil {
+		return nil, err
+	}
 
-		// Normalize the change if total percentage is not exactly 100%
-		if totalPercentage != 0 {
-			totalChange = (totalChange / totalPercentage) * 100
-		}
+	response := make(map[int64]PortfolioListResponse, len(portfolios))
+	for _, portfolio := range portfolios {
+		response[portfolio.Id] = valueFollowedPortfolio(portfolio, currency, quotes)
+	}
+	return response, nil
+}
 
-		portfolioResponse := PortfolioListResponse{
-			Id:     portfolio.Id,
-			Symbol: portfolio.Symbol,
-			Name:   portfolio.Name,
-			Change: totalChange,
-			Owner:  "", // Assuming you have this field in your portfolio struct
-			Amount: 0,  // As per your request, we're not calculating the actual amount
+// valueFollowedPortfolio calculates the daily change of the target allocation, without holdings
+func valueFollowedPortfolio(portfolio PortfolioDTO, currency string, quotes map[int64]dailyQuote) PortfolioListResponse {
+	var totalChange float64
+	var totalLocalChange float64
+	var totalPercentage float64
+
+	for _, allocation := range portfolio.Allocations {
+		quote := quotes[allocation.Asset.Id]
+
+		// Calculate daily change percentage for this asset, with and without the FX move
+		assetChange := 0.0
+		assetLocalChange := 0.0
+		if quote.previous != 0 {
+			assetLocalChange = ((quote.latest - quote.previous) / quote.previous) * 100
+			assetChange = ((quote.latest*quote.latestRate)/(quote.previous*quote.previousRate) - 1) * 100
 		}
 
-		response = append(response, portfolioResponse)
+		// Weight the change by the target percentage
+		totalChange += assetChange * (allocation.TargetPercentage / 100)
+		totalLocalChange += assetLocalChange * (allocation.TargetPercentage / 100)
+		totalPercentage += allocation.TargetPercentage
+	}
+
+	// Normalize the change if total percentage is not exactly 100%
+	if totalPercentage != 0 {
+		totalChange = (totalChange / totalPercentage) * 100
+		totalLocalChange = (totalLocalChange / totalPercentage) * 100
+	}
+
+	return PortfolioListResponse{
+		Id:             portfolio.Id,
+		Symbol:         portfolio.Symbol,
+		Name:           portfolio.Name,
+		Change:         totalChange,
+		Owner:          "", // Assuming you have this field in your portfolio struct
+		Amount:         0,  // As per your request, we're not calculating the actual amount
+		Currency:       currency,
+		CurrencyEffect: totalChange - totalLocalChange,
 	}
-	return response, nil
 }
 
 func (s *Service) GetFollowerCount(portfolioID int64) (int, error) {
@@ -305,6 +484,11 @@ func (s *Service) CreatePortfolio(req CreatePortfolioRequest) (*PortfolioDTO, er
 		return nil, fmt.Errorf("failed to create allocations: %w", err)
 	}
 
+	currency, err := s.GetUserBaseCurrency(req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
 	// Fetch the created portfolio with allocations
-	return s.GetPortfolioWithAllocations(createdPortfolio.Id)
+	return s.GetPortfolioWithAllocations(createdPortfolio.Id, currency)
 }
@@ -0,0 +1,268 @@
+package portfolio
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/internal/tradingcalendar"
+	"github.com/karataydev/portfoliomanbackend/internal/transaction"
+	"github.com/karataydev/portfoliomanbackend/internal/user"
+	"github.com/lib/pq"
+)
+
+// benchDSNEnv names the connection string of a migrated database the benchmarks seed,
+// they're skipped when it's unset
+const benchDSNEnv = "BENCH_DATABASE_DSN"
+
+const (
+	benchPortfolios              = 100
+	benchSmallPortfolios         = 10
+	benchAssets                  = 20
+	benchAllocationsPerPortfolio = 5
+)
+
+// BenchmarkGetPortfolioListByUser values the list of a user with 100 portfolios, uncached,
+// so every iteration runs the queries of the list. It fails when the list of 100 portfolios
+// runs more queries than the list of 10, the query count must not grow with the portfolios.
+func BenchmarkGetPortfolioListByUser(b *testing.B) {
+	dsn := os.Getenv(benchDSNEnv)
+	if dsn == "" {
+		b.Skipf("%s is not set", benchDSNEnv)
+	}
+	queries := &atomic.Int64{}
+	conn := openCountingDB(b, dsn, queries)
+
+	assetIds := seedAssets(b, conn)
+	smallUserId := seedPortfolios(b, conn, assetIds, benchSmallPortfolios)
+	userId := seedPortfolios(b, conn, assetIds, benchPortfolios)
+
+	calendars, err := tradingcalendar.Load("")
+	if err != nil {
+		b.Fatal(err)
+	}
+	assetService := asset.NewService(asset.NewRepository(conn), calendars, 0, nil)
+	fxService := fx.NewService(assetService)
+	transactionService := transaction.NewService(transaction.NewRepository(conn), assetService, fxService)
+	userService := user.NewService(user.NewRepository(conn), nil, fxService)
+	service := NewService(NewRepository(conn), transactionService, assetService, userService, fxService, 0)
+
+	countQueries := func(userId int64, want int) int64 {
+		queries.Store(0)
+		portfolios, err := service.GetPortfolioListByUser(userId)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(portfolios) != want {
+			b.Fatalf("got %d portfolios, want %d", len(portfolios), want)
+		}
+		return queries.Load()
+	}
+	small := countQueries(smallUserId, benchSmallPortfolios)
+	large := countQueries(userId, benchPortfolios)
+	if large > small {
+		b.Fatalf("listing %d portfolios ran %d queries and %d portfolios ran %d, the count grows with the portfolios",
+			benchSmallPortfolios, small, benchPortfolios, large)
+	}
+	b.ReportMetric(float64(large), "queries/op")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		portfolios, err := service.GetPortfolioListByUser(userId)
+		if err != nil {
+			b.Fatal(err)
+		}
+		if len(portfolios) != benchPortfolios {
+			b.Fatalf("got %d portfolios, want %d", len(portfolios), benchPortfolios)
+		}
+	}
+}
+
+// seedAssets creates the assets with ten days of hourly bars, they're deleted again with
+// their bars when the benchmark ends
+func seedAssets(b *testing.B, db *database.DBConnection) []int64 {
+	b.Helper()
+	suffix := time.Now().UnixNano()
+
+	assetIds := make([]int64, benchAssets)
+	for i := range assetIds {
+		err := db.Get(&assetIds[i], `
+			INSERT INTO asset (symbol, name, currency)
+			VALUES ($1, $1, 'USD')
+			RETURNING id
+		`, fmt.Sprintf("BENCH%d-%d", i, suffix))
+		if err != nil {
+			b.Fatalf("Failed to seed asset: %v", err)
+		}
+	}
+
+	b.Cleanup(func() {
+		// asset_quote doesn't cascade, the bars go first
+		for _, query := range []string{
+			"DELETE FROM asset_quote WHERE asset_id = ANY($1)",
+			"DELETE FROM asset_quote_snapshot WHERE asset_id = ANY($1)",
+			"DELETE FROM asset WHERE id = ANY($1)",
+		} {
+			if _, err := db.Exec(query, pq.Array(assetIds)); err != nil {
+				b.Fatalf("Failed to clean up assets: %v", err)
+			}
+		}
+	})
+
+	_, err := db.Exec(`
+		INSERT INTO asset_quote (asset_id, interval, open, high, low, quote, volume, quote_time)
+		SELECT a.id, $2, 100, 101, 99, 100 + random(), 1000, t
+		FROM unnest($1::bigint[]) AS a(id)
+		CROSS JOIN generate_series(date_trunc('hour', NOW()) - INTERVAL '10 days', date_trunc('hour', NOW()), INTERVAL '1 hour') AS t
+	`, pq.Array(assetIds), quoteprovider.IntervalOneHour)
+	if err != nil {
+		b.Fatalf("Failed to seed quotes: %v", err)
+	}
+	return assetIds
+}
+
+// seedPortfolios creates a user with the portfolios and their allocations with a buy each.
+// Everything is deleted again when the benchmark ends.
+func seedPortfolios(b *testing.B, db *database.DBConnection, assetIds []int64, portfolios int) int64 {
+	b.Helper()
+
+	var userId int64
+	err := db.Get(&userId, `
+		INSERT INTO users (first_name, last_name, email)
+		VALUES ('Bench', 'User', $1)
+		RETURNING id
+	`, fmt.Sprintf("bench-%d-%d@example.com", portfolios, time.Now().UnixNano()))
+	if err != nil {
+		b.Fatalf("Failed to seed user: %v", err)
+	}
+
+	b.Cleanup(func() {
+		// transactions don't cascade, the user takes its portfolios and allocations along
+		_, err := db.Exec(`DELETE FROM transaction WHERE allocation_id IN (
+			SELECT a.id FROM allocation a JOIN portfolio p ON p.id = a.portfolio_id WHERE p.user_id = $1
+		)`, userId)
+		if err == nil {
+			_, err = db.Exec("DELETE FROM users WHERE id = $1", userId)
+		}
+		if err != nil {
+			b.Fatalf("Failed to clean up portfolios: %v", err)
+		}
+	})
+
+	for p := 0; p < portfolios; p++ {
+		var portfolioId int64
+		err := db.Get(&portfolioId, `
+			INSERT INTO portfolio (user_id, name)
+			VALUES ($1, $2)
+			RETURNING id
+		`, userId, fmt.Sprintf("Bench %d", p))
+		if err != nil {
+			b.Fatalf("Failed to seed portfolio: %v", err)
+		}
+
+		for i := 0; i < benchAllocationsPerPortfolio; i++ {
+			assetId := assetIds[(p+i)%len(assetIds)]
+			var allocationId int64
+			err := db.Get(&allocationId, `
+				INSERT INTO allocation (portfolio_id, asset_id, target_percentage)
+				VALUES ($1, $2, 20)
+				RETURNING id
+			`, portfolioId, assetId)
+			if err != nil {
+				b.Fatalf("Failed to seed allocation: %v", err)
+			}
+
+			_, err = db.Exec(`
+				INSERT INTO transaction (side, quantity, price, allocation_id, created_at)
+				VALUES (0, 10, 95, $1, NOW() - INTERVAL '5 days')
+			`, allocationId)
+			if err != nil {
+				b.Fatalf("Failed to seed transaction: %v", err)
+			}
+		}
+	}
+	return userId
+}
+
+// openCountingDB connects through lib/pq, counting every statement sent to the database
+func openCountingDB(b *testing.B, dsn string, queries *atomic.Int64) *database.DBConnection {
+	b.Helper()
+	connector, err := pq.NewConnector(dsn)
+	if err != nil {
+		b.Fatalf("Failed to connect to database: %v", err)
+	}
+	db := sqlx.NewDb(sql.OpenDB(&countingConnector{Connector: connector, queries: queries}), "postgres")
+	if err := db.Ping(); err != nil {
+		b.Fatalf("Failed to connect to database: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+	return &database.DBConnection{DB: db}
+}
+
+type countingConnector struct {
+	*pq.Connector
+	queries *atomic.Int64
+}
+
+func (c *countingConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.Connector.Connect(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &countingConn{conn: conn, queries: c.queries}, nil
+}
+
+// countingConn forwards to the lib/pq connection, which implements every interface used here
+type countingConn struct {
+	conn    driver.Conn
+	queries *atomic.Int64
+}
+
+func (c *countingConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *countingConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	c.queries.Add(1)
+	return c.conn.(driver.ConnPrepareContext).PrepareContext(ctx, query)
+}
+
+func (c *countingConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries.Add(1)
+	return c.conn.(driver.QueryerContext).QueryContext(ctx, query, args)
+}
+
+func (c *countingConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries.Add(1)
+	return c.conn.(driver.ExecerContext).ExecContext(ctx, query, args)
+}
+
+func (c *countingConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *countingConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	return c.conn.(driver.ConnBeginTx).BeginTx(ctx, opts)
+}
+
+func (c *countingConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *countingConn) ResetSession(ctx context.Context) error {
+	return c.conn.(driver.SessionResetter).ResetSession(ctx)
+}
+
+func (c *countingConn) IsValid() bool {
+	return c.conn.(driver.Validator).IsValid()
+}
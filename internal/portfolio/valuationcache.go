@@ -0,0 +1,68 @@
+package portfolio
+
+import (
+	"slices"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
+	"github.com/karataydev/portfoliomanbackend/pkg/cache"
+)
+
+// valuationKey identifies a portfolio list entry, followed entries are weighted by target
+// percentages instead of holdings so they are kept apart
+type valuationKey struct {
+	portfolioId int64
+	currency    string
+	followed    bool
+}
+
+// valuation is a memoized list entry with the assets it was calculated from and the
+// currencies whose rates converted it
+type valuation struct {
+	response   PortfolioListResponse
+	assetIds   []int64
+	currencies []string
+}
+
+func newValuationCache(ttl time.Duration) *cache.Cache[valuationKey, valuation] {
+	return cache.New[valuationKey, valuation](ttl)
+}
+
+func allocationAssetIds(allocations []AllocationDTO) []int64 {
+	assetIds := make([]int64, 0, len(allocations))
+	for _, allocation := range allocations {
+		assetIds = append(assetIds, allocation.Asset.Id)
+	}
+	return assetIds
+}
+
+// valuationCurrencies returns the currency of the valuation and the currencies of its assets
+func valuationCurrencies(currency string, allocations []AllocationDTO) []string {
+	currencies := []string{fx.Normalize(currency)}
+	for _, allocation := range allocations {
+		if assetCurrency := fx.Normalize(allocation.Asset.Currency); !slices.Contains(currencies, assetCurrency) {
+			currencies = append(currencies, assetCurrency)
+		}
+	}
+	return currencies
+}
+
+// OnQuote drops the valuations of the portfolios holding the quoted asset, and for an FX rate
+// the valuations converted with it
+func (s *Service) OnQuote(quote asset.AssetQuoteChanData) {
+	rateCurrency, isRate := fx.RateCurrency(quote.Symbol)
+	s.valuations.DeleteFunc(func(_ valuationKey, v valuation) bool {
+		return slices.Contains(v.assetIds, quote.AssetId) || (isRate && slices.Contains(v.currencies, rateCurrency))
+	})
+}
+
+func (s *Service) invalidatePortfolio(portfolioId int64) {
+	s.valuations.DeleteFunc(func(key valuationKey, _ valuation) bool {
+		return key.portfolioId == portfolioId
+	})
+}
+
+func (s *Service) GetCacheMetrics() map[string]cache.Stats {
+	return map[string]cache.Stats{"portfolio_valuation": s.valuations.Stats()}
+}
@@ -0,0 +1,41 @@
+package quotebackfill
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetCoverage(c *fiber.Ctx) error {
+	coverage, err := h.service.GetCoverage()
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to calculate quote coverage"})
+	}
+	return c.JSON(coverage)
+}
+
+func (h *Handler) ScanGaps(c *fiber.Ctx) error {
+	result, err := h.service.ScanGaps()
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to scan quote gaps"})
+	}
+	return c.JSON(result)
+}
+
+func (h *Handler) GetJobs(c *fiber.Ctx) error {
+	jobs, err := h.service.GetJobs(c.Query("status"))
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to get backfill jobs"})
+	}
+	return c.JSON(jobs)
+}
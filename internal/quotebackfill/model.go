@@ -0,0 +1,70 @@
+package quotebackfill
+
+import (
+	"database/sql"
+	"time"
+)
+
+// runningJobTimeout is how long a job may run before it's taken as abandoned by a crashed instance
+const runningJobTimeout = time.Hour
+
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// BackfillJob is a range of trading days missing quotes of an asset
+type BackfillJob struct {
+	Id         int64          `db:"id" json:"id"`
+	AssetId    int64          `db:"asset_id" json:"asset_id"`
+	RangeStart time.Time      `db:"range_start" json:"range_start"`
+	RangeEnd   time.Time      `db:"range_end" json:"range_end"`
+	Interval   string         `db:"interval" json:"interval"`
+	Status     string         `db:"status" json:"status"`
+	Attempts   int            `db:"attempts" json:"attempts"`
+	LastError  sql.NullString `db:"last_error" json:"last_error"`
+	CreatedAt  time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt  time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// quoteDay is a day with at least one quote of an asset
+type quoteDay struct {
+	AssetId int64     `db:"asset_id"`
+	Day     time.Time `db:"day"`
+}
+
+// historyStart is the time of the first quote of an asset
+type historyStart struct {
+	AssetId int64     `db:"asset_id"`
+	Start   time.Time `db:"start"`
+}
+
+type DateRange struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type AssetCoverage struct {
+	AssetId       int64       `json:"asset_id"`
+	Symbol        string      `json:"symbol"`
+	ExpectedDays  int         `json:"expected_days"`
+	CoveredDays   int         `json:"covered_days"`
+	Coverage      float64     `json:"coverage"`
+	FirstQuoteDay *time.Time  `json:"first_quote_day"`
+	LastQuoteDay  *time.Time  `json:"last_quote_day"`
+	MissingRanges []DateRange `json:"missing_ranges"`
+	PendingJobs   int         `json:"pending_jobs"`
+}
+
+type CoverageResponse struct {
+	From   time.Time       `json:"from"`
+	To     time.Time       `json:"to"`
+	Assets []AssetCoverage `json:"assets"`
+}
+
+type ScanResult struct {
+	ScannedAssets int `json:"scanned_assets"`
+	QueuedJobs    int `json:"queued_jobs"`
+}
@@ -0,0 +1,164 @@
+package quotebackfill
+
+import (
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+// GetQuoteDays returns the UTC days having quotes of every asset since from
+func (r *Repository) GetQuoteDays(from time.Time) ([]quoteDay, error) {
+	query := `
+		SELECT asset_id, (quote_time AT TIME ZONE 'UTC')::date AS day
+		FROM asset_quote
+		WHERE interval = $2 AND quote_time >= $1
+		GROUP BY asset_id, day
+		ORDER BY asset_id, day
+	`
+	var days []quoteDay
+	err := r.db.Select(&days, query, from, quoteprovider.IntervalOneHour)
+	if err != nil {
+		return nil, err
+	}
+	return days, nil
+}
+
+// GetHistoryStarts returns the time of the first quote of every asset having quotes
+func (r *Repository) GetHistoryStarts() ([]historyStart, error) {
+	query := `
+		SELECT a.id AS asset_id, q.quote_time AS start
+		FROM asset a
+		JOIN LATERAL (
+			SELECT quote_time
+			FROM asset_quote
+			WHERE asset_id = a.id AND interval = $1
+			ORDER BY quote_time ASC
+			LIMIT 1
+		) q ON TRUE
+		WHERE a.delisted_at IS NULL
+	`
+	var starts []historyStart
+	err := r.db.Select(&starts, query, quoteprovider.IntervalOneHour)
+	if err != nil {
+		return nil, err
+	}
+	return starts, nil
+}
+
+// QueueJob queues the range again when its latest job finished without filling it, while it has
+// attempts left, and inserts a job for a range never queued. A range already pending or running
+// is left alone. Returns whether the range was queued.
+func (r *Repository) QueueJob(job BackfillJob, maxAttempts int) (bool, error) {
+	query := `
+		WITH previous AS (
+			SELECT id, status, attempts
+			FROM quote_backfill_job
+			WHERE asset_id = $1 AND range_start = $2 AND range_end = $3 AND interval = $4
+			ORDER BY created_at DESC, id DESC
+			LIMIT 1
+		), requeued AS (
+			UPDATE quote_backfill_job j
+			SET status = 'pending'
+			FROM previous p
+			WHERE j.id = p.id AND p.status IN ('done', 'failed') AND p.attempts < $5
+			RETURNING j.id
+		), inserted AS (
+			INSERT INTO quote_backfill_job (asset_id, range_start, range_end, interval)
+			SELECT $1, $2, $3, $4
+			WHERE NOT EXISTS (SELECT 1 FROM previous)
+			ON CONFLICT DO NOTHING
+			RETURNING id
+		)
+		SELECT (SELECT COUNT(*) FROM requeued) + (SELECT COUNT(*) FROM inserted)
+	`
+	var queued int
+	err := r.db.Get(&queued, query, job.AssetId, job.RangeStart, job.RangeEnd, job.Interval, maxAttempts)
+	if err != nil {
+		return false, err
+	}
+	return queued > 0, nil
+}
+
+func (r *Repository) GetJobs(status string) ([]BackfillJob, error) {
+	query := `
+		SELECT *
+		FROM quote_backfill_job
+		WHERE $1 = '' OR status = $1
+		ORDER BY created_at ASC
+	`
+	jobs := []BackfillJob{}
+	err := r.db.Select(&jobs, query, status)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// ClaimPendingJobs marks up to limit pending jobs as running and returns them, the most recent
+// ranges first so old gaps can't hold up new ones
+func (r *Repository) ClaimPendingJobs(limit int) ([]BackfillJob, error) {
+	query := `
+		UPDATE quote_backfill_job
+		SET status = 'running', attempts = attempts + 1
+		WHERE id IN (
+			SELECT id FROM quote_backfill_job
+			WHERE status = 'pending'
+			ORDER BY range_end DESC, range_start DESC
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *
+	`
+	var jobs []BackfillJob
+	err := r.db.Select(&jobs, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	return jobs, nil
+}
+
+// FailAbandonedJobs fails the jobs running since before the time, an instance that stopped mid run
+// left them behind. Their claim counted as an attempt, so the next scan queues them like any failed job.
+func (r *Repository) FailAbandonedJobs(before time.Time) (int64, error) {
+	query := `
+		UPDATE quote_backfill_job
+		SET status = 'failed', last_error = 'abandoned while running'
+		WHERE status = 'running' AND updated_at < $1
+	`
+	result, err := r.db.Exec(query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
+// ReleaseJobs hands claimed jobs that never ran back to the queue, their claim didn't count as an attempt
+func (r *Repository) ReleaseJobs(jobIds []int64) error {
+	query := `
+		UPDATE quote_backfill_job
+		SET status = 'pending', attempts = attempts - 1
+		WHERE id = ANY($1) AND status = 'running'
+	`
+	_, err := r.db.Exec(query, pq.Array(jobIds))
+	return err
+}
+
+func (r *Repository) FinishJob(jobId int64, status string, lastError string) error {
+	query := `
+		UPDATE quote_backfill_job
+		SET status = $2, last_error = NULLIF($3, '')
+		WHERE id = $1
+	`
+	_, err := r.db.Exec(query, jobId, status, lastError)
+	return err
+}
@@ -0,0 +1,259 @@
+package quotebackfill
+
+import (
+	"context"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/assetquotefeeder"
+	"github.com/karataydev/portfoliomanbackend/internal/quoteprovider"
+	"github.com/karataydev/portfoliomanbackend/internal/tradingcalendar"
+)
+
+const dayLayout = "2006-01-02"
+
+type Service struct {
+	repo                    *Repository
+	assetService            *asset.Service
+	assetQuoteFeederService *assetquotefeeder.Service
+	calendars               *tradingcalendar.Registry
+	lookbackDays            int
+	maxAttempts             int
+}
+
+func NewService(repo *Repository, assetService *asset.Service, assetQuoteFeederService *assetquotefeeder.Service, calendars *tradingcalendar.Registry, lookbackDays int, maxAttempts int) *Service {
+	if maxAttempts <= 0 {
+		maxAttempts = 1
+	}
+	return &Service{
+		repo:                    repo,
+		assetService:            assetService,
+		assetQuoteFeederService: assetQuoteFeederService,
+		calendars:               calendars,
+		lookbackDays:            lookbackDays,
+		maxAttempts:             maxAttempts,
+	}
+}
+
+// window is the period checked for gaps, it ends yesterday as today's quotes may not be in yet
+func (s *Service) window() (time.Time, time.Time) {
+	now := time.Now().UTC()
+	to := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -1)
+	return to.AddDate(0, 0, -s.lookbackDays), to
+}
+
+func (s *Service) GetCoverage() (*CoverageResponse, error) {
+	from, to := s.window()
+	coverage, err := s.calculateCoverage(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	pendingJobs, err := s.repo.GetJobs(JobStatusPending)
+	if err != nil {
+		return nil, err
+	}
+	pendingByAsset := make(map[int64]int)
+	for _, job := range pendingJobs {
+		pendingByAsset[job.AssetId]++
+	}
+	for i := range coverage {
+		coverage[i].PendingJobs = pendingByAsset[coverage[i].AssetId]
+	}
+
+	return &CoverageResponse{From: from, To: to, Assets: coverage}, nil
+}
+
+// ScanGaps compares the quote coverage of every active asset with the trading calendar
+// and queues a backfill job for each missing range that has attempts left. Jobs left running
+// by a stopped instance are failed first, so their ranges are queued again.
+func (s *Service) ScanGaps() (*ScanResult, error) {
+	abandoned, err := s.repo.FailAbandonedJobs(time.Now().Add(-runningJobTimeout))
+	if err != nil {
+		return nil, err
+	}
+	if abandoned > 0 {
+		log.Warnf("Failed %d backfill jobs abandoned while running", abandoned)
+	}
+
+	from, to := s.window()
+	coverage, err := s.calculateCoverage(from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ScanResult{ScannedAssets: len(coverage)}
+	for _, assetCoverage := range coverage {
+		for _, missing := range assetCoverage.MissingRanges {
+			queued, err := s.repo.QueueJob(BackfillJob{
+				AssetId:    assetCoverage.AssetId,
+				RangeStart: missing.Start,
+				RangeEnd:   missing.End,
+				Interval:   quoteprovider.IntervalOneHour,
+			}, s.maxAttempts)
+			if err != nil {
+				return nil, err
+			}
+			if queued {
+				result.QueuedJobs++
+			}
+		}
+	}
+	return result, nil
+}
+
+func (s *Service) GetJobs(status string) ([]BackfillJob, error) {
+	return s.repo.GetJobs(status)
+}
+
+// RunPendingJobs fetches the quotes of up to limit pending jobs, ranges still missing are queued
+// again by the next scan until they run out of attempts. Once ctx is done the jobs not started
+// yet are handed back without counting the attempt.
+func (s *Service) RunPendingJobs(ctx context.Context, limit int) error {
+	jobs, err := s.repo.ClaimPendingJobs(limit)
+	if err != nil {
+		return err
+	}
+	if len(jobs) == 0 {
+		return nil
+	}
+
+	assets, err := s.assetService.GetActiveAssets()
+	if err != nil {
+		return err
+	}
+	assetsById := make(map[int64]asset.SimpleAssetDTO, len(assets))
+	for _, a := range assets {
+		assetsById[a.Id] = a
+	}
+
+	for i, job := range jobs {
+		if ctx.Err() != nil {
+			s.releaseJobs(jobs[i:])
+			return ctx.Err()
+		}
+		a, ok := assetsById[job.AssetId]
+		if !ok {
+			s.finishJob(job, JobStatusFailed, "asset is not active")
+			continue
+		}
+		// the job is done once its bars are saved, not when they're queued
+		err := s.assetQuoteFeederService.ScrapeAssetAndWait(a, job.RangeStart, job.RangeEnd.AddDate(0, 0, 1), job.Interval)
+		if err != nil {
+			log.Warnf("Backfill of %s from %s to %s failed: %v", a.Symbol, job.RangeStart.Format(dayLayout), job.RangeEnd.Format(dayLayout), err)
+			s.finishJob(job, JobStatusFailed, err.Error())
+			continue
+		}
+		s.finishJob(job, JobStatusDone, "")
+	}
+	return nil
+}
+
+func (s *Service) releaseJobs(jobs []BackfillJob) {
+	jobIds := make([]int64, len(jobs))
+	for i, job := range jobs {
+		jobIds[i] = job.Id
+	}
+	if err := s.repo.ReleaseJobs(jobIds); err != nil {
+		log.Errorf("Error releasing backfill jobs: %v", err)
+	}
+}
+
+func (s *Service) finishJob(job BackfillJob, status string, lastError string) {
+	if err := s.repo.FinishJob(job.Id, status, lastError); err != nil {
+		log.Errorf("Error finishing backfill job %d: %v", job.Id, err)
+	}
+}
+
+// ScanAndBackfill queues the current gaps and runs the pending jobs until ctx is done
+func (s *Service) ScanAndBackfill(ctx context.Context, limit int) {
+	result, err := s.ScanGaps()
+	if err != nil {
+		log.Errorf("Error scanning quote gaps: %v", err)
+		return
+	}
+	log.Infof("Quote gap scan checked %d assets and queued %d backfill jobs", result.ScannedAssets, result.QueuedJobs)
+
+	if err := s.RunPendingJobs(ctx, limit); err != nil {
+		log.Errorf("Error running backfill jobs: %v", err)
+	}
+}
+
+func (s *Service) calculateCoverage(from, to time.Time) ([]AssetCoverage, error) {
+	assets, err := s.assetService.GetActiveAssets()
+	if err != nil {
+		return nil, err
+	}
+	quoteDays, err := s.repo.GetQuoteDays(from)
+	if err != nil {
+		return nil, err
+	}
+	starts, err := s.repo.GetHistoryStarts()
+	if err != nil {
+		return nil, err
+	}
+	startsByAsset := make(map[int64]time.Time, len(starts))
+	for _, start := range starts {
+		startsByAsset[start.AssetId] = start.Start
+	}
+
+	daysByAsset := make(map[int64]map[string]bool)
+	for _, quoteDay := range quoteDays {
+		if daysByAsset[quoteDay.AssetId] == nil {
+			daysByAsset[quoteDay.AssetId] = make(map[string]bool)
+		}
+		daysByAsset[quoteDay.AssetId][quoteDay.Day.Format(dayLayout)] = true
+	}
+
+	coverage := make([]AssetCoverage, 0, len(assets))
+	for _, a := range assets {
+		// the days before the first quote of an asset were never traded or never offered by the provider
+		assetFrom := from
+		if start, ok := startsByAsset[a.Id]; ok {
+			if day := start.UTC().Truncate(24 * time.Hour); day.After(assetFrom) {
+				assetFrom = day
+			}
+		}
+		expected := s.calendars.For(a.Exchange).TradingDays(assetFrom, to)
+		coverage = append(coverage, assetCoverage(a, expected, daysByAsset[a.Id]))
+	}
+	return coverage, nil
+}
+
+func assetCoverage(a asset.SimpleAssetDTO, expected []time.Time, covered map[string]bool) AssetCoverage {
+	result := AssetCoverage{
+		AssetId:       a.Id,
+		Symbol:        a.Symbol,
+		ExpectedDays:  len(expected),
+		MissingRanges: []DateRange{},
+	}
+
+	var current *DateRange
+	for _, day := range expected {
+		if covered[day.Format(dayLayout)] {
+			result.CoveredDays++
+			if result.FirstQuoteDay == nil {
+				first := day
+				result.FirstQuoteDay = &first
+			}
+			last := day
+			result.LastQuoteDay = &last
+			current = nil
+			continue
+		}
+
+		// consecutive missing trading days form a single range, even across weekends
+		if current == nil {
+			result.MissingRanges = append(result.MissingRanges, DateRange{Start: day, End: day})
+			current = &result.MissingRanges[len(result.MissingRanges)-1]
+		} else {
+			current.End = day
+		}
+	}
+
+	if result.ExpectedDays > 0 {
+		result.Coverage = float64(result.CoveredDays) / float64(result.ExpectedDays)
+	}
+	return result
+}
@@ -0,0 +1,144 @@
+package quoteprovider
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CSVProvider reads bars from files in a directory. Bars of an interval are read from
+// <SYMBOL>_<interval>.csv, falling back to <SYMBOL>.csv. Files need a header with
+// time and close columns, open, high, low and volume are optional. Times are either
+// RFC3339 or dates, and mark when the bar closes.
+type CSVProvider struct {
+	dir string
+}
+
+func NewCSVProvider(dir string) *CSVProvider {
+	return &CSVProvider{dir: dir}
+}
+
+func (p *CSVProvider) Name() string {
+	return "csv"
+}
+
+func (p *CSVProvider) HistoricalBars(symbol string, from, to time.Time, interval string) ([]Bar, error) {
+	bars, err := p.readBars(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	return filterBars(bars, from, to), nil
+}
+
+func (p *CSVProvider) LatestQuote(symbol string) (*Bar, error) {
+	bars, err := p.readBars(symbol, IntervalOneHour)
+	if err != nil {
+		return nil, err
+	}
+	if len(bars) == 0 {
+		return nil, NoDataErr
+	}
+	return &bars[len(bars)-1], nil
+}
+
+func (p *CSVProvider) LookupSymbol(symbol string) (*SymbolInfo, error) {
+	if _, err := p.path(symbol, IntervalOneDay); err != nil {
+		return nil, err
+	}
+	return &SymbolInfo{Symbol: strings.ToUpper(symbol)}, nil
+}
+
+func (p *CSVProvider) path(symbol, interval string) (string, error) {
+	symbol = strings.ToUpper(symbol)
+	for _, name := range []string{symbol + "_" + interval + ".csv", symbol + ".csv"} {
+		path := filepath.Join(p.dir, name)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return "", SymbolNotFoundErr
+}
+
+func (p *CSVProvider) readBars(symbol, interval string) ([]Bar, error) {
+	path, err := p.path(symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%s: could not read header: %v", path, err)
+	}
+	columns := make(map[string]int)
+	for i, column := range header {
+		columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	if _, ok := columns["time"]; !ok {
+		return nil, fmt.Errorf("%s: time column is required", path)
+	}
+	if _, ok := columns["close"]; !ok {
+		return nil, fmt.Errorf("%s: close column is required", path)
+	}
+
+	period := intervalDuration(interval)
+	var bars []Bar
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", path, line, err)
+		}
+
+		closesAt, err := parseTime(record[columns["time"]])
+		if err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", path, line, err)
+		}
+		value := func(name string) (float64, error) {
+			i, ok := columns[name]
+			if !ok || i >= len(record) || record[i] == "" {
+				return 0, nil
+			}
+			return strconv.ParseFloat(record[i], 64)
+		}
+
+		bar := Bar{Symbol: strings.ToUpper(symbol), OpensAt: closesAt.Add(-period), ClosesAt: closesAt}
+		for name, target := range map[string]*float64{
+			"open": &bar.Open, "high": &bar.High, "low": &bar.Low, "close": &bar.Close, "volume": &bar.Volume,
+		} {
+			if *target, err = value(name); err != nil {
+				return nil, fmt.Errorf("%s: line %d: invalid %s: %v", path, line, name, err)
+			}
+		}
+		bars = append(bars, bar)
+	}
+
+	sort.Slice(bars, func(i, j int) bool {
+		return bars[i].ClosesAt.Before(bars[j].ClosesAt)
+	})
+	return bars, nil
+}
+
+func parseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
@@ -0,0 +1,130 @@
+package quoteprovider
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FakeProvider serves bars from memory, so the feeder can run and be tested without network
+// access. The app only registers it when QUOTE_FAKE_PROVIDER is set.
+type FakeProvider struct {
+	name  string
+	mu    sync.RWMutex
+	bars  map[fakeSeries][]Bar
+	infos map[string]SymbolInfo
+	errs  map[string]error
+}
+
+// fakeSeries identifies the bars of a symbol in an interval
+type fakeSeries struct {
+	symbol   string
+	interval string
+}
+
+// NewFakeProvider returns an empty fake registered under name, "fake" when name is empty,
+// several fakes can stand in for a provider and its fallbacks
+func NewFakeProvider(name string) *FakeProvider {
+	if name == "" {
+		name = "fake"
+	}
+	return &FakeProvider{
+		name:  name,
+		bars:  make(map[fakeSeries][]Bar),
+		infos: make(map[string]SymbolInfo),
+		errs:  make(map[string]error),
+	}
+}
+
+func (p *FakeProvider) Name() string {
+	return p.name
+}
+
+// AddBars stores bars of the interval for the symbol, which makes the symbol known to LookupSymbol
+func (p *FakeProvider) AddBars(symbol, interval string, bars ...Bar) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	symbol = strings.ToUpper(symbol)
+	for i := range bars {
+		bars[i].Symbol = symbol
+	}
+	series := fakeSeries{symbol: symbol, interval: interval}
+	p.bars[series] = append(p.bars[series], bars...)
+	sort.Slice(p.bars[series], func(i, j int) bool {
+		return p.bars[series][i].ClosesAt.Before(p.bars[series][j].ClosesAt)
+	})
+	if _, ok := p.infos[symbol]; !ok {
+		p.infos[symbol] = SymbolInfo{Symbol: symbol}
+	}
+}
+
+func (p *FakeProvider) SetSymbolInfo(info SymbolInfo) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	info.Symbol = strings.ToUpper(info.Symbol)
+	p.infos[info.Symbol] = info
+}
+
+// SetError makes every call for the symbol fail with err, a nil err clears it
+func (p *FakeProvider) SetError(symbol string, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	symbol = strings.ToUpper(symbol)
+	if err == nil {
+		delete(p.errs, symbol)
+		return
+	}
+	p.errs[symbol] = err
+}
+
+func (p *FakeProvider) HistoricalBars(symbol string, from, to time.Time, interval string) ([]Bar, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	symbol = strings.ToUpper(symbol)
+	if err := p.errs[symbol]; err != nil {
+		return nil, err
+	}
+	return filterBars(p.bars[fakeSeries{symbol: symbol, interval: interval}], from, to), nil
+}
+
+func (p *FakeProvider) LatestQuote(symbol string) (*Bar, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	symbol = strings.ToUpper(symbol)
+	if err := p.errs[symbol]; err != nil {
+		return nil, err
+	}
+	// the latest bar of any interval
+	var latest *Bar
+	for series, bars := range p.bars {
+		if series.symbol != symbol || len(bars) == 0 {
+			continue
+		}
+		if bar := bars[len(bars)-1]; latest == nil || bar.ClosesAt.After(latest.ClosesAt) {
+			latest = &bar
+		}
+	}
+	if latest == nil {
+		return nil, NoDataErr
+	}
+	return latest, nil
+}
+
+func (p *FakeProvider) LookupSymbol(symbol string) (*SymbolInfo, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	symbol = strings.ToUpper(symbol)
+	if err := p.errs[symbol]; err != nil {
+		return nil, err
+	}
+	info, ok := p.infos[symbol]
+	if !ok {
+		return nil, SymbolNotFoundErr
+	}
+	return &info, nil
+}
@@ -0,0 +1,120 @@
+package quoteprovider
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// HTTPProviderConfig holds the endpoint templates of an HTTP quote source. The
+// {symbol}, {from}, {to} and {interval} placeholders are replaced in every request,
+// times are formatted as RFC3339.
+//
+// The history endpoint returns {"bars": [Bar...]}, the latest endpoint a single Bar
+// and the lookup endpoint a SymbolInfo, a 404 from lookup means the symbol is unknown.
+type HTTPProviderConfig struct {
+	HistoryURL   string
+	LatestURL    string
+	LookupURL    string
+	APIKey       string
+	APIKeyHeader string
+	Timeout      time.Duration
+}
+
+type HTTPProvider struct {
+	config     HTTPProviderConfig
+	httpClient *http.Client
+}
+
+func NewHTTPProvider(config HTTPProviderConfig) *HTTPProvider {
+	if config.APIKeyHeader == "" {
+		config.APIKeyHeader = "X-API-Key"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = 30 * time.Second
+	}
+	return &HTTPProvider{
+		config:     config,
+		httpClient: &http.Client{Timeout: config.Timeout},
+	}
+}
+
+func (p *HTTPProvider) Name() string {
+	return "http"
+}
+
+func (p *HTTPProvider) HistoricalBars(symbol string, from, to time.Time, interval string) ([]Bar, error) {
+	var resp struct {
+		Bars []Bar `json:"bars"`
+	}
+	if err := p.get(p.config.HistoryURL, symbol, from, to, interval, &resp); err != nil {
+		return nil, err
+	}
+	return filterBars(resp.Bars, from, to), nil
+}
+
+func (p *HTTPProvider) LatestQuote(symbol string) (*Bar, error) {
+	var bar Bar
+	now := time.Now()
+	if err := p.get(p.config.LatestURL, symbol, now, now, "", &bar); err != nil {
+		return nil, err
+	}
+	if bar.ClosesAt.IsZero() {
+		return nil, NoDataErr
+	}
+	return &bar, nil
+}
+
+func (p *HTTPProvider) LookupSymbol(symbol string) (*SymbolInfo, error) {
+	var info SymbolInfo
+	now := time.Now()
+	if err := p.get(p.config.LookupURL, symbol, now, now, "", &info); err != nil {
+		return nil, err
+	}
+	if info.Symbol == "" {
+		info.Symbol = strings.ToUpper(symbol)
+	}
+	return &info, nil
+}
+
+func (p *HTTPProvider) get(template, symbol string, from, to time.Time, interval string, target interface{}) error {
+	if template == "" {
+		return fmt.Errorf("%s provider: endpoint is not configured", p.Name())
+	}
+
+	requestUrl := strings.NewReplacer(
+		"{symbol}", url.QueryEscape(symbol),
+		"{from}", url.QueryEscape(from.Format(time.RFC3339)),
+		"{to}", url.QueryEscape(to.Format(time.RFC3339)),
+		"{interval}", url.QueryEscape(interval),
+	).Replace(template)
+
+	req, err := http.NewRequest("GET", requestUrl, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %v", err)
+	}
+	if p.config.APIKey != "" {
+		req.Header.Set(p.config.APIKeyHeader, p.config.APIKey)
+	}
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return SymbolNotFoundErr
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(target); err != nil {
+		return fmt.Errorf("failed to decode response: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package quoteprovider
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	IntervalOneHour = "1h"
+	IntervalOneDay  = "1d"
+)
+
+var SymbolNotFoundErr error = errors.New("symbol not found at quote provider")
+var NoDataErr error = errors.New("quote provider returned no data")
+
+// Bar is a single OHLCV bar of a symbol
+type Bar struct {
+	Symbol   string    `json:"symbol"`
+	OpensAt  time.Time `json:"opens_at"`
+	ClosesAt time.Time `json:"closes_at"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Volume   float64   `json:"volume"`
+}
+
+type SymbolInfo struct {
+	Symbol     string `json:"symbol"`
+	Name       string `json:"name"`
+	Currency   string `json:"currency"`
+	Exchange   string `json:"exchange"`
+	AssetClass string `json:"asset_class"`
+}
+
+// QuoteProvider is a source of market data
+type QuoteProvider interface {
+	// Name identifies the provider in configuration
+	Name() string
+	// HistoricalBars returns the bars of the interval closing between from and to, ordered by time
+	HistoricalBars(symbol string, from, to time.Time, interval string) ([]Bar, error)
+	// LatestQuote returns the most recent bar available
+	LatestQuote(symbol string) (*Bar, error)
+	// LookupSymbol returns what the provider knows about a symbol, or SymbolNotFoundErr
+	LookupSymbol(symbol string) (*SymbolInfo, error)
+}
+
+// filterBars keeps the bars closing between from and to
+func filterBars(bars []Bar, from, to time.Time) []Bar {
+	filtered := make([]Bar, 0, len(bars))
+	for _, bar := range bars {
+		if bar.ClosesAt.Before(from) || bar.ClosesAt.After(to) {
+			continue
+		}
+		filtered = append(filtered, bar)
+	}
+	return filtered
+}
+
+func intervalDuration(interval string) time.Duration {
+	switch interval {
+	case IntervalOneHour:
+		return time.Hour
+	case IntervalOneDay:
+		return 24 * time.Hour
+	default:
+		if d, err := time.ParseDuration(interval); err == nil {
+			return d
+		}
+		return 24 * time.Hour
+	}
+}
@@ -0,0 +1,55 @@
+package quoteprovider
+
+import (
+	"sync"
+	"time"
+)
+
+// RateLimitedProvider spaces out the calls to a provider to stay under its request limit
+type RateLimitedProvider struct {
+	provider QuoteProvider
+	spacing  time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// NewRateLimitedProvider allows requestsPerMinute calls to provider, callers over the limit wait their turn
+func NewRateLimitedProvider(provider QuoteProvider, requestsPerMinute int) *RateLimitedProvider {
+	return &RateLimitedProvider{
+		provider: provider,
+		spacing:  time.Minute / time.Duration(requestsPerMinute),
+	}
+}
+
+func (p *RateLimitedProvider) wait() {
+	p.mu.Lock()
+	now := time.Now()
+	if p.next.Before(now) {
+		p.next = now
+	}
+	delay := p.next.Sub(now)
+	p.next = p.next.Add(p.spacing)
+	p.mu.Unlock()
+
+	time.Sleep(delay)
+}
+
+func (p *RateLimitedProvider) Name() string {
+	return p.provider.Name()
+}
+
+func (p *RateLimitedProvider) HistoricalBars(symbol string, from, to time.Time, interval string) ([]Bar, error) {
+	p.wait()
+	return p.provider.HistoricalBars(symbol, from, to, interval)
+}
+
+func (p *RateLimitedProvider) LatestQuote(symbol string) (*Bar, error) {
+	p.wait()
+	return p.provider.LatestQuote(symbol)
+}
+
+func (p *RateLimitedProvider) LookupSymbol(symbol string) (*SymbolInfo, error) {
+	p.wait()
+	return p.provider.LookupSymbol(symbol)
+}
@@ -0,0 +1,82 @@
+package quoteprovider
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Registry picks the provider of every symbol, the default one unless the symbol is overridden
+type Registry struct {
+	providers       map[string]QuoteProvider
+	defaultProvider string
+	overrides       map[string]string
+	fallbacks       []string
+}
+
+func NewRegistry(defaultProvider string, overrides map[string]string) *Registry {
+	normalized := make(map[string]string, len(overrides))
+	for symbol, provider := range overrides {
+		normalized[strings.ToUpper(symbol)] = provider
+	}
+	return &Registry{
+		providers:       make(map[string]QuoteProvider),
+		defaultProvider: defaultProvider,
+		overrides:       normalized,
+	}
+}
+
+func (r *Registry) Register(provider QuoteProvider) {
+	r.providers[provider.Name()] = provider
+}
+
+// SetFallbacks sets the providers tried, in order, when the provider of a symbol fails
+func (r *Registry) SetFallbacks(names ...string) {
+	r.fallbacks = names
+}
+
+// Validate checks every configured provider is registered
+func (r *Registry) Validate() error {
+	if _, ok := r.providers[r.defaultProvider]; !ok {
+		return fmt.Errorf("default quote provider %q is not registered", r.defaultProvider)
+	}
+	for symbol, name := range r.overrides {
+		if _, ok := r.providers[name]; !ok {
+			return fmt.Errorf("quote provider %q of %s is not registered", name, symbol)
+		}
+	}
+	for _, name := range r.fallbacks {
+		if _, ok := r.providers[name]; !ok {
+			return fmt.Errorf("fallback quote provider %q is not registered", name)
+		}
+	}
+	return nil
+}
+
+func (r *Registry) Get(name string) (QuoteProvider, bool) {
+	provider, ok := r.providers[name]
+	return provider, ok
+}
+
+// For returns the provider configured for the symbol
+func (r *Registry) For(symbol string) QuoteProvider {
+	if name, ok := r.overrides[strings.ToUpper(symbol)]; ok {
+		if provider, ok := r.providers[name]; ok {
+			return provider
+		}
+	}
+	return r.providers[r.defaultProvider]
+}
+
+// Chain returns the provider of the symbol followed by the fallback providers
+func (r *Registry) Chain(symbol string) []QuoteProvider {
+	primary := r.For(symbol)
+	chain := []QuoteProvider{primary}
+	for _, name := range r.fallbacks {
+		provider, ok := r.providers[name]
+		if !ok || provider.Name() == primary.Name() {
+			continue
+		}
+		chain = append(chain, provider)
+	}
+	return chain
+}
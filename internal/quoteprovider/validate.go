@@ -0,0 +1,48 @@
+package quoteprovider
+
+import (
+	"math"
+	"time"
+)
+
+// Discrepancy is a bar whose close disagrees with another source
+type Discrepancy struct {
+	Bar            Bar
+	ReferenceClose float64
+	Deviation      float64
+}
+
+// CompareBars matches the bars of two sources by interval and returns the ones whose closes
+// deviate by more than tolerance, a fraction of the reference close
+func CompareBars(bars, reference []Bar, interval string, tolerance float64) []Discrepancy {
+	step := intervalDuration(interval)
+	referenceCloses := make(map[time.Time]float64, len(reference))
+	for _, bar := range reference {
+		referenceCloses[bar.ClosesAt.Truncate(step)] = bar.Close
+	}
+
+	var discrepancies []Discrepancy
+	for _, bar := range bars {
+		referenceClose, ok := referenceCloses[bar.ClosesAt.Truncate(step)]
+		if !ok || referenceClose <= 0 {
+			continue
+		}
+		deviation := math.Abs(bar.Close-referenceClose) / referenceClose
+		if deviation > tolerance {
+			discrepancies = append(discrepancies, Discrepancy{
+				Bar:            bar,
+				ReferenceClose: referenceClose,
+				Deviation:      deviation,
+			})
+		}
+	}
+	return discrepancies
+}
+
+// IsStale reports whether the latest bar closed more than maxAge before the end of the requested period
+func IsStale(bars []Bar, to time.Time, maxAge time.Duration) bool {
+	if len(bars) == 0 {
+		return true
+	}
+	return to.Sub(bars[len(bars)-1].ClosesAt) > maxAge
+}
@@ -0,0 +1,104 @@
+package quoteprovider
+
+import (
+	"strings"
+	"time"
+
+	"github.com/svarlamov/goyhfin"
+)
+
+// YahooProvider reads quotes through the unofficial Yahoo Finance chart api
+type YahooProvider struct{}
+
+func NewYahooProvider() *YahooProvider {
+	return &YahooProvider{}
+}
+
+func (p *YahooProvider) Name() string {
+	return "yahoo"
+}
+
+func (p *YahooProvider) HistoricalBars(symbol string, from, to time.Time, interval string) ([]Bar, error) {
+	resp, err := goyhfin.GetTickerData(symbol, yahooRange(from), interval, false)
+	if err != nil {
+		return nil, err
+	}
+	return filterBars(toBars(resp), from, to), nil
+}
+
+func (p *YahooProvider) LatestQuote(symbol string) (*Bar, error) {
+	resp, err := goyhfin.GetTickerData(symbol, goyhfin.OneDay, goyhfin.FiveMinutes, false)
+	if err != nil {
+		return nil, err
+	}
+
+	bars := toBars(resp)
+	// The last bar of a live session can be empty
+	for i := len(bars) - 1; i >= 0; i-- {
+		if bars[i].Close != 0 {
+			return &bars[i], nil
+		}
+	}
+	return nil, NoDataErr
+}
+
+func (p *YahooProvider) LookupSymbol(symbol string) (*SymbolInfo, error) {
+	resp, err := goyhfin.GetTickerData(symbol, goyhfin.FiveDay, goyhfin.OneDay, false)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Symbol == "" {
+		return nil, SymbolNotFoundErr
+	}
+
+	return &SymbolInfo{
+		Symbol:     resp.Symbol,
+		Currency:   resp.Currency,
+		Exchange:   resp.ExchangeName,
+		AssetClass: strings.ToLower(resp.InstrumentType),
+	}, nil
+}
+
+func toBars(resp goyhfin.ChartQueryResponse) []Bar {
+	bars := make([]Bar, 0, len(resp.Quotes))
+	for _, quote := range resp.Quotes {
+		bars = append(bars, Bar{
+			Symbol:   resp.Symbol,
+			OpensAt:  quote.OpensAt,
+			ClosesAt: quote.ClosesAt,
+			Open:     quote.Open,
+			High:     quote.High,
+			Low:      quote.Low,
+			Close:    quote.Close,
+			Volume:   quote.Volume,
+		})
+	}
+	return bars
+}
+
+// yahooRange picks the smallest range the chart api accepts that reaches back to from
+func yahooRange(from time.Time) string {
+	age := time.Since(from)
+	switch {
+	case age <= 24*time.Hour:
+		return goyhfin.OneDay
+	case age <= 5*24*time.Hour:
+		return goyhfin.FiveDay
+	case age <= 31*24*time.Hour:
+		return goyhfin.OneMonth
+	case age <= 92*24*time.Hour:
+		return goyhfin.ThreeMonth
+	case age <= 183*24*time.Hour:
+		return goyhfin.SixMonth
+	case age <= 366*24*time.Hour:
+		return goyhfin.OneYear
+	case age <= 2*366*24*time.Hour:
+		return goyhfin.TwoYear
+	case age <= 5*366*24*time.Hour:
+		return goyhfin.FiveYear
+	case age <= 10*366*24*time.Hour:
+		return goyhfin.TenYear
+	default:
+		return goyhfin.YahooMax
+	}
+}
@@ -0,0 +1,23 @@
+package quoteretention
+
+import "time"
+
+// Policy is how long the bars of an interval are kept and what happens to them after
+type Policy struct {
+	Interval string
+	// Keep is how long bars stay at this interval, zero keeps them forever
+	Keep time.Duration
+	// DownsampleTo is the interval older bars are rolled up into, without one they are archived
+	DownsampleTo string
+}
+
+// Result is what a retention run did to the bars of an interval
+type Result struct {
+	Interval string    `json:"interval"`
+	Cutoff   time.Time `json:"cutoff"`
+	// Removed is the number of bars older than the cutoff, Created the rolled up bars
+	// they were downsampled into, Archived the bars moved to the archive
+	Removed  int64 `json:"removed"`
+	Created  int64 `json:"created"`
+	Archived int64 `json:"archived"`
+}
@@ -0,0 +1,79 @@
+package quoteretention
+
+import (
+	"context"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+// Downsample rolls the bars of interval older than before up into one bar per UTC day of
+// the target interval and deletes them. A day already having a bar of the target interval,
+// like a daily bar fetched from the provider, keeps it.
+func (r *Repository) Downsample(ctx context.Context, interval, target string, before time.Time) (removed int64, created int64, err error) {
+	query := `
+		WITH old AS (
+			DELETE FROM asset_quote
+			WHERE interval = $1 AND quote_time < $3
+			RETURNING asset_id, open, high, low, quote, volume, quote_time
+		), days AS (
+			SELECT
+				asset_id,
+				date_trunc('day', quote_time AT TIME ZONE 'UTC') AS day,
+				(array_agg(COALESCE(open, quote) ORDER BY quote_time ASC))[1] AS open,
+				MAX(COALESCE(high, quote)) AS high,
+				MIN(COALESCE(low, quote)) AS low,
+				(array_agg(quote ORDER BY quote_time DESC))[1] AS quote,
+				SUM(volume) AS volume,
+				MAX(quote_time) AS quote_time,
+				COUNT(*) AS bars
+			FROM old
+			GROUP BY asset_id, day
+		), inserted AS (
+			INSERT INTO asset_quote (asset_id, interval, open, high, low, quote, volume, quote_time)
+			SELECT d.asset_id, $2, d.open, d.high, d.low, d.quote, d.volume, d.quote_time
+			FROM days d
+			WHERE NOT EXISTS (
+				SELECT 1 FROM asset_quote q
+				WHERE q.asset_id = d.asset_id AND q.interval = $2
+				AND q.quote_time >= d.day AT TIME ZONE 'UTC'
+				AND q.quote_time < (d.day + INTERVAL '1 day') AT TIME ZONE 'UTC'
+			)
+			ON CONFLICT (asset_id, interval, quote_time) DO NOTHING
+			RETURNING 1
+		)
+		SELECT
+			COALESCE((SELECT SUM(bars) FROM days), 0) AS removed,
+			(SELECT COUNT(*) FROM inserted) AS created
+	`
+	err = r.db.QueryRowxContext(ctx, query, interval, target, before).Scan(&removed, &created)
+	return removed, created, err
+}
+
+// Archive moves the bars of interval older than before to asset_quote_archive
+func (r *Repository) Archive(ctx context.Context, interval string, before time.Time) (int64, error) {
+	query := `
+		WITH moved AS (
+			DELETE FROM asset_quote
+			WHERE interval = $1 AND quote_time < $2
+			RETURNING id, asset_id, interval, open, high, low, quote, volume, quote_time, created_at
+		), archived AS (
+			INSERT INTO asset_quote_archive (id, asset_id, interval, open, high, low, quote, volume, quote_time, created_at)
+			SELECT id, asset_id, interval, open, high, low, quote, volume, quote_time, created_at FROM moved
+			ON CONFLICT (asset_id, interval, quote_time) DO NOTHING
+			RETURNING 1
+		)
+		SELECT COUNT(*) FROM archived
+	`
+	var archived int64
+	err := r.db.GetContext(ctx, &archived, query, interval, before)
+	return archived, err
+}
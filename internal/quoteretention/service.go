@@ -0,0 +1,70 @@
+package quoteretention
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Service struct {
+	repo     *Repository
+	policies []Policy
+}
+
+func NewService(repo *Repository, policies []Policy) *Service {
+	return &Service{repo: repo, policies: policies}
+}
+
+// Apply downsamples or archives the bars older than their policy allows. Cutoffs fall on
+// UTC midnight so a day is never split between the raw and the rolled up bars. Once ctx is done
+// the running policy is rolled back and the remaining ones are skipped.
+func (s *Service) Apply(ctx context.Context, now time.Time) ([]Result, error) {
+	today := now.UTC().Truncate(24 * time.Hour)
+
+	var results []Result
+	for _, policy := range s.policies {
+		if policy.Keep <= 0 {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return results, err
+		}
+
+		result := Result{Interval: policy.Interval, Cutoff: today.Add(-policy.Keep).Truncate(24 * time.Hour)}
+		var err error
+		if policy.DownsampleTo != "" {
+			result.Removed, result.Created, err = s.repo.Downsample(ctx, policy.Interval, policy.DownsampleTo, result.Cutoff)
+		} else {
+			result.Archived, err = s.repo.Archive(ctx, policy.Interval, result.Cutoff)
+			result.Removed = result.Archived
+		}
+		if err != nil {
+			return results, fmt.Errorf("retention of %s quotes: %w", policy.Interval, err)
+		}
+
+		log.Infof("Quote retention of %s before %s removed %d bars, created %d and archived %d",
+			result.Interval, result.Cutoff.Format("2006-01-02"), result.Removed, result.Created, result.Archived)
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Policies builds the policies from the days to keep per interval and the downsample targets.
+// Shorter lived intervals come first, so their rolled up bars are archived in the same run.
+func Policies(keepDays map[string]int, downsampleTo map[string]string) []Policy {
+	policies := make([]Policy, 0, len(keepDays))
+	for interval, days := range keepDays {
+		policies = append(policies, Policy{
+			Interval:     interval,
+			Keep:         time.Duration(days) * 24 * time.Hour,
+			DownsampleTo: downsampleTo[interval],
+		})
+	}
+	sort.Slice(policies, func(i, j int) bool {
+		return policies[i].Keep < policies[j].Keep
+	})
+	return policies
+}
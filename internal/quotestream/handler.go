@@ -0,0 +1,188 @@
+package quotestream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/gofiber/websocket/v2"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
+	"github.com/valyala/fasthttp"
+)
+
+// heartbeatInterval keeps idle connections from being closed by proxies
+const heartbeatInterval = 15 * time.Second
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+// Stream sends the subscribed ticks as Server-Sent Events,
+// e.g. /api/stream?symbols=AAPL,MSFT&portfolios=1,2
+func (h *Handler) Stream(c *fiber.Ctx) error {
+	symbols, portfolioIds, err := subscription(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+	currency, err := h.currency(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch base currency"})
+	}
+
+	c.Set("Content-Type", "text/event-stream")
+	c.Set("Cache-Control", "no-cache")
+	c.Set("Connection", "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	subscriber := h.service.Connect(currency)
+	c.Context().SetBodyStreamWriter(fasthttp.StreamWriter(func(w *bufio.Writer) {
+		defer h.service.Disconnect(subscriber)
+		h.service.Subscribe(subscriber, symbols, portfolioIds)
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-subscriber.Events():
+				if !ok {
+					return
+				}
+				data, err := json.Marshal(event.Data)
+				if err != nil {
+					log.Errorf("Error encoding stream event: %v", err)
+					continue
+				}
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			}
+			// a failed flush means the client is gone
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	}))
+
+	return nil
+}
+
+// UpgradeWebSocket only lets websocket handshakes through to WebSocket
+func (h *Handler) UpgradeWebSocket(c *fiber.Ctx) error {
+	if !websocket.IsWebSocketUpgrade(c) {
+		return fiber.ErrUpgradeRequired
+	}
+	currency, err := h.currency(c)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch base currency"})
+	}
+	c.Locals("currency", currency)
+	return c.Next()
+}
+
+// WebSocket streams the subscribed ticks as json events, clients change their subscriptions
+// by sending SubscriptionMessages
+func (h *Handler) WebSocket() fiber.Handler {
+	return websocket.New(func(conn *websocket.Conn) {
+		subscriber := h.service.Connect(conn.Locals("currency").(string))
+		defer h.service.Disconnect(subscriber)
+
+		symbols := parseSymbols(conn.Query("symbols"))
+		portfolioIds, err := parsePortfolioIds(conn.Query("portfolios"))
+		if err != nil {
+			conn.WriteJSON(Event{Type: EventError, Data: err.Error()})
+			return
+		}
+		h.service.Subscribe(subscriber, symbols, portfolioIds)
+
+		go h.readSubscriptions(conn, subscriber)
+
+		heartbeat := time.NewTicker(heartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case event, ok := <-subscriber.Events():
+				if !ok {
+					return
+				}
+				if err := conn.WriteJSON(event); err != nil {
+					return
+				}
+			case <-heartbeat.C:
+				if err := conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+					return
+				}
+			}
+		}
+	})
+}
+
+// readSubscriptions applies the client's messages until the connection closes
+func (h *Handler) readSubscriptions(conn *websocket.Conn, subscriber *Subscriber) {
+	defer h.service.Disconnect(subscriber)
+
+	for {
+		var message SubscriptionMessage
+		if err := conn.ReadJSON(&message); err != nil {
+			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseNormalClosure) {
+				log.Warnf("Websocket stream closed: %v", err)
+			}
+			return
+		}
+
+		symbols := parseSymbols(strings.Join(message.Symbols, ","))
+		switch message.Action {
+		case ActionSubscribe:
+			h.service.Subscribe(subscriber, symbols, message.Portfolios)
+		case ActionUnsubscribe:
+			h.service.Unsubscribe(subscriber, symbols, message.Portfolios)
+		default:
+			subscriber.send(Event{Type: EventError, Data: "action must be subscribe or unsubscribe"})
+		}
+	}
+}
+
+// currency returns the currency requested in the query, falling back to the user's base currency
+func (h *Handler) currency(c *fiber.Ctx) (string, error) {
+	if currency := c.Query("currency"); currency != "" {
+		return fx.Normalize(currency), nil
+	}
+	return h.service.GetUserBaseCurrency(c.Locals("userId").(int64))
+}
+
+func subscription(c *fiber.Ctx) ([]string, []int64, error) {
+	symbols := parseSymbols(c.Query("symbols"))
+	portfolioIds, err := parsePortfolioIds(c.Query("portfolios"))
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(symbols) == 0 && len(portfolioIds) == 0 {
+		return nil, nil, fmt.Errorf("symbols or portfolios is required")
+	}
+	return symbols, portfolioIds, nil
+}
+
+func parsePortfolioIds(value string) ([]int64, error) {
+	var portfolioIds []int64
+	for _, id := range strings.Split(value, ",") {
+		if id = strings.TrimSpace(id); id == "" {
+			continue
+		}
+		portfolioId, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid portfolio id %q", id)
+		}
+		portfolioIds = append(portfolioIds, portfolioId)
+	}
+	return portfolioIds, nil
+}
@@ -0,0 +1,183 @@
+package quotestream
+
+import (
+	"strings"
+	"sync"
+)
+
+// subscriberBuffer is how many events a slow client may lag behind before ticks are dropped
+const subscriberBuffer = 64
+
+// Subscriber is a connected client and the symbols and portfolios it follows
+type Subscriber struct {
+	currency string
+
+	// sendMu guards events against sends after close
+	sendMu sync.Mutex
+	events chan Event
+	closed bool
+
+	mu         sync.RWMutex
+	symbols    map[string]bool
+	portfolios map[int64]bool
+}
+
+func (s *Subscriber) Events() <-chan Event {
+	return s.events
+}
+
+func (s *Subscriber) subscribe(symbols []string, portfolioIds []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, symbol := range symbols {
+		s.symbols[strings.ToUpper(symbol)] = true
+	}
+	for _, portfolioId := range portfolioIds {
+		s.portfolios[portfolioId] = true
+	}
+}
+
+func (s *Subscriber) unsubscribe(symbols []string, portfolioIds []int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, symbol := range symbols {
+		delete(s.symbols, strings.ToUpper(symbol))
+	}
+	for _, portfolioId := range portfolioIds {
+		delete(s.portfolios, portfolioId)
+	}
+}
+
+func (s *Subscriber) followsSymbol(symbol string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.symbols[symbol]
+}
+
+func (s *Subscriber) followsPortfolio(portfolioId int64) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.portfolios[portfolioId]
+}
+
+// send never blocks the publisher, a client too slow to keep up misses ticks
+func (s *Subscriber) send(event Event) {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if s.closed {
+		return
+	}
+	select {
+	case s.events <- event:
+	default:
+	}
+}
+
+func (s *Subscriber) close() {
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+	if !s.closed {
+		s.closed = true
+		close(s.events)
+	}
+}
+
+// Hub fans the price and portfolio ticks out to the subscribers
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[*Subscriber]struct{}
+	latest      map[string]PriceTick
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[*Subscriber]struct{}),
+		latest:      make(map[string]PriceTick),
+	}
+}
+
+func (h *Hub) Register(currency string) *Subscriber {
+	subscriber := &Subscriber{
+		currency:   currency,
+		events:     make(chan Event, subscriberBuffer),
+		symbols:    make(map[string]bool),
+		portfolios: make(map[int64]bool),
+	}
+	h.mu.Lock()
+	h.subscribers[subscriber] = struct{}{}
+	h.mu.Unlock()
+	return subscriber
+}
+
+// Unregister removes the subscriber and closes its events, it is safe to call more than once
+func (h *Hub) Unregister(subscriber *Subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.subscribers, subscriber)
+	subscriber.close()
+}
+
+func (h *Hub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for subscriber := range h.subscribers {
+		delete(h.subscribers, subscriber)
+		subscriber.close()
+	}
+}
+
+// PublishPrice sends the tick to the subscribers of its symbol. Ticks older than the last one
+// published for the symbol, e.g. from a backfill, are dropped and false is returned.
+func (h *Hub) PublishPrice(tick PriceTick) bool {
+	tick.Symbol = strings.ToUpper(tick.Symbol)
+
+	h.mu.Lock()
+	if latest, ok := h.latest[tick.Symbol]; ok && !tick.QuoteTime.After(latest.QuoteTime) {
+		h.mu.Unlock()
+		return false
+	}
+	h.latest[tick.Symbol] = tick
+	h.mu.Unlock()
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for subscriber := range h.subscribers {
+		if subscriber.followsSymbol(tick.Symbol) {
+			subscriber.send(Event{Type: EventPrice, Data: tick})
+		}
+	}
+	return true
+}
+
+func (h *Hub) PublishPortfolio(tick PortfolioTick) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for subscriber := range h.subscribers {
+		if subscriber.currency == tick.Currency && subscriber.followsPortfolio(tick.PortfolioId) {
+			subscriber.send(Event{Type: EventPortfolio, Data: tick})
+		}
+	}
+}
+
+// LatestPrice returns the last tick published for the symbol
+func (h *Hub) LatestPrice(symbol string) (PriceTick, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	tick, ok := h.latest[strings.ToUpper(symbol)]
+	return tick, ok
+}
+
+// followedPortfolios returns the portfolios and currencies someone is subscribed to
+func (h *Hub) followedPortfolios() map[portfolioKey]bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	followed := make(map[portfolioKey]bool)
+	for subscriber := range h.subscribers {
+		subscriber.mu.RLock()
+		for portfolioId := range subscriber.portfolios {
+			followed[portfolioKey{portfolioId: portfolioId, currency: subscriber.currency}] = true
+		}
+		subscriber.mu.RUnlock()
+	}
+	return followed
+}
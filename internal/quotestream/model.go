@@ -0,0 +1,50 @@
+package quotestream
+
+import (
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
+)
+
+const (
+	EventPrice     = "price"
+	EventPortfolio = "portfolio"
+	EventError     = "error"
+
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+type PriceTick struct {
+	AssetId   int64     `json:"asset_id"`
+	Symbol    string    `json:"symbol"`
+	Price     float64   `json:"price"`
+	Interval  string    `json:"interval"`
+	QuoteTime time.Time `json:"quote_time"`
+}
+
+type PortfolioTick struct {
+	PortfolioId  int64                     `json:"portfolio_id"`
+	Currency     string                    `json:"currency"`
+	Value        float64                   `json:"value"`
+	UnrealizedPL float64                   `json:"unrealized_pl"`
+	Allocations  []portfolio.AllocationDTO `json:"allocations"`
+	UpdatedAt    time.Time                 `json:"updated_at"`
+}
+
+// SubscriptionMessage is sent by websocket clients to change their subscriptions
+type SubscriptionMessage struct {
+	Action     string   `json:"action"`
+	Symbols    []string `json:"symbols"`
+	Portfolios []int64  `json:"portfolios"`
+}
+
+type portfolioKey struct {
+	portfolioId int64
+	currency    string
+}
@@ -0,0 +1,184 @@
+package quotestream
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/portfolio"
+)
+
+// portfolioRecomputeInterval batches the ticks of a portfolio's assets into one recomputation
+const portfolioRecomputeInterval = 2 * time.Second
+
+type Service struct {
+	hub              *Hub
+	portfolioService *portfolio.Service
+
+	mu              sync.Mutex
+	portfolioAssets map[int64]map[int64]bool
+	dirty           map[int64]bool
+	stop            chan struct{}
+}
+
+func NewService(hub *Hub, portfolioService *portfolio.Service) *Service {
+	return &Service{
+		hub:              hub,
+		portfolioService: portfolioService,
+		portfolioAssets:  make(map[int64]map[int64]bool),
+		dirty:            make(map[int64]bool),
+		stop:             make(chan struct{}),
+	}
+}
+
+// Start recomputes the portfolios whose assets ticked in the background
+func (s *Service) Start() {
+	go func() {
+		ticker := time.NewTicker(portfolioRecomputeInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-s.stop:
+				return
+			case <-ticker.C:
+				s.publishDirtyPortfolios()
+			}
+		}
+	}()
+}
+
+// Stop ends the recomputation and disconnects every client, so open streams don't hold up the server shutdown
+func (s *Service) Stop() {
+	close(s.stop)
+	s.hub.closeAll()
+}
+
+// OnQuote is called for every saved quote, it has to return quickly as it runs on the ingestion path
+func (s *Service) OnQuote(quote asset.AssetQuoteChanData) {
+	published := s.hub.PublishPrice(PriceTick{
+		AssetId:   quote.AssetId,
+		Symbol:    quote.Symbol,
+		Price:     quote.Quote,
+		Interval:  quote.Interval,
+		QuoteTime: quote.QuoteTime,
+	})
+	if !published {
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for portfolioId, assets := range s.portfolioAssets {
+		if assets[quote.AssetId] {
+			s.dirty[portfolioId] = true
+		}
+	}
+}
+
+func (s *Service) Connect(currency string) *Subscriber {
+	return s.hub.Register(currency)
+}
+
+func (s *Service) Disconnect(subscriber *Subscriber) {
+	s.hub.Unregister(subscriber)
+}
+
+// Subscribe follows the symbols and portfolios and sends their current state right away
+func (s *Service) Subscribe(subscriber *Subscriber, symbols []string, portfolioIds []int64) {
+	subscriber.subscribe(symbols, portfolioIds)
+
+	for _, symbol := range symbols {
+		if tick, ok := s.hub.LatestPrice(symbol); ok {
+			subscriber.send(Event{Type: EventPrice, Data: tick})
+		}
+	}
+
+	for _, portfolioId := range portfolioIds {
+		tick, err := s.portfolioTick(portfolioId, subscriber.currency)
+		if err != nil {
+			log.Errorf("Error streaming portfolio %d: %v", portfolioId, err)
+			subscriber.send(Event{Type: EventError, Data: "Failed to load portfolio"})
+			continue
+		}
+		subscriber.send(Event{Type: EventPortfolio, Data: tick})
+	}
+}
+
+func (s *Service) Unsubscribe(subscriber *Subscriber, symbols []string, portfolioIds []int64) {
+	subscriber.unsubscribe(symbols, portfolioIds)
+}
+
+func (s *Service) GetUserBaseCurrency(userId int64) (string, error) {
+	return s.portfolioService.GetUserBaseCurrency(userId)
+}
+
+func (s *Service) publishDirtyPortfolios() {
+	s.mu.Lock()
+	dirty := s.dirty
+	s.dirty = make(map[int64]bool)
+	s.mu.Unlock()
+
+	followed := s.hub.followedPortfolios()
+	followedIds := make(map[int64]bool)
+	for key := range followed {
+		followedIds[key.portfolioId] = true
+		if !dirty[key.portfolioId] {
+			continue
+		}
+		tick, err := s.portfolioTick(key.portfolioId, key.currency)
+		if err != nil {
+			log.Errorf("Error recomputing portfolio %d: %v", key.portfolioId, err)
+			continue
+		}
+		s.hub.PublishPortfolio(*tick)
+	}
+
+	// forget the assets of portfolios nobody follows anymore
+	s.mu.Lock()
+	for portfolioId := range s.portfolioAssets {
+		if !followedIds[portfolioId] {
+			delete(s.portfolioAssets, portfolioId)
+		}
+	}
+	s.mu.Unlock()
+}
+
+// portfolioTick computes the portfolio's value and remembers its assets to catch their ticks
+func (s *Service) portfolioTick(portfolioId int64, currency string) (*PortfolioTick, error) {
+	dto, err := s.portfolioService.GetPortfolioWithAllocations(portfolioId, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	tick := &PortfolioTick{
+		PortfolioId: portfolioId,
+		Currency:    dto.Currency,
+		Allocations: dto.Allocations,
+		UpdatedAt:   time.Now(),
+	}
+	assets := make(map[int64]bool, len(dto.Allocations))
+	for _, allocation := range dto.Allocations {
+		tick.Value += allocation.Amount
+		tick.UnrealizedPL += allocation.UnrealizedPL
+		assets[allocation.Asset.Id] = true
+	}
+
+	s.mu.Lock()
+	s.portfolioAssets[portfolioId] = assets
+	s.mu.Unlock()
+
+	return tick, nil
+}
+
+// parseSymbols splits a comma separated symbol list
+func parseSymbols(value string) []string {
+	var symbols []string
+	for _, symbol := range strings.Split(value, ",") {
+		if symbol = strings.TrimSpace(symbol); symbol != "" {
+			symbols = append(symbols, strings.ToUpper(symbol))
+		}
+	}
+	return symbols
+}
@@ -0,0 +1,209 @@
+package tradingcalendar
+
+import (
+	"fmt"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxClosedDays bounds the walk to a neighbouring trading day
+const maxClosedDays = 30
+
+// Calendar knows the sessions, holidays and half days of an exchange
+type Calendar struct {
+	Exchange string
+	location *time.Location
+	open     int
+	close    int
+	weekend  map[time.Weekday]bool
+	holidays map[string]string
+	halfDays map[string]int
+}
+
+// weekdayCalendar trades every weekday around the clock, used for exchanges without a data file
+func weekdayCalendar() *Calendar {
+	return &Calendar{
+		Exchange: DefaultExchange,
+		location: time.UTC,
+		open:     0,
+		close:    24 * 60,
+		weekend:  map[time.Weekday]bool{time.Saturday: true, time.Sunday: true},
+		holidays: map[string]string{},
+		halfDays: map[string]int{},
+	}
+}
+
+func newCalendar(file calendarFile) (*Calendar, error) {
+	if file.Exchange == "" {
+		return nil, fmt.Errorf("%w: exchange is required", InvalidCalendarErr)
+	}
+	location, err := time.LoadLocation(file.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s timezone: %v", InvalidCalendarErr, file.Exchange, err)
+	}
+	open, err := parseClock(file.Open)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s open: %v", InvalidCalendarErr, file.Exchange, err)
+	}
+	close, err := parseClock(file.Close)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s close: %v", InvalidCalendarErr, file.Exchange, err)
+	}
+	if close <= open {
+		return nil, fmt.Errorf("%w: %s closes before it opens", InvalidCalendarErr, file.Exchange)
+	}
+
+	calendar := &Calendar{
+		Exchange: file.Exchange,
+		location: location,
+		open:     open,
+		close:    close,
+		weekend:  make(map[time.Weekday]bool),
+		holidays: make(map[string]string),
+		halfDays: make(map[string]int),
+	}
+
+	for _, name := range file.Weekend {
+		weekday, err := parseWeekday(name)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s weekend: %v", InvalidCalendarErr, file.Exchange, err)
+		}
+		calendar.weekend[weekday] = true
+	}
+	if len(calendar.weekend) == 7 {
+		return nil, fmt.Errorf("%w: %s never trades", InvalidCalendarErr, file.Exchange)
+	}
+
+	for _, holiday := range file.Holidays {
+		if _, err := time.Parse(dateLayout, holiday.Date); err != nil {
+			return nil, fmt.Errorf("%w: %s holiday: %v", InvalidCalendarErr, file.Exchange, err)
+		}
+		calendar.holidays[holiday.Date] = holiday.Name
+	}
+	for _, halfDay := range file.HalfDays {
+		if _, err := time.Parse(dateLayout, halfDay.Date); err != nil {
+			return nil, fmt.Errorf("%w: %s half day: %v", InvalidCalendarErr, file.Exchange, err)
+		}
+		halfDayClose, err := parseClock(halfDay.Close)
+		if err != nil || halfDayClose <= open {
+			return nil, fmt.Errorf("%w: %s half day close of %s", InvalidCalendarErr, file.Exchange, halfDay.Date)
+		}
+		calendar.halfDays[halfDay.Date] = halfDayClose
+	}
+
+	return calendar, nil
+}
+
+func (c *Calendar) Location() *time.Location {
+	return c.location
+}
+
+// IsTradingDay reports whether the exchange has a session on the local date of t
+func (c *Calendar) IsTradingDay(t time.Time) bool {
+	local := t.In(c.location)
+	if c.weekend[local.Weekday()] {
+		return false
+	}
+	_, holiday := c.holidays[local.Format(dateLayout)]
+	return !holiday
+}
+
+// Holiday returns the name of the holiday on the local date of t
+func (c *Calendar) Holiday(t time.Time) (string, bool) {
+	name, ok := c.holidays[t.In(c.location).Format(dateLayout)]
+	return name, ok
+}
+
+// Session returns the trading hours on the local date of t, false when the exchange is closed that day
+func (c *Calendar) Session(t time.Time) (Session, bool) {
+	if !c.IsTradingDay(t) {
+		return Session{}, false
+	}
+	local := t.In(c.location)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, c.location)
+
+	session := Session{
+		Open:  midnight.Add(time.Duration(c.open) * time.Minute),
+		Close: midnight.Add(time.Duration(c.close) * time.Minute),
+	}
+	if halfDayClose, ok := c.halfDays[local.Format(dateLayout)]; ok {
+		session.Close = midnight.Add(time.Duration(halfDayClose) * time.Minute)
+		session.HalfDay = true
+	}
+	return session, true
+}
+
+// IsOpen reports whether t falls into a session
+func (c *Calendar) IsOpen(t time.Time) bool {
+	session, ok := c.Session(t)
+	return ok && !t.Before(session.Open) && t.Before(session.Close)
+}
+
+// PreviousSession returns the last session of a trading day before the local date of t
+func (c *Calendar) PreviousSession(t time.Time) (Session, bool) {
+	day := t.In(c.location)
+	for i := 0; i < maxClosedDays; i++ {
+		day = day.AddDate(0, 0, -1)
+		if session, ok := c.Session(day); ok {
+			return session, true
+		}
+	}
+	return Session{}, false
+}
+
+// LastSessionClose returns the close of the latest session that ended at or before t
+func (c *Calendar) LastSessionClose(t time.Time) (time.Time, bool) {
+	if session, ok := c.Session(t); ok && !t.Before(session.Close) {
+		return session.Close, true
+	}
+	session, ok := c.PreviousSession(t)
+	return session.Close, ok
+}
+
+// HasSessionBetween reports whether a session overlaps the period from to
+func (c *Calendar) HasSessionBetween(from, to time.Time) bool {
+	for day := from.In(c.location); !day.After(to.AddDate(0, 0, 1)); day = day.AddDate(0, 0, 1) {
+		session, ok := c.Session(day)
+		if ok && session.Open.Before(to) && session.Close.After(from) {
+			return true
+		}
+	}
+	return false
+}
+
+// TradingDays returns the trading dates between from and to as UTC midnights
+func (c *Calendar) TradingDays(from, to time.Time) []time.Time {
+	var days []time.Time
+	start := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+	for day := start; !day.After(to); day = day.AddDate(0, 0, 1) {
+		// check the exchange's local date, not the UTC midnight which may fall on the previous day
+		local := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, c.location)
+		if c.IsTradingDay(local) {
+			days = append(days, day)
+		}
+	}
+	return days
+}
+
+// parseClock parses HH:MM into minutes after midnight, 24:00 is the end of the day
+func parseClock(value string) (int, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	minutes := hour*60 + minute
+	if hour < 0 || minute < 0 || minute > 59 || minutes > 24*60 {
+		return 0, fmt.Errorf("invalid time %q", value)
+	}
+	return minutes, nil
+}
+
+func parseWeekday(name string) (time.Weekday, error) {
+	for day := time.Sunday; day <= time.Saturday; day++ {
+		if day.String() == name {
+			return day, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid weekday %q", name)
+}
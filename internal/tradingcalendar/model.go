@@ -0,0 +1,39 @@
+package tradingcalendar
+
+import (
+	"errors"
+	"time"
+)
+
+const DefaultExchange = "DEFAULT"
+
+var InvalidCalendarErr error = errors.New("invalid trading calendar")
+
+// calendarFile is the json layout of a calendar data file
+type calendarFile struct {
+	Exchange string         `json:"exchange"`
+	Aliases  []string       `json:"aliases"`
+	Timezone string         `json:"timezone"`
+	Open     string         `json:"open"`
+	Close    string         `json:"close"`
+	Weekend  []string       `json:"weekend"`
+	Holidays []holidayEntry `json:"holidays"`
+	HalfDays []halfDayEntry `json:"half_days"`
+}
+
+type holidayEntry struct {
+	Date string `json:"date"`
+	Name string `json:"name"`
+}
+
+type halfDayEntry struct {
+	Date  string `json:"date"`
+	Close string `json:"close"`
+}
+
+// Session is the trading hours of an exchange on a day
+type Session struct {
+	Open    time.Time `json:"open"`
+	Close   time.Time `json:"close"`
+	HalfDay bool      `json:"half_day"`
+}
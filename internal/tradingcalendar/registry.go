@@ -0,0 +1,85 @@
+package tradingcalendar
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+//go:embed data/*.json
+var defaultCalendars embed.FS
+
+// Registry maps exchange codes to their calendars
+type Registry struct {
+	calendars map[string]*Calendar
+	fallback  *Calendar
+}
+
+// Load reads the bundled calendars and then the json files in dir, which replace bundled
+// calendars of the same exchange. An empty dir only loads the bundled ones.
+func Load(dir string) (*Registry, error) {
+	registry := &Registry{
+		calendars: make(map[string]*Calendar),
+		fallback:  weekdayCalendar(),
+	}
+
+	bundled, err := fs.Sub(defaultCalendars, "data")
+	if err != nil {
+		return nil, err
+	}
+	if err := registry.loadDir(bundled); err != nil {
+		return nil, err
+	}
+
+	if dir == "" {
+		return registry, nil
+	}
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		log.Warnf("Trading calendar directory %s does not exist, using bundled calendars", dir)
+		return registry, nil
+	}
+	if err := registry.loadDir(os.DirFS(dir)); err != nil {
+		return nil, err
+	}
+	return registry, nil
+}
+
+func (r *Registry) loadDir(dir fs.FS) error {
+	files, err := fs.Glob(dir, "*.json")
+	if err != nil {
+		return err
+	}
+	for _, name := range files {
+		data, err := fs.ReadFile(dir, name)
+		if err != nil {
+			return err
+		}
+		var file calendarFile
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("%w: %s: %v", InvalidCalendarErr, filepath.Base(name), err)
+		}
+		calendar, err := newCalendar(file)
+		if err != nil {
+			return err
+		}
+		r.calendars[strings.ToUpper(file.Exchange)] = calendar
+		for _, alias := range file.Aliases {
+			r.calendars[strings.ToUpper(alias)] = calendar
+		}
+	}
+	return nil
+}
+
+// For returns the calendar of the exchange, or a weekday calendar when the exchange is unknown
+func (r *Registry) For(exchange string) *Calendar {
+	if calendar, ok := r.calendars[strings.ToUpper(strings.TrimSpace(exchange))]; ok {
+		return calendar
+	}
+	return r.fallback
+}
@@ -14,11 +14,13 @@ type Transaction struct {
 	Side         OrderSide `db:"side" json:"side"`
 	Quantity     float64   `db:"quantity" json:"quantity"`
 	Price        float64   `db:"price" json:"price"`
+	Currency     string    `db:"currency" json:"currency"`
 	AllocationId int64     `db:"allocation_id" json:"allocation_id"`
 	CreatedAt    time.Time `db:"created_at" json:"created_at"`
 }
 
 type AmountAndPLResult struct {
-    CurrentAmount float64
-    UnrealizedPL  float64
+	CurrentAmount  float64
+	UnrealizedPL   float64
+	CurrencyEffect float64
 }
@@ -36,8 +36,8 @@ func (r *Repository) Get(allocationIds ...int64) ([]Transaction, error) {
 
 func (r *Repository) Save(t *Transaction) (*Transaction, error) {
 	query := `
-			INSERT INTO transaction (allocation_id, side, quantity, price)
-			VALUES (:allocation_id, :side, :quantity, :price)
+			INSERT INTO transaction (allocation_id, side, quantity, price, currency)
+			VALUES (:allocation_id, :side, :quantity, :price, :currency)
 			RETURNING id, created_at
 		`
 	rows, err := r.db.NamedQuery(query, t)
@@ -1,16 +1,23 @@
 package transaction
 
-import "github.com/karataydev/portfoliomanbackend/internal/asset"
+import (
+	"database/sql"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
+)
 
 type Service struct {
 	repo         *Repository
 	assetService *asset.Service
+	fxService    *fx.Service
 }
 
-func NewService(repo *Repository, assetService *asset.Service) *Service {
+func NewService(repo *Repository, assetService *asset.Service, fxService *fx.Service) *Service {
 	return &Service{
 		repo:         repo,
 		assetService: assetService,
+		fxService:    fxService,
 	}
 }
 
@@ -19,10 +26,14 @@ func (s *Service) Get(allocationIds ...int64) ([]Transaction, error) {
 }
 
 func (s *Service) Save(t *Transaction) (*Transaction, error) {
+	t.Currency = fx.Normalize(t.Currency)
 	return s.repo.Save(t)
 }
 
-func (s *Service) CalculateAmountsAndPL(allocationIds, assetIds []int64) (map[int64]AmountAndPLResult, error) {
+// CalculateAmountsAndPL values every allocation in the given currency. Costs are converted
+// at the FX rate of each transaction's time and current amounts at the latest quote's time,
+// the part of the unrealized PL caused by FX moves is reported as the currency effect.
+func (s *Service) CalculateAmountsAndPL(allocationIds, assetIds []int64, currency string) (map[int64]AmountAndPLResult, error) {
 	transactions, err := s.repo.Get(allocationIds...)
 	if err != nil {
 		return nil, err
@@ -34,35 +45,95 @@ func (s *Service) CalculateAmountsAndPL(allocationIds, assetIds []int64) (map[in
 		allocationToAsset[allocID] = assetIds[i]
 	}
 
+	assets, err := s.assetService.GetAssetsByIds(assetIds)
+	if err != nil {
+		return nil, err
+	}
+	assetCurrencies := make(map[int64]string, len(assets))
+	for _, a := range assets {
+		assetCurrencies[a.Id] = fx.Normalize(a.Currency.String)
+	}
+
 	// Group transactions by allocation ID
 	transactionsByAllocation := make(map[int64][]Transaction)
+	var allocationOrder []int64
 	for _, t := range transactions {
+		if _, ok := transactionsByAllocation[t.AllocationId]; !ok {
+			allocationOrder = append(allocationOrder, t.AllocationId)
+		}
 		transactionsByAllocation[t.AllocationId] = append(transactionsByAllocation[t.AllocationId], t)
 	}
 
-	resultMap := make(map[int64]AmountAndPLResult)
-	for allocID, txs := range transactionsByAllocation {
-		latestQuote, err := s.assetService.GetLatestQuote(allocationToAsset[allocID])
-		if err != nil {
-			return nil, err
+	latestQuotes, err := s.assetService.GetLatestQuotes(assetIds)
+	if err != nil {
+		return nil, err
+	}
+
+	// every rate is loaded at once, per transaction the local and the target rate
+	// followed by the current rate of the allocation
+	var rateRequests []fx.RateRequest
+	for _, allocID := range allocationOrder {
+		txs := transactionsByAllocation[allocID]
+		assetId := allocationToAsset[allocID]
+		assetCurrency := assetCurrencies[assetId]
+		latestQuote, ok := latestQuotes[assetId]
+		if !ok {
+			return nil, sql.ErrNoRows
+		}
+
+		for _, t := range txs {
+			rateRequests = append(rateRequests,
+				fx.RateRequest{From: t.Currency, To: assetCurrency, Time: t.CreatedAt},
+				fx.RateRequest{From: t.Currency, To: currency, Time: t.CreatedAt},
+			)
 		}
+		rateRequests = append(rateRequests, fx.RateRequest{From: assetCurrency, To: currency, Time: latestQuote.QuoteTime})
+	}
+	rates, err := s.fxService.Rates(rateRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	resultMap := make(map[int64]AmountAndPLResult)
+	next := 0
+	for _, allocID := range allocationOrder {
+		txs := transactionsByAllocation[allocID]
+		latestQuote := latestQuotes[allocationToAsset[allocID]]
+
 		quantity := 0.0
+		localCost := 0.0
 		totalCost := 0.0
 		for _, t := range txs {
+			localRate, rate := rates[next], rates[next+1]
+			next += 2
+
 			if t.Side == Buy {
 				quantity += t.Quantity
-				totalCost += t.Quantity * t.Price
+				localCost += t.Quantity * t.Price * localRate
+				totalCost += t.Quantity * t.Price * rate
 			} else {
 				quantity -= t.Quantity
-				totalCost -= t.Quantity * t.Price
+				localCost -= t.Quantity * t.Price * localRate
+				totalCost -= t.Quantity * t.Price * rate
 			}
 		}
-		currentAmount := quantity * latestQuote.Quote
+
+		currentRate := rates[next]
+		next++
+		localAmount := quantity * latestQuote.Quote
+		currentAmount := localAmount * currentRate
 		unrealizedPL := currentAmount - totalCost
 
+		// The average rate the position was bought at
+		costRate := currentRate
+		if localCost != 0 {
+			costRate = totalCost / localCost
+		}
+
 		resultMap[allocID] = AmountAndPLResult{
-			CurrentAmount: currentAmount,
-			UnrealizedPL:  unrealizedPL,
+			CurrentAmount:  currentAmount,
+			UnrealizedPL:   unrealizedPL,
+			CurrencyEffect: localAmount * (currentRate - costRate),
 		}
 	}
 
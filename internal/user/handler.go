@@ -1,8 +1,11 @@
 package user
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
 )
 
 type Handler struct {
@@ -56,3 +59,34 @@ func (h *Handler) SignIn(c *fiber.Ctx) error {
 
 	return c.Status(fiber.StatusOK).JSON(response)
 }
+
+func (h *Handler) UpdateBaseCurrency(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	var req UpdateBaseCurrencyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+			"error": "Invalid request body",
+		})
+	}
+
+	user, err := h.service.UpdateBaseCurrency(userId, req.Currency)
+	if err != nil {
+		if errors.Is(err, fx.UnsupportedCurrencyErr) {
+			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		if err == UserNotFoundErr {
+			return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
+				"error": err.Error(),
+			})
+		}
+		log.Errorf("Failed to update base currency: %v", err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
+			"error": "Failed to update base currency",
+		})
+	}
+
+	return c.JSON(user)
+}
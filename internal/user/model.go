@@ -15,6 +15,7 @@ type User struct {
 	Email             string    `db:"email" json:"email"`
 	GoogleId          string    `db:"google_id" json:"google_id"`
 	ProfilePictureUrl string    `db:"profile_picture_url" json:"profile_picture_url"`
+	BaseCurrency      string    `db:"base_currency" json:"base_currency"`
 	CreatedAt         time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt         time.Time `db:"updated_at" json:"updated_at"`
 }
@@ -26,3 +27,7 @@ type SignInUpResponse struct {
 	ExpiresIn   int    `json:"expires_in"`
 	UserExisted bool   `json:"user_existed"`
 }
+
+type UpdateBaseCurrencyRequest struct {
+	Currency string `json:"currency"`
+}
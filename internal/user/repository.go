@@ -74,3 +74,24 @@ func (r *Repository) Save(user *User) (*User, error) {
 
 	return user, nil
 }
+
+func (r *Repository) UpdateBaseCurrency(userId int64, currency string) error {
+	query := `
+		UPDATE users
+		SET base_currency = $2
+		WHERE id = $1
+	`
+	result, err := r.db.Exec(query, userId, currency)
+	if err != nil {
+		log.Errorf("Error updating base currency: %v", err)
+		return err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if affected == 0 {
+		return UserNotFoundErr
+	}
+	return nil
+}
@@ -4,15 +4,17 @@ import (
 	"fmt"
 
 	"github.com/karataydev/portfoliomanbackend/internal/auth"
+	"github.com/karataydev/portfoliomanbackend/internal/fx"
 )
 
 type Service struct {
 	repo         *Repository
 	tokenService *auth.TokenService
+	fxService    *fx.Service
 }
 
-func NewService(repo *Repository, tokenService *auth.TokenService) *Service {
-	return &Service{repo: repo, tokenService: tokenService}
+func NewService(repo *Repository, tokenService *auth.TokenService, fxService *fx.Service) *Service {
+	return &Service{repo: repo, tokenService: tokenService, fxService: fxService}
 }
 
 func (s *Service) GetByEmail(email string) (*User, error) {
@@ -23,6 +25,26 @@ func (s *Service) Get(id int64) (*User, error) {
 	return s.repo.Get(id)
 }
 
+func (s *Service) GetBaseCurrency(id int64) (string, error) {
+	user, err := s.repo.Get(id)
+	if err != nil {
+		return "", err
+	}
+	return fx.Normalize(user.BaseCurrency), nil
+}
+
+func (s *Service) UpdateBaseCurrency(id int64, currency string) (*User, error) {
+	currency = fx.Normalize(currency)
+	if !s.fxService.IsSupported(currency) {
+		return nil, fmt.Errorf("%w: %s", fx.UnsupportedCurrencyErr, currency)
+	}
+
+	if err := s.repo.UpdateBaseCurrency(id, currency); err != nil {
+		return nil, err
+	}
+	return s.repo.Get(id)
+}
+
 func (s *Service) SignUp(googleToken string) (*SignInUpResponse, error) {
 	googleClaims, err := s.tokenService.ValidateGoogleToken(googleToken)
 	if err != nil {
@@ -0,0 +1,170 @@
+package watchlist
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type Handler struct {
+	service *Service
+}
+
+func NewHandler(service *Service) *Handler {
+	return &Handler{service: service}
+}
+
+func (h *Handler) GetWatchlists(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	watchlists, err := h.service.GetWatchlists(userId)
+	if err != nil {
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Failed to fetch watchlists"})
+	}
+	return c.JSON(watchlists)
+}
+
+func (h *Handler) GetWatchlist(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	watchlistId, err := c.ParamsInt("watchlistId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Watchlist ID"})
+	}
+
+	watchlist, err := h.service.GetWatchlist(userId, int64(watchlistId))
+	if err != nil {
+		return h.watchlistError(c, err, "Failed to fetch watchlist")
+	}
+	return c.JSON(watchlist)
+}
+
+func (h *Handler) CreateWatchlist(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+
+	var req WatchlistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	watchlist, err := h.service.CreateWatchlist(userId, req)
+	if err != nil {
+		return h.watchlistError(c, err, "Failed to create watchlist")
+	}
+	return c.Status(fiber.StatusCreated).JSON(watchlist)
+}
+
+func (h *Handler) RenameWatchlist(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	watchlistId, err := c.ParamsInt("watchlistId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Watchlist ID"})
+	}
+
+	var req WatchlistRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if err := req.validate(); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	}
+
+	watchlist, err := h.service.RenameWatchlist(userId, int64(watchlistId), req)
+	if err != nil {
+		return h.watchlistError(c, err, "Failed to rename watchlist")
+	}
+	return c.JSON(watchlist)
+}
+
+func (h *Handler) DeleteWatchlist(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	watchlistId, err := c.ParamsInt("watchlistId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Watchlist ID"})
+	}
+
+	if err := h.service.DeleteWatchlist(userId, int64(watchlistId)); err != nil {
+		return h.watchlistError(c, err, "Failed to delete watchlist")
+	}
+	return c.JSON(fiber.Map{"message": "Watchlist deleted"})
+}
+
+func (h *Handler) AddAsset(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	watchlistId, err := c.ParamsInt("watchlistId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Watchlist ID"})
+	}
+
+	var req AddAssetRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+	if req.Symbol == "" {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "symbol is required"})
+	}
+
+	watchlist, err := h.service.AddAsset(userId, int64(watchlistId), req.Symbol)
+	if err == sql.ErrNoRows {
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Asset not found"})
+	}
+	if err != nil {
+		return h.watchlistError(c, err, "Failed to add asset to watchlist")
+	}
+	return c.JSON(watchlist)
+}
+
+func (h *Handler) RemoveAsset(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	watchlistId, err := c.ParamsInt("watchlistId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Watchlist ID"})
+	}
+	assetId, err := c.ParamsInt("assetId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Asset ID"})
+	}
+
+	watchlist, err := h.service.RemoveAsset(userId, int64(watchlistId), int64(assetId))
+	if err != nil {
+		return h.watchlistError(c, err, "Failed to remove asset from watchlist")
+	}
+	return c.JSON(watchlist)
+}
+
+func (h *Handler) ReorderAssets(c *fiber.Ctx) error {
+	userId := c.Locals("userId").(int64)
+	watchlistId, err := c.ParamsInt("watchlistId")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid Watchlist ID"})
+	}
+
+	var req ReorderRequest
+	if err := c.BodyParser(&req); err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request body"})
+	}
+
+	watchlist, err := h.service.ReorderAssets(userId, int64(watchlistId), req.AssetIds)
+	if err != nil {
+		return h.watchlistError(c, err, "Failed to reorder watchlist")
+	}
+	return c.JSON(watchlist)
+}
+
+func (h *Handler) watchlistError(c *fiber.Ctx, err error, message string) error {
+	switch err {
+	case WatchlistNotFoundErr, AssetNotWatchedErr:
+		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": err.Error()})
+	case WatchlistExistsErr, AssetAlreadyWatchedErr:
+		return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": err.Error()})
+	case InvalidOrderErr:
+		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": err.Error()})
+	default:
+		log.Error(err)
+		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": message})
+	}
+}
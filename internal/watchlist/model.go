@@ -0,0 +1,74 @@
+package watchlist
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+var WatchlistNotFoundErr error = errors.New("watchlist not found")
+var WatchlistExistsErr error = errors.New("a watchlist with this name already exists")
+var AssetAlreadyWatchedErr error = errors.New("asset is already in the watchlist")
+var AssetNotWatchedErr error = errors.New("asset is not in the watchlist")
+var InvalidOrderErr error = errors.New("order must list every asset of the watchlist once")
+
+type Watchlist struct {
+	Id        int64     `db:"id" json:"id"`
+	UserId    int64     `db:"user_id" json:"-"`
+	Name      string    `db:"name" json:"name"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// WatchlistItem is an asset of a watchlist in the order the user put it
+type WatchlistItem struct {
+	WatchlistId int64 `db:"watchlist_id" json:"-"`
+	Position    int   `db:"position" json:"position"`
+	asset.SimpleAssetDTO
+}
+
+type WatchlistDTO struct {
+	Watchlist
+	Assets []asset.SimpleAssetDTO `json:"assets"`
+}
+
+// WatchlistEntry is an asset of a watchlist with its prices, the prices are null when no quote is available
+type WatchlistEntry struct {
+	Asset         asset.SimpleAssetDTO `json:"asset"`
+	Price         *float64             `json:"price"`
+	QuoteTime     *time.Time           `json:"quote_time"`
+	PreviousClose *float64             `json:"previous_close"`
+	Change        *float64             `json:"change"`
+	Week52Low     *float64             `json:"week_52_low"`
+	Week52High    *float64             `json:"week_52_high"`
+}
+
+type WatchlistResponse struct {
+	Watchlist
+	Entries []WatchlistEntry `json:"entries"`
+}
+
+type WatchlistRequest struct {
+	Name string `json:"name"`
+}
+
+func (r *WatchlistRequest) validate() error {
+	r.Name = strings.TrimSpace(r.Name)
+	if r.Name == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Name) > 255 {
+		return errors.New("name must be at most 255 characters")
+	}
+	return nil
+}
+
+type AddAssetRequest struct {
+	Symbol string `json:"symbol"`
+}
+
+type ReorderRequest struct {
+	AssetIds []int64 `json:"asset_ids"`
+}
@@ -0,0 +1,182 @@
+package watchlist
+
+import (
+	"database/sql"
+
+	"github.com/gofiber/fiber/v2/log"
+	"github.com/karataydev/portfoliomanbackend/internal/database"
+	"github.com/lib/pq"
+)
+
+type Repository struct {
+	db *database.DBConnection
+}
+
+func NewRepository(db *database.DBConnection) *Repository {
+	return &Repository{db: db}
+}
+
+func (r *Repository) GetWatchlistsByUser(userId int64) ([]Watchlist, error) {
+	query := `
+		SELECT *
+		FROM watchlist
+		WHERE user_id = $1
+		ORDER BY created_at, id
+	`
+	watchlists := []Watchlist{}
+	err := r.db.Select(&watchlists, query, userId)
+	if err != nil {
+		log.Errorf("Error fetching watchlists: %v", err)
+		return nil, err
+	}
+	return watchlists, nil
+}
+
+// GetWatchlist returns the watchlist if it belongs to the user
+func (r *Repository) GetWatchlist(userId, watchlistId int64) (*Watchlist, error) {
+	query := `
+		SELECT *
+		FROM watchlist
+		WHERE id = $1 AND user_id = $2
+	`
+	var watchlist Watchlist
+	err := r.db.Get(&watchlist, query, watchlistId, userId)
+	if err == sql.ErrNoRows {
+		return nil, WatchlistNotFoundErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &watchlist, nil
+}
+
+// GetItems returns the assets of the watchlists in their order
+func (r *Repository) GetItems(watchlistIds []int64) ([]WatchlistItem, error) {
+	query := `
+		SELECT
+			wi.watchlist_id,
+			wi.position,
+			a.id,
+			a.name,
+			a.symbol,
+			COALESCE(a.currency, 'USD') AS currency,
+			COALESCE(a.exchange, '') AS exchange
+		FROM watchlist_item wi
+		JOIN asset a ON a.id = wi.asset_id
+		WHERE wi.watchlist_id = ANY($1)
+		ORDER BY wi.watchlist_id, wi.position
+	`
+	var items []WatchlistItem
+	err := r.db.Select(&items, query, pq.Array(watchlistIds))
+	if err != nil {
+		log.Errorf("Error fetching watchlist items: %v", err)
+		return nil, err
+	}
+	return items, nil
+}
+
+func (r *Repository) CreateWatchlist(userId int64, name string) (*Watchlist, error) {
+	query := `
+		INSERT INTO watchlist (user_id, name)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, name) DO NOTHING
+		RETURNING *
+	`
+	var watchlist Watchlist
+	err := r.db.Get(&watchlist, query, userId, name)
+	if err == sql.ErrNoRows {
+		return nil, WatchlistExistsErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &watchlist, nil
+}
+
+func (r *Repository) RenameWatchlist(userId, watchlistId int64, name string) (*Watchlist, error) {
+	query := `
+		UPDATE watchlist
+		SET name = $3
+		WHERE id = $1 AND user_id = $2
+			AND NOT EXISTS (SELECT 1 FROM watchlist WHERE user_id = $2 AND name = $3 AND id <> $1)
+		RETURNING *
+	`
+	var watchlist Watchlist
+	err := r.db.Get(&watchlist, query, watchlistId, userId, name)
+	if err == sql.ErrNoRows {
+		// either the watchlist is missing or the name is taken
+		if _, err := r.GetWatchlist(userId, watchlistId); err != nil {
+			return nil, err
+		}
+		return nil, WatchlistExistsErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &watchlist, nil
+}
+
+func (r *Repository) DeleteWatchlist(userId, watchlistId int64) error {
+	result, err := r.db.Exec("DELETE FROM watchlist WHERE id = $1 AND user_id = $2", watchlistId, userId)
+	if err != nil {
+		return err
+	}
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return WatchlistNotFoundErr
+	}
+	return nil
+}
+
+// AddItem appends the asset to the end of the watchlist
+func (r *Repository) AddItem(watchlistId, assetId int64) error {
+	query := `
+		INSERT INTO watchlist_item (watchlist_id, asset_id, position)
+		SELECT $1, $2, COALESCE(MAX(position) + 1, 0)
+		FROM watchlist_item
+		WHERE watchlist_id = $1
+		ON CONFLICT (watchlist_id, asset_id) DO NOTHING
+	`
+	result, err := r.db.Exec(query, watchlistId, assetId)
+	if err != nil {
+		return err
+	}
+	added, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if added == 0 {
+		return AssetAlreadyWatchedErr
+	}
+	return nil
+}
+
+func (r *Repository) RemoveItem(watchlistId, assetId int64) error {
+	result, err := r.db.Exec("DELETE FROM watchlist_item WHERE watchlist_id = $1 AND asset_id = $2", watchlistId, assetId)
+	if err != nil {
+		return err
+	}
+	removed, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if removed == 0 {
+		return AssetNotWatchedErr
+	}
+	return nil
+}
+
+// ReorderItems sets the position of every asset to its index in assetIds
+func (r *Repository) ReorderItems(watchlistId int64, assetIds []int64) error {
+	query := `
+		UPDATE watchlist_item wi
+		SET position = ids.position - 1
+		FROM unnest($2::bigint[]) WITH ORDINALITY AS ids(asset_id, position)
+		WHERE wi.watchlist_id = $1 AND wi.asset_id = ids.asset_id
+	`
+	_, err := r.db.Exec(query, watchlistId, pq.Array(assetIds))
+	return err
+}
@@ -0,0 +1,230 @@
+package watchlist
+
+import (
+	"strings"
+	"time"
+
+	"github.com/karataydev/portfoliomanbackend/internal/asset"
+)
+
+type Service struct {
+	repo         *Repository
+	assetService *asset.Service
+}
+
+func NewService(repo *Repository, assetService *asset.Service) *Service {
+	return &Service{
+		repo:         repo,
+		assetService: assetService,
+	}
+}
+
+// GetWatchlists returns the watchlists of the user with their assets
+func (s *Service) GetWatchlists(userId int64) ([]WatchlistDTO, error) {
+	watchlists, err := s.repo.GetWatchlistsByUser(userId)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]WatchlistDTO, 0, len(watchlists))
+	if len(watchlists) == 0 {
+		return response, nil
+	}
+
+	watchlistIds := make([]int64, len(watchlists))
+	for i, watchlist := range watchlists {
+		watchlistIds[i] = watchlist.Id
+	}
+	items, err := s.repo.GetItems(watchlistIds)
+	if err != nil {
+		return nil, err
+	}
+
+	assetsByWatchlist := make(map[int64][]asset.SimpleAssetDTO, len(watchlists))
+	for _, item := range items {
+		assetsByWatchlist[item.WatchlistId] = append(assetsByWatchlist[item.WatchlistId], item.SimpleAssetDTO)
+	}
+	for _, watchlist := range watchlists {
+		assets := assetsByWatchlist[watchlist.Id]
+		if assets == nil {
+			assets = []asset.SimpleAssetDTO{}
+		}
+		response = append(response, WatchlistDTO{Watchlist: watchlist, Assets: assets})
+	}
+	return response, nil
+}
+
+// GetWatchlist returns the watchlist with the latest quote, daily change and 52 week range of every asset
+func (s *Service) GetWatchlist(userId, watchlistId int64) (*WatchlistResponse, error) {
+	watchlist, err := s.repo.GetWatchlist(userId, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.GetItems([]int64{watchlist.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := s.getEntries(items)
+	if err != nil {
+		return nil, err
+	}
+	return &WatchlistResponse{Watchlist: *watchlist, Entries: entries}, nil
+}
+
+// getEntries loads the prices of the items, a missing quote leaves the prices depending on it empty
+func (s *Service) getEntries(items []WatchlistItem) ([]WatchlistEntry, error) {
+	entries := make([]WatchlistEntry, 0, len(items))
+	if len(items) == 0 {
+		return entries, nil
+	}
+
+	assetIds := make([]int64, len(items))
+	for i, item := range items {
+		assetIds[i] = item.Id
+	}
+	latestQuotes, err := s.assetService.GetLatestQuotes(assetIds)
+	if err != nil {
+		return nil, err
+	}
+
+	previousRequests := make([]asset.PreviousCloseRequest, 0, len(items))
+	for _, item := range items {
+		if latestQuote, ok := latestQuotes[item.Id]; ok {
+			previousRequests = append(previousRequests, asset.PreviousCloseRequest{AssetId: item.Id, Exchange: item.Exchange, Time: latestQuote.QuoteTime})
+		}
+	}
+	previousQuotes, err := s.assetService.GetPreviousTradingDayQuotes(previousRequests)
+	if err != nil {
+		return nil, err
+	}
+
+	ranges, err := s.assetService.Get52WeekRanges(assetIds, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		entry := WatchlistEntry{Asset: item.SimpleAssetDTO}
+
+		latestQuote, hasLatest := latestQuotes[item.Id]
+		if hasLatest {
+			entry.Price = &latestQuote.Quote
+			entry.QuoteTime = &latestQuote.QuoteTime
+		}
+		if previousQuote, ok := previousQuotes[item.Id]; ok {
+			entry.PreviousClose = &previousQuote.Quote
+			if previousQuote.Quote != 0 {
+				change := ((latestQuote.Quote - previousQuote.Quote) / previousQuote.Quote) * 100
+				entry.Change = &change
+			}
+		}
+		if priceRange, ok := ranges[item.Id]; ok {
+			// the range ends before today, the latest price extends it like it triggers an alert
+			if hasLatest {
+				priceRange.Low = min(priceRange.Low, latestQuote.Quote)
+				priceRange.High = max(priceRange.High, latestQuote.Quote)
+			}
+			entry.Week52Low = &priceRange.Low
+			entry.Week52High = &priceRange.High
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func (s *Service) CreateWatchlist(userId int64, request WatchlistRequest) (*WatchlistDTO, error) {
+	watchlist, err := s.repo.CreateWatchlist(userId, request.Name)
+	if err != nil {
+		return nil, err
+	}
+	return &WatchlistDTO{Watchlist: *watchlist, Assets: []asset.SimpleAssetDTO{}}, nil
+}
+
+func (s *Service) RenameWatchlist(userId, watchlistId int64, request WatchlistRequest) (*WatchlistDTO, error) {
+	if _, err := s.repo.RenameWatchlist(userId, watchlistId, request.Name); err != nil {
+		return nil, err
+	}
+	return s.getWatchlistDTO(userId, watchlistId)
+}
+
+func (s *Service) DeleteWatchlist(userId, watchlistId int64) error {
+	return s.repo.DeleteWatchlist(userId, watchlistId)
+}
+
+// AddAsset appends the asset of the symbol to the watchlist
+func (s *Service) AddAsset(userId, watchlistId int64, symbol string) (*WatchlistDTO, error) {
+	watchlist, err := s.repo.GetWatchlist(userId, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+
+	a, err := s.assetService.GetAssetBySymbol(strings.ToUpper(strings.TrimSpace(symbol)))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.AddItem(watchlist.Id, a.Id); err != nil {
+		return nil, err
+	}
+	return s.getWatchlistDTO(userId, watchlistId)
+}
+
+func (s *Service) RemoveAsset(userId, watchlistId, assetId int64) (*WatchlistDTO, error) {
+	watchlist, err := s.repo.GetWatchlist(userId, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.RemoveItem(watchlist.Id, assetId); err != nil {
+		return nil, err
+	}
+	return s.getWatchlistDTO(userId, watchlistId)
+}
+
+// ReorderAssets puts the assets of the watchlist in the given order, it must list each of them once
+func (s *Service) ReorderAssets(userId, watchlistId int64, assetIds []int64) (*WatchlistDTO, error) {
+	watchlist, err := s.repo.GetWatchlist(userId, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.GetItems([]int64{watchlist.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(assetIds) != len(items) {
+		return nil, InvalidOrderErr
+	}
+	watched := make(map[int64]bool, len(items))
+	for _, item := range items {
+		watched[item.Id] = true
+	}
+	for _, assetId := range assetIds {
+		if !watched[assetId] {
+			return nil, InvalidOrderErr
+		}
+		// a repeated id is no longer found
+		delete(watched, assetId)
+	}
+
+	if err := s.repo.ReorderItems(watchlist.Id, assetIds); err != nil {
+		return nil, err
+	}
+	return s.getWatchlistDTO(userId, watchlistId)
+}
+
+func (s *Service) getWatchlistDTO(userId, watchlistId int64) (*WatchlistDTO, error) {
+	watchlist, err := s.repo.GetWatchlist(userId, watchlistId)
+	if err != nil {
+		return nil, err
+	}
+	items, err := s.repo.GetItems([]int64{watchlist.Id})
+	if err != nil {
+		return nil, err
+	}
+
+	assets := make([]asset.SimpleAssetDTO, 0, len(items))
+	for _, item := range items {
+		assets = append(assets, item.SimpleAssetDTO)
+	}
+	return &WatchlistDTO{Watchlist: *watchlist, Assets: assets}, nil
+}
@@ -0,0 +1,14 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_asset_sector;
+DROP INDEX IF EXISTS idx_asset_asset_class;
+
+ALTER TABLE asset
+DROP COLUMN IF EXISTS sector,
+DROP COLUMN IF EXISTS industry,
+DROP COLUMN IF EXISTS asset_class,
+DROP COLUMN IF EXISTS country,
+DROP COLUMN IF EXISTS currency,
+DROP COLUMN IF EXISTS exchange;
+
+COMMIT;
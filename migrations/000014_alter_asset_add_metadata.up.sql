@@ -0,0 +1,64 @@
+BEGIN;
+
+ALTER TABLE asset
+ADD COLUMN IF NOT EXISTS sector VARCHAR(100),
+ADD COLUMN IF NOT EXISTS industry VARCHAR(100),
+ADD COLUMN IF NOT EXISTS asset_class VARCHAR(50),
+ADD COLUMN IF NOT EXISTS country VARCHAR(100),
+ADD COLUMN IF NOT EXISTS currency VARCHAR(3),
+ADD COLUMN IF NOT EXISTS exchange VARCHAR(50);
+
+CREATE INDEX IF NOT EXISTS idx_asset_sector ON asset(sector);
+CREATE INDEX IF NOT EXISTS idx_asset_asset_class ON asset(asset_class);
+
+-- Every seeded asset is a US listed equity priced in USD
+UPDATE asset SET asset_class = 'equity', currency = 'USD', exchange = 'NASDAQ', country = 'US';
+UPDATE asset SET asset_class = 'etf', exchange = 'NYSEARCA' WHERE symbol = 'VOO';
+
+UPDATE asset a
+SET sector = v.sector
+FROM (VALUES
+    ('ARM', 'Technology'), ('AVGO', 'Technology'), ('CDNS', 'Technology'), ('ADBE', 'Technology'),
+    ('CRWD', 'Technology'), ('CSCO', 'Technology'), ('CTSH', 'Technology'), ('DDOG', 'Technology'),
+    ('ON', 'Technology'), ('TTD', 'Technology'), ('GFS', 'Technology'), ('FTNT', 'Technology'),
+    ('INTC', 'Technology'), ('INTU', 'Technology'), ('MRVL', 'Technology'), ('KLAC', 'Technology'),
+    ('LRCX', 'Technology'), ('MCHP', 'Technology'), ('MSFT', 'Technology'), ('MU', 'Technology'),
+    ('NVDA', 'Technology'), ('NXPI', 'Technology'), ('PANW', 'Technology'), ('QCOM', 'Technology'),
+    ('SNPS', 'Technology'), ('TXN', 'Technology'), ('WDAY', 'Technology'), ('ZS', 'Technology'),
+    ('AMD', 'Technology'), ('CDW', 'Technology'), ('ADI', 'Technology'), ('MDB', 'Technology'),
+    ('ROP', 'Technology'), ('ANSS', 'Technology'), ('AAPL', 'Technology'), ('AMAT', 'Technology'),
+    ('ASML', 'Technology'), ('TEAM', 'Technology'), ('ADSK', 'Technology'),
+    ('CHTR', 'Communication Services'), ('CMCSA', 'Communication Services'), ('EA', 'Communication Services'),
+    ('META', 'Communication Services'), ('GOOG', 'Communication Services'), ('GOOGL', 'Communication Services'),
+    ('NFLX', 'Communication Services'), ('TMUS', 'Communication Services'), ('WBD', 'Communication Services'),
+    ('AZN', 'Health Care'), ('BIIB', 'Health Care'), ('DXCM', 'Health Care'), ('GILD', 'Health Care'),
+    ('ILMN', 'Health Care'), ('ISRG', 'Health Care'), ('IDXX', 'Health Care'), ('MRNA', 'Health Care'),
+    ('GRAL', 'Health Care'), ('REGN', 'Health Care'), ('VRTX', 'Health Care'), ('AMGN', 'Health Care'),
+    ('GEHC', 'Health Care'),
+    ('BKR', 'Energy'), ('FANG', 'Energy'),
+    ('LIN', 'Materials'),
+    ('BKNG', 'Consumer Discretionary'), ('LULU', 'Consumer Discretionary'), ('MELI', 'Consumer Discretionary'),
+    ('MAR', 'Consumer Discretionary'), ('ORLY', 'Consumer Discretionary'), ('ROST', 'Consumer Discretionary'),
+    ('SBUX', 'Consumer Discretionary'), ('TSLA', 'Consumer Discretionary'), ('ABNB', 'Consumer Discretionary'),
+    ('AMZN', 'Consumer Discretionary'), ('DASH', 'Consumer Discretionary'), ('PDD', 'Consumer Discretionary'),
+    ('COST', 'Consumer Staples'), ('KDP', 'Consumer Staples'), ('KHC', 'Consumer Staples'),
+    ('MDLZ', 'Consumer Staples'), ('MNST', 'Consumer Staples'), ('PEP', 'Consumer Staples'),
+    ('CCEP', 'Consumer Staples'), ('DLTR', 'Consumer Staples'),
+    ('CPRT', 'Industrials'), ('CTAS', 'Industrials'), ('CSX', 'Industrials'), ('FAST', 'Industrials'),
+    ('HON', 'Industrials'), ('ODFL', 'Industrials'), ('PCAR', 'Industrials'), ('PAYX', 'Industrials'),
+    ('VRSK', 'Industrials'), ('ADP', 'Industrials'),
+    ('CSGP', 'Real Estate'),
+    ('EXC', 'Utilities'), ('XEL', 'Utilities'), ('AEP', 'Utilities'), ('CEG', 'Utilities'),
+    ('FI', 'Financials'), ('PYPL', 'Financials')
+) AS v(symbol, sector)
+WHERE a.symbol = v.symbol;
+
+UPDATE asset a
+SET country = v.country
+FROM (VALUES
+    ('ARM', 'GB'), ('AZN', 'GB'), ('CCEP', 'GB'), ('LIN', 'IE'), ('NXPI', 'NL'),
+    ('ASML', 'NL'), ('LULU', 'CA'), ('MELI', 'AR'), ('PDD', 'CN')
+) AS v(symbol, country)
+WHERE a.symbol = v.symbol;
+
+COMMIT;
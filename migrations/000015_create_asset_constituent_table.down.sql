@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP INDEX IF EXISTS idx_asset_constituent_asset_id;
+
+DROP TABLE IF EXISTS asset_constituent;
+
+COMMIT;
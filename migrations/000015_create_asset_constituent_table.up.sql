@@ -0,0 +1,29 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS asset_constituent (
+    id BIGSERIAL PRIMARY KEY,
+    asset_id BIGINT NOT NULL,
+    constituent_asset_id BIGINT,
+    symbol VARCHAR(50) NOT NULL,
+    name VARCHAR(255),
+    weight DOUBLE PRECISION NOT NULL CHECK (weight >= 0 AND weight <= 100),
+    sector VARCHAR(100),
+    country VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_asset_constituent_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_asset_constituent_constituent_asset
+        FOREIGN KEY (constituent_asset_id)
+        REFERENCES asset(id)
+        ON DELETE SET NULL,
+    CONSTRAINT uq_asset_constituent_asset_symbol
+        UNIQUE (asset_id, symbol)
+);
+
+CREATE INDEX IF NOT EXISTS idx_asset_constituent_asset_id ON asset_constituent(asset_id);
+
+COMMENT ON TABLE asset_constituent IS 'Holdings and weights of funds such as ETFs, used for look-through analysis';
+
+COMMIT;
@@ -0,0 +1,14 @@
+BEGIN;
+
+DELETE FROM asset_quote WHERE asset_id IN (SELECT id FROM asset WHERE asset_class = 'fx');
+DELETE FROM asset WHERE asset_class = 'fx';
+
+ALTER TABLE users
+DROP COLUMN IF EXISTS base_currency;
+
+ALTER TABLE transaction
+DROP COLUMN IF EXISTS currency;
+
+ALTER TABLE asset ALTER COLUMN currency DROP DEFAULT;
+
+COMMIT;
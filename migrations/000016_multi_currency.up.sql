@@ -0,0 +1,23 @@
+BEGIN;
+
+-- Assets without a currency are priced in USD
+UPDATE asset SET currency = 'USD' WHERE currency IS NULL;
+ALTER TABLE asset ALTER COLUMN currency SET DEFAULT 'USD';
+
+ALTER TABLE transaction
+ADD COLUMN IF NOT EXISTS currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+ALTER TABLE users
+ADD COLUMN IF NOT EXISTS base_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+-- FX rates are stored as quotes of special assets, <CCY>USD=X is the USD price of one unit of CCY
+INSERT INTO asset (symbol, name, description, asset_class, currency, exchange) VALUES
+('EURUSD=X', 'EUR/USD', 'Euro to US Dollar exchange rate', 'fx', 'USD', 'CCY'),
+('GBPUSD=X', 'GBP/USD', 'British Pound to US Dollar exchange rate', 'fx', 'USD', 'CCY'),
+('JPYUSD=X', 'JPY/USD', 'Japanese Yen to US Dollar exchange rate', 'fx', 'USD', 'CCY'),
+('CHFUSD=X', 'CHF/USD', 'Swiss Franc to US Dollar exchange rate', 'fx', 'USD', 'CCY'),
+('CADUSD=X', 'CAD/USD', 'Canadian Dollar to US Dollar exchange rate', 'fx', 'USD', 'CCY'),
+('TRYUSD=X', 'TRY/USD', 'Turkish Lira to US Dollar exchange rate', 'fx', 'USD', 'CCY')
+ON CONFLICT (symbol) DO NOTHING;
+
+COMMIT;
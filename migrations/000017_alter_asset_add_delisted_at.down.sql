@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE asset
+DROP COLUMN IF EXISTS delisted_at;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE asset
+ADD COLUMN IF NOT EXISTS delisted_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
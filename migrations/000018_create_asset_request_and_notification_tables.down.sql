@@ -0,0 +1,9 @@
+BEGIN;
+
+DROP TABLE IF EXISTS notification;
+
+DROP TRIGGER IF EXISTS update_asset_request_updated_at ON asset_request;
+
+DROP TABLE IF EXISTS asset_request;
+
+COMMIT;
@@ -0,0 +1,25 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS quote_anomaly (
+    id BIGSERIAL PRIMARY KEY,
+    asset_id BIGINT NOT NULL,
+    quote_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    quote DOUBLE PRECISION NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    reference_provider VARCHAR(50),
+    reference_quote DOUBLE PRECISION,
+    deviation DOUBLE PRECISION,
+    reason VARCHAR(50) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'quarantined' CHECK (status IN ('quarantined', 'released', 'discarded')),
+    resolved_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_quote_anomaly_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quote_anomaly_status ON quote_anomaly(status);
+CREATE INDEX IF NOT EXISTS idx_quote_anomaly_asset_id_quote_time ON quote_anomaly(asset_id, quote_time);
+
+COMMIT;
@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TRIGGER IF EXISTS update_quote_backfill_job_updated_at ON quote_backfill_job;
+
+DROP TABLE IF EXISTS quote_backfill_job;
+
+COMMIT;
@@ -0,0 +1,28 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS quote_backfill_job (
+    id BIGSERIAL PRIMARY KEY,
+    asset_id BIGINT NOT NULL,
+    range_start DATE NOT NULL,
+    range_end DATE NOT NULL,
+    interval VARCHAR(10) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'failed')),
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_quote_backfill_job_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_quote_backfill_job_status ON quote_backfill_job(status);
+CREATE UNIQUE INDEX IF NOT EXISTS uq_quote_backfill_job_open_range ON quote_backfill_job(asset_id, range_start, range_end) WHERE status IN ('pending', 'running');
+
+CREATE TRIGGER update_quote_backfill_job_updated_at
+BEFORE UPDATE ON quote_backfill_job
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+COMMIT;
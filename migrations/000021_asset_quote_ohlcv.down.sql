@@ -0,0 +1,21 @@
+BEGIN;
+
+ALTER TABLE quote_anomaly
+DROP COLUMN IF EXISTS interval;
+
+ALTER TABLE asset_quote
+DROP CONSTRAINT IF EXISTS uq_asset_quote_asset_id_interval_quote_time;
+
+DELETE FROM asset_quote WHERE interval <> '1h';
+
+ALTER TABLE asset_quote
+ADD CONSTRAINT idx_unique_asset_quote_asset_id_quote_time UNIQUE (asset_id, quote_time);
+
+ALTER TABLE asset_quote
+DROP COLUMN IF EXISTS interval,
+DROP COLUMN IF EXISTS open,
+DROP COLUMN IF EXISTS high,
+DROP COLUMN IF EXISTS low,
+DROP COLUMN IF EXISTS volume;
+
+COMMIT;
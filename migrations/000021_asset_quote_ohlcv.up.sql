@@ -0,0 +1,19 @@
+BEGIN;
+
+ALTER TABLE asset_quote
+ADD COLUMN IF NOT EXISTS interval VARCHAR(10) NOT NULL DEFAULT '1h',
+ADD COLUMN IF NOT EXISTS open DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS high DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS low DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS volume DOUBLE PRECISION;
+
+ALTER TABLE asset_quote
+DROP CONSTRAINT IF EXISTS idx_unique_asset_quote_asset_id_quote_time;
+
+ALTER TABLE asset_quote
+ADD CONSTRAINT uq_asset_quote_asset_id_interval_quote_time UNIQUE (asset_id, interval, quote_time);
+
+ALTER TABLE quote_anomaly
+ADD COLUMN IF NOT EXISTS interval VARCHAR(10) NOT NULL DEFAULT '1h';
+
+COMMIT;
@@ -0,0 +1,21 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS asset_quote_snapshot (
+    id BIGSERIAL PRIMARY KEY,
+    asset_id BIGINT NOT NULL,
+    price DOUBLE PRECISION NOT NULL,
+    open DOUBLE PRECISION,
+    high DOUBLE PRECISION,
+    low DOUBLE PRECISION,
+    volume DOUBLE PRECISION,
+    provider VARCHAR(50) NOT NULL,
+    quote_time TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_asset_quote_snapshot_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE,
+    CONSTRAINT uq_asset_quote_snapshot_asset_id_quote_time UNIQUE (asset_id, quote_time)
+);
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE scheduled_job
+    DROP COLUMN IF EXISTS lock_expires_at,
+    DROP COLUMN IF EXISTS locked_by,
+    DROP COLUMN IF EXISTS scheduled_for;
+
+COMMIT;
@@ -0,0 +1,8 @@
+BEGIN;
+
+ALTER TABLE scheduled_job
+    ADD COLUMN IF NOT EXISTS scheduled_for TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN IF NOT EXISTS locked_by VARCHAR(255),
+    ADD COLUMN IF NOT EXISTS lock_expires_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
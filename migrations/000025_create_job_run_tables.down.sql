@@ -0,0 +1,7 @@
+BEGIN;
+
+DROP TABLE IF EXISTS job_run_item;
+DROP TABLE IF EXISTS job_run;
+ALTER TABLE scheduled_job DROP COLUMN IF EXISTS paused;
+
+COMMIT;
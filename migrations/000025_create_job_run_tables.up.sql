@@ -0,0 +1,39 @@
+BEGIN;
+
+ALTER TABLE scheduled_job ADD COLUMN IF NOT EXISTS paused BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS job_run (
+    id BIGSERIAL PRIMARY KEY,
+    job_name VARCHAR(100) NOT NULL,
+    scheduled_for TIMESTAMP WITH TIME ZONE NOT NULL,
+    trigger VARCHAR(20) NOT NULL CHECK (trigger IN ('schedule', 'manual', 'takeover')),
+    attempt INT NOT NULL DEFAULT 1,
+    owner VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('running', 'succeeded', 'failed')),
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT fk_job_run_scheduled_job
+        FOREIGN KEY (job_name)
+        REFERENCES scheduled_job(name)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_run_job_name_started_at ON job_run(job_name, started_at DESC);
+
+CREATE TABLE IF NOT EXISTS job_run_item (
+    id BIGSERIAL PRIMARY KEY,
+    run_id BIGINT NOT NULL,
+    item VARCHAR(255) NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('succeeded', 'failed')),
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_job_run_item_job_run
+        FOREIGN KEY (run_id)
+        REFERENCES job_run(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_job_run_item_run_id ON job_run_item(run_id);
+
+COMMIT;
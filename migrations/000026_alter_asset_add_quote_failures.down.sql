@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE asset
+DROP COLUMN IF EXISTS quote_suspended_at,
+DROP COLUMN IF EXISTS last_quote_failure_at,
+DROP COLUMN IF EXISTS last_quote_error,
+DROP COLUMN IF EXISTS quote_failure_count;
+
+COMMIT;
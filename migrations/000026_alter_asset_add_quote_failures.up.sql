@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE asset
+ADD COLUMN IF NOT EXISTS quote_failure_count INT NOT NULL DEFAULT 0,
+ADD COLUMN IF NOT EXISTS last_quote_error TEXT,
+ADD COLUMN IF NOT EXISTS last_quote_failure_at TIMESTAMP WITH TIME ZONE,
+ADD COLUMN IF NOT EXISTS quote_suspended_at TIMESTAMP WITH TIME ZONE;
+
+COMMIT;
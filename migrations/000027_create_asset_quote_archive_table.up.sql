@@ -0,0 +1,9 @@
+BEGIN;
+
+-- Same columns as asset_quote, so archived rows can be read back with it
+CREATE TABLE IF NOT EXISTS asset_quote_archive (LIKE asset_quote INCLUDING DEFAULTS);
+
+ALTER TABLE asset_quote_archive
+ADD CONSTRAINT uq_asset_quote_archive_asset_id_interval_quote_time UNIQUE (asset_id, interval, quote_time);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS market_list_asset;
+DROP TABLE IF EXISTS market_list;
+
+COMMIT;
@@ -0,0 +1,50 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS market_list (
+    id BIGSERIAL PRIMARY KEY,
+    slug VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    position INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TRIGGER update_market_list_updated_at
+BEFORE UPDATE ON market_list
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS market_list_asset (
+    market_list_id BIGINT NOT NULL,
+    asset_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    PRIMARY KEY (market_list_id, asset_id),
+    CONSTRAINT fk_market_list_asset_list
+        FOREIGN KEY (market_list_id)
+        REFERENCES market_list(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_market_list_asset_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE
+);
+
+-- the lists replace the symbols the overview used to hard-code
+INSERT INTO market_list (slug, name, position) VALUES
+('indices', 'Indices', 0),
+('tech-leaders', 'Tech Leaders', 1);
+
+INSERT INTO market_list_asset (market_list_id, asset_id, position)
+SELECT l.id, a.id, s.position
+FROM market_list l
+JOIN (VALUES
+    ('indices', 'VOO', 0),
+    ('tech-leaders', 'AAPL', 0),
+    ('tech-leaders', 'GOOGL', 1),
+    ('tech-leaders', 'MSFT', 2),
+    ('tech-leaders', 'AMZN', 3),
+    ('tech-leaders', 'META', 4)
+) AS s(slug, symbol, position) ON s.slug = l.slug
+JOIN asset a ON a.symbol = s.symbol;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS watchlist_item;
+DROP TABLE IF EXISTS watchlist;
+
+COMMIT;
@@ -0,0 +1,37 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS watchlist (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_watchlist_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT uq_watchlist_user_name UNIQUE (user_id, name)
+);
+
+CREATE TRIGGER update_watchlist_updated_at
+BEFORE UPDATE ON watchlist
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS watchlist_item (
+    watchlist_id BIGINT NOT NULL,
+    asset_id BIGINT NOT NULL,
+    position INT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (watchlist_id, asset_id),
+    CONSTRAINT fk_watchlist_item_watchlist
+        FOREIGN KEY (watchlist_id)
+        REFERENCES watchlist(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_watchlist_item_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE
+);
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+DROP TABLE IF EXISTS alert_event;
+DROP TABLE IF EXISTS price_alert;
+
+COMMIT;
@@ -0,0 +1,63 @@
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS price_alert (
+    id BIGSERIAL PRIMARY KEY,
+    user_id BIGINT NOT NULL,
+    asset_id BIGINT,
+    portfolio_id BIGINT,
+    condition VARCHAR(20) NOT NULL CHECK (condition IN ('above', 'below', 'daily_move', 'high_52w', 'low_52w')),
+    -- a price for above and below, a percentage for daily_move, unused for the 52 week conditions
+    threshold DOUBLE PRECISION,
+    recurring BOOLEAN NOT NULL DEFAULT FALSE,
+    cooldown_seconds INT NOT NULL DEFAULT 3600 CHECK (cooldown_seconds >= 0),
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    last_triggered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_price_alert_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_price_alert_asset
+        FOREIGN KEY (asset_id)
+        REFERENCES asset(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_price_alert_portfolio
+        FOREIGN KEY (portfolio_id)
+        REFERENCES portfolio(id)
+        ON DELETE CASCADE,
+    CONSTRAINT chk_price_alert_target CHECK ((asset_id IS NULL) <> (portfolio_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_alert_user_id ON price_alert(user_id);
+CREATE INDEX IF NOT EXISTS idx_price_alert_active_asset ON price_alert(asset_id) WHERE active;
+CREATE INDEX IF NOT EXISTS idx_price_alert_active_portfolio ON price_alert(portfolio_id) WHERE active;
+
+CREATE TRIGGER update_price_alert_updated_at
+BEFORE UPDATE ON price_alert
+FOR EACH ROW
+EXECUTE FUNCTION update_updated_at_column();
+
+CREATE TABLE IF NOT EXISTS alert_event (
+    id BIGSERIAL PRIMARY KEY,
+    alert_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    -- the price, value or daily move that met the condition
+    value DOUBLE PRECISION NOT NULL,
+    message TEXT NOT NULL,
+    is_read BOOLEAN NOT NULL DEFAULT FALSE,
+    triggered_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT fk_alert_event_alert
+        FOREIGN KEY (alert_id)
+        REFERENCES price_alert(id)
+        ON DELETE CASCADE,
+    CONSTRAINT fk_alert_event_user
+        FOREIGN KEY (user_id)
+        REFERENCES users(id)
+        ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_event_user_triggered ON alert_event(user_id, triggered_at DESC);
+
+COMMIT;
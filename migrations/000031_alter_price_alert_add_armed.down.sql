@@ -0,0 +1,5 @@
+BEGIN;
+
+ALTER TABLE price_alert DROP COLUMN IF EXISTS armed;
+
+COMMIT;
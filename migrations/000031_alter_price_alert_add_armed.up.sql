@@ -0,0 +1,6 @@
+BEGIN;
+
+-- a triggered alert waits for its condition to stop holding before it can trigger again
+ALTER TABLE price_alert ADD COLUMN IF NOT EXISTS armed BOOLEAN NOT NULL DEFAULT TRUE;
+
+COMMIT;
@@ -0,0 +1,6 @@
+BEGIN;
+
+ALTER TABLE quote_anomaly
+DROP CONSTRAINT IF EXISTS uq_quote_anomaly_asset_id_interval_quote_time;
+
+COMMIT;
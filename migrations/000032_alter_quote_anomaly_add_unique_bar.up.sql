@@ -0,0 +1,19 @@
+BEGIN;
+
+-- Keep one anomaly per bar, a resolved one over the quarantined repeats of it
+DELETE FROM quote_anomaly
+WHERE id IN (
+    SELECT id FROM (
+        SELECT id, ROW_NUMBER() OVER (
+            PARTITION BY asset_id, interval, quote_time
+            ORDER BY (status = 'quarantined'), id
+        ) AS rn
+        FROM quote_anomaly
+    ) ranked
+    WHERE rn > 1
+);
+
+ALTER TABLE quote_anomaly
+ADD CONSTRAINT uq_quote_anomaly_asset_id_interval_quote_time UNIQUE (asset_id, interval, quote_time);
+
+COMMIT;
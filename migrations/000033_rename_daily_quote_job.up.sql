@@ -0,0 +1,14 @@
+BEGIN;
+
+-- The daily quote job is registered as 'daily-quote-insert', its state and runs move over from 'daily quote data insert'
+INSERT INTO scheduled_job (name, schedule, timezone, next_run_at, last_run_at, last_finished_at, last_status, last_error, scheduled_for, paused, created_at)
+SELECT 'daily-quote-insert', schedule, timezone, next_run_at, last_run_at, last_finished_at, last_status, last_error, scheduled_for, paused, created_at
+FROM scheduled_job
+WHERE name = 'daily quote data insert'
+ON CONFLICT (name) DO NOTHING;
+
+UPDATE job_run SET job_name = 'daily-quote-insert' WHERE job_name = 'daily quote data insert';
+
+DELETE FROM scheduled_job WHERE name = 'daily quote data insert';
+
+COMMIT;
@@ -0,0 +1,9 @@
+BEGIN;
+
+ALTER TABLE quote_anomaly
+DROP COLUMN IF EXISTS open,
+DROP COLUMN IF EXISTS high,
+DROP COLUMN IF EXISTS low,
+DROP COLUMN IF EXISTS volume;
+
+COMMIT;
@@ -0,0 +1,10 @@
+BEGIN;
+
+-- A released anomaly is saved as the whole bar, older anomalies only have the close
+ALTER TABLE quote_anomaly
+ADD COLUMN IF NOT EXISTS open DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS high DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS low DOUBLE PRECISION,
+ADD COLUMN IF NOT EXISTS volume DOUBLE PRECISION;
+
+COMMIT;
@@ -0,0 +1,156 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Stats are the counters of a cache since it was created
+type Stats struct {
+	Hits          int64   `json:"hits"`
+	Misses        int64   `json:"misses"`
+	Invalidations int64   `json:"invalidations"`
+	Entries       int     `json:"entries"`
+	HitRate       float64 `json:"hit_rate"`
+}
+
+type entry[V any] struct {
+	value     V
+	expiresAt time.Time
+}
+
+// deletion is the generation a key was deleted at
+type deletion struct {
+	generation uint64
+	at         time.Time
+}
+
+// Cache is an in-process map whose entries expire after a ttl, safe for concurrent use.
+//
+// A value loaded while its key is deleted would put the stale value back, so loads read the
+// Generation before they start and Set drops the value when the key was deleted since.
+type Cache[K comparable, V any] struct {
+	ttl time.Duration
+
+	mu        sync.RWMutex
+	entries   map[K]entry[V]
+	lastSweep time.Time
+
+	// generation counts the deletions, deleted keeps the generation of every key deleted
+	// within the last ttl and deletedAll the one of the last DeleteFunc
+	generation uint64
+	deleted    map[K]deletion
+	deletedAll uint64
+
+	hits          atomic.Int64
+	misses        atomic.Int64
+	invalidations atomic.Int64
+}
+
+func New[K comparable, V any](ttl time.Duration) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:       ttl,
+		entries:   make(map[K]entry[V]),
+		deleted:   make(map[K]deletion),
+		lastSweep: time.Now(),
+	}
+}
+
+func (c *Cache[K, V]) Get(key K) (V, bool) {
+	c.mu.RLock()
+	e, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if !ok || time.Now().After(e.expiresAt) {
+		c.misses.Add(1)
+		var zero V
+		return zero, false
+	}
+	c.hits.Add(1)
+	return e.value, true
+}
+
+// Generation is read before loading a value, Set only stores the value when none of
+// its keys were deleted since
+func (c *Cache[K, V]) Generation() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// Set stores the value loaded after the generation was read, unless the key was deleted
+// since. A cache with a ttl of zero or less stores nothing.
+func (c *Cache[K, V]) Set(key K, value V, generation uint64) {
+	if c.ttl <= 0 {
+		return
+	}
+	now := time.Now()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.deletedAll > generation || c.deleted[key].generation > generation {
+		return
+	}
+	c.entries[key] = entry[V]{value: value, expiresAt: now.Add(c.ttl)}
+
+	// expired entries are dropped once per ttl, so keys never read again don't pile up.
+	// Deletions older than the ttl go too, only a load running longer could still miss one.
+	if now.Sub(c.lastSweep) > c.ttl {
+		for k, e := range c.entries {
+			if now.After(e.expiresAt) {
+				delete(c.entries, k)
+			}
+		}
+		for k, d := range c.deleted {
+			if now.Sub(d.at) > c.ttl {
+				delete(c.deleted, k)
+			}
+		}
+		c.lastSweep = now
+	}
+}
+
+// Delete removes the entry and keeps a load of the key running now from storing its value
+func (c *Cache[K, V]) Delete(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.deleted[key] = deletion{generation: c.generation, at: time.Now()}
+	if _, ok := c.entries[key]; ok {
+		delete(c.entries, key)
+		c.invalidations.Add(1)
+	}
+}
+
+// DeleteFunc removes the entries matching the predicate. The keys of the loads running now
+// are unknown, so none of them stores its value.
+func (c *Cache[K, V]) DeleteFunc(match func(K, V) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	c.deletedAll = c.generation
+	for k, e := range c.entries {
+		if match(k, e.value) {
+			delete(c.entries, k)
+			c.invalidations.Add(1)
+		}
+	}
+}
+
+func (c *Cache[K, V]) Stats() Stats {
+	c.mu.RLock()
+	entries := len(c.entries)
+	c.mu.RUnlock()
+
+	stats := Stats{
+		Hits:          c.hits.Load(),
+		Misses:        c.misses.Load(),
+		Invalidations: c.invalidations.Load(),
+		Entries:       entries,
+	}
+	if total := stats.Hits + stats.Misses; total > 0 {
+		stats.HitRate = float64(stats.Hits) / float64(total)
+	}
+	return stats
+}
@@ -0,0 +1,93 @@
+// Package lifecycle runs the shutdown hooks of an app in the order they were added,
+// e.g. stop taking requests first and close the database last.
+package lifecycle
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+type hook struct {
+	name string
+	stop func(ctx context.Context) error
+}
+
+type Manager struct {
+	hooks   []hook
+	signals chan os.Signal
+}
+
+func New() *Manager {
+	return &Manager{}
+}
+
+// OnShutdown adds a hook, it should return once its component stopped or ctx is done
+func (m *Manager) OnShutdown(name string, stop func(ctx context.Context) error) {
+	m.hooks = append(m.hooks, hook{name: name, stop: stop})
+}
+
+// ListenForSignals catches SIGINT and SIGTERM from now on, so a signal arriving while the
+// app starts up waits for WaitForSignal instead of killing the process
+func (m *Manager) ListenForSignals() {
+	if m.signals != nil {
+		return
+	}
+	m.signals = make(chan os.Signal, 1)
+	signal.Notify(m.signals, syscall.SIGINT, syscall.SIGTERM)
+}
+
+// WaitForSignal blocks until SIGINT or SIGTERM is received, or returns the error
+// of a component that stopped on its own, like a server failing to listen
+func (m *Manager) WaitForSignal(errs <-chan error) error {
+	m.ListenForSignals()
+	defer signal.Stop(m.signals)
+
+	select {
+	case sig := <-m.signals:
+		log.Infof("Received %s, shutting down", sig)
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+// Shutdown runs every hook within timeout, a failing hook doesn't stop the ones after it
+func (m *Manager) Shutdown(timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var firstErr error
+	for _, h := range m.hooks {
+		start := time.Now()
+		if err := h.stop(ctx); err != nil {
+			log.Errorf("Shutdown of %s failed: %v", h.name, err)
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		log.Infof("Stopped %s in %s", h.name, time.Since(start).Round(time.Millisecond))
+	}
+	return firstErr
+}
+
+// Wait waits for wait to return or ctx to be done
+func Wait(ctx context.Context, wait func()) error {
+	done := make(chan struct{})
+	go func() {
+		wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+)
+
+type itemsKey struct{}
+
+type itemRecorder struct {
+	mu    sync.Mutex
+	items []ItemResult
+}
+
+// ReportItem records the outcome of an item processed by the running job, it's
+// stored with the run. Calls outside of a scheduled run are ignored.
+func ReportItem(ctx context.Context, item string, err error) {
+	recorder, ok := ctx.Value(itemsKey{}).(*itemRecorder)
+	if !ok {
+		return
+	}
+
+	result := ItemResult{Item: item, Status: StatusSucceeded}
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+
+	recorder.mu.Lock()
+	recorder.items = append(recorder.items, result)
+	recorder.mu.Unlock()
+}
+
+func withItemRecorder(ctx context.Context) (context.Context, *itemRecorder) {
+	recorder := &itemRecorder{}
+	return context.WithValue(ctx, itemsKey{}, recorder), recorder
+}
+
+func (r *itemRecorder) results() []ItemResult {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.items
+}
@@ -1,78 +1,476 @@
 package scheduler
 
-// So much to improve
-// make it sateless multi instance
-// definintions from db or config?
-// for now it gets the job done for only the monolithic apps
-
 import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"os"
+	"sync"
 	"time"
+
+	"github.com/gofiber/fiber/v2/log"
+)
+
+// Job is a task run on a cron schedule
+type Job struct {
+	Name string
+	// Cron is a standard 5 field cron expression or a descriptor like "@daily"
+	Cron string
+	// Timezone the cron expression is evaluated in, defaults to the local time zone
+	Timezone string
+	// CatchUp runs the job once on start when a run was missed while the process was down
+	CatchUp bool
+	// Retries is how many times a failed run is retried, waiting RetryBackoff
+	// before the first retry and doubling it for each next one
+	Retries      int
+	RetryBackoff time.Duration
+	Run          func(ctx context.Context) error
+}
+
+const (
+	TriggerSchedule = "schedule"
+	TriggerManual   = "manual"
+	TriggerTakeOver = "takeover"
 )
 
-type Task struct {
-	hour, min, sec int
-	taskFunc       func()
-	nextRun        time.Time
-	name           string
+var JobNotFoundErr error = errors.New("job not found")
+var JobRunningErr error = errors.New("job is already running")
+
+type scheduledJob struct {
+	Job
+	schedule *Schedule
+	location *time.Location
+	nextRun  time.Time
+	running  bool
 }
 
 type Scheduler struct {
-	tasks []Task
+	store Store
+	// owner identifies this instance in the leases, lease is how long a claimed run
+	// stays locked without renewal before another instance may take it over
+	owner        string
+	lease        time.Duration
+	nextTakeOver time.Time
+
+	mu   sync.Mutex
+	jobs []*scheduledJob
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stop    chan struct{}
+	running sync.WaitGroup
 }
 
-func New() *Scheduler {
-	return &Scheduler{}
+func New(store Store, lease time.Duration) *Scheduler {
+	if lease <= 0 {
+		lease = time.Minute
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		store:  store,
+		owner:  instanceId(),
+		lease:  lease,
+		ctx:    ctx,
+		cancel: cancel,
+		stop:   make(chan struct{}),
+	}
 }
 
-func (s *Scheduler) AddTask(task Task) {
-	now := time.Now()
-	nextRun := time.Date(now.Year(), now.Month(), now.Day(), task.hour, task.min, task.sec, 0, now.Location())
-	if nextRun.Before(now) {
-		nextRun = nextRun.Add(24 * time.Hour)
+func instanceId() string {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
 	}
-	task.nextRun = nextRun
-	s.tasks = append(s.tasks, task)
+	suffix := make([]byte, 4)
+	rand.Read(suffix)
+	return fmt.Sprintf("%s-%d-%s", host, os.Getpid(), hex.EncodeToString(suffix))
 }
 
-func (s *Scheduler) Add(name string, hour, min, sec int, taskFunc func()) {
-	task := Task{
-		hour:     hour,
-		min:      min,
-		sec:      sec,
-		taskFunc: taskFunc,
+// Add registers a job, it has to be called before Start
+func (s *Scheduler) Add(job Job) error {
+	if job.Name == "" || job.Run == nil {
+		return errors.New("scheduler job needs a name and a run func")
+	}
+	if job.Retries > 0 && job.RetryBackoff <= 0 {
+		job.RetryBackoff = 30 * time.Second
+	}
+	schedule, err := ParseCron(job.Cron)
+	if err != nil {
+		return err
+	}
+	location := time.Local
+	if job.Timezone != "" {
+		if location, err = time.LoadLocation(job.Timezone); err != nil {
+			return fmt.Errorf("job %s: %w", job.Name, err)
+		}
 	}
-	s.AddTask(task)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.findJob(job.Name) != nil {
+		return fmt.Errorf("job %s is already scheduled", job.Name)
+	}
+	s.jobs = append(s.jobs, &scheduledJob{Job: job, schedule: schedule, location: location})
+	return nil
 }
 
+// Start loads the persisted state of the jobs and starts triggering them
 func (s *Scheduler) Start() {
+	now := time.Now()
+
+	s.mu.Lock()
+	for _, j := range s.jobs {
+		j.nextRun = j.schedule.Next(now.In(j.location))
+
+		state, err := s.store.GetJobState(j.Name)
+		if err != nil {
+			log.Errorf("could not load state of job %s: %v", j.Name, err)
+		} else if j.CatchUp && missedRun(state, now) {
+			// the missed slot is kept, so only one instance catches up on it
+			log.Infof("Job %s missed its run at %s, catching up", j.Name, state.NextRunAt.Time.Format(time.RFC3339))
+			j.nextRun = state.NextRunAt.Time
+		}
+
+		s.saveSchedule(j, j.nextRun)
+	}
+	s.mu.Unlock()
+
 	go s.schedule()
 }
 
+// missedRun reports whether a run was due while the process was down,
+// a job stored for the first time has nothing to catch up
+func missedRun(state *JobState, now time.Time) bool {
+	return state != nil && state.NextRunAt.Valid && state.NextRunAt.Time.Before(now)
+}
+
 func (s *Scheduler) schedule() {
 	for {
 		now := time.Now()
-		closestRun := now.Add(-2 * time.Hour)
-		for _, t := range s.tasks {
-			if time.Now().After(t.nextRun) {
+		if !now.Before(s.nextTakeOver) {
+			s.takeOverAbandonedRuns()
+			s.nextTakeOver = now.Add(s.lease)
+		}
+
+		wait := s.triggerDueJobs(now)
+		if untilTakeOver := s.nextTakeOver.Sub(now); untilTakeOver < wait {
+			wait = untilTakeOver
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-s.stop:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// triggerDueJobs starts the due jobs and returns how long to sleep until the next one
+func (s *Scheduler) triggerDueJobs(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := time.Hour
+	for _, j := range s.jobs {
+		if j.nextRun.IsZero() {
+			continue
+		}
+		if !now.Before(j.nextRun) {
+			slot := j.nextRun
+			j.nextRun = j.schedule.Next(now.In(j.location))
+			if j.running {
+				log.Warnf("Job %s is still running, skipping its run at %s", j.Name, slot.Format(time.RFC3339))
+				s.saveSchedule(j, j.nextRun)
+			} else {
+				j.running = true
+				s.running.Add(1)
 				// works async to dont block
-				go t.taskFunc()
-				fmt.Printf("Task %s triggered at %s\n", t.name, time.Now().Format("2006-01-02 15:04:05"))
-
-				// Recalculate next run time
-				now = time.Now()
-				nextRun := time.Date(now.Year(), now.Month(), now.Day(), t.hour, t.min, t.sec, 0, now.Location())
-				if nextRun.Before(now) {
-					nextRun = nextRun.Add(24 * time.Hour)
-				}
-				t.nextRun = nextRun
-				if now.After(closestRun) || nextRun.After(closestRun) {
-					closestRun = nextRun
-				}
+				go s.runScheduled(j, slot, j.nextRun)
 			}
+		}
+		if !j.nextRun.IsZero() && j.nextRun.Sub(now) < wait {
+			wait = j.nextRun.Sub(now)
+		}
+	}
+	return wait
+}
+
+// takeOverAbandonedRuns reruns the slots whose holding instance died mid run
+func (s *Scheduler) takeOverAbandonedRuns() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, j := range s.jobs {
+		if j.running {
+			continue
+		}
+		slot, ok, err := s.store.TakeOverLease(j.Name, s.owner, s.lease)
+		if err != nil {
+			log.Errorf("could not take over job %s: %v", j.Name, err)
+			continue
+		}
+		if ok {
+			log.Warnf("Job %s run at %s was abandoned by its instance, taking over", j.Name, slot.Format(time.RFC3339))
+			j.running = true
+			s.running.Add(1)
+			go s.execute(j, slot, j.nextRun, TriggerTakeOver)
+		}
+	}
+}
+
+// runScheduled executes the slot unless the job is paused or another instance claimed it
+func (s *Scheduler) runScheduled(j *scheduledJob, slot, nextRun time.Time) {
+	state, err := s.store.GetJobState(j.Name)
+	if err != nil {
+		log.Errorf("could not load state of job %s: %v", j.Name, err)
+	} else if state != nil && state.Paused {
+		log.Infof("Job %s is paused, skipping its run at %s", j.Name, slot.Format(time.RFC3339))
+		s.mu.Lock()
+		next := j.nextRun
+		s.mu.Unlock()
+		s.saveSchedule(j, next)
+		s.finish(j)
+		return
+	}
+
+	acquired, err := s.store.AcquireLease(j.Name, s.owner, slot, s.lease)
+	if err != nil {
+		log.Errorf("could not acquire lease of job %s: %v", j.Name, err)
+	}
+	if !acquired {
+		if err == nil {
+			log.Infof("Job %s run at %s is handled by another instance", j.Name, slot.Format(time.RFC3339))
+		}
+		s.finish(j)
+		return
+	}
+
+	s.execute(j, slot, nextRun, TriggerSchedule)
+}
+
+// finish releases a job marked running by the trigger that started it
+func (s *Scheduler) finish(j *scheduledJob) {
+	s.mu.Lock()
+	j.running = false
+	s.mu.Unlock()
+	s.running.Done()
+}
+
+// execute runs a slot whose lease is held, retrying failed attempts while the lease is kept
+func (s *Scheduler) execute(j *scheduledJob, slot, nextRun time.Time, trigger string) {
+	defer s.finish(j)
+
+	log.Infof("Job %s triggered at %s by %s", j.Name, time.Now().Format(time.RFC3339), trigger)
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	renewed := make(chan struct{})
+	go func() {
+		defer close(renewed)
+		s.renewLease(ctx, cancel, j.Name)
+	}()
 
-			sleepDuration := closestRun.Sub(now)
-			time.Sleep(sleepDuration)
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = s.attempt(ctx, j, slot, trigger, attempt)
+		if err == nil || attempt > j.Retries {
+			break
 		}
+
+		backoff := j.RetryBackoff << (attempt - 1)
+		log.Warnf("Job %s attempt %d failed, retrying in %s: %v", j.Name, attempt, backoff, err)
+		select {
+		case <-ctx.Done():
+		case <-time.After(backoff):
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	cancel()
+	<-renewed
+
+	status, runErr := StatusSucceeded, ""
+	if err != nil {
+		status, runErr = StatusFailed, err.Error()
+		log.Errorf("Job %s failed: %v", j.Name, err)
+	}
+	if err := s.store.RecordRunEnd(j.Name, s.owner, time.Now(), status, runErr, nextRun); err != nil {
+		log.Errorf("could not record end of job %s: %v", j.Name, err)
+	}
+}
+
+// attempt runs the job once and stores the attempt with the items it reported
+func (s *Scheduler) attempt(ctx context.Context, j *scheduledJob, slot time.Time, trigger string, attempt int) error {
+	run := &JobRun{
+		JobName:      j.Name,
+		ScheduledFor: slot,
+		Trigger:      trigger,
+		Attempt:      attempt,
+		Owner:        s.owner,
+		Status:       StatusRunning,
+	}
+	if err := s.store.StartRun(run); err != nil {
+		log.Errorf("could not record run of job %s: %v", j.Name, err)
+	}
+
+	itemCtx, recorder := withItemRecorder(ctx)
+	err := runSafely(itemCtx, j.Run)
+
+	run.Items = recorder.results()
+	run.Status = StatusSucceeded
+	if err != nil {
+		run.Status = StatusFailed
+		run.Error = sql.NullString{String: err.Error(), Valid: true}
+	}
+	if run.Id != 0 {
+		if err := s.store.FinishRun(run); err != nil {
+			log.Errorf("could not record run %d of job %s: %v", run.Id, j.Name, err)
+		}
+	}
+	return err
+}
+
+// Trigger runs the job now, regardless of its schedule or pause
+func (s *Scheduler) Trigger(name string) error {
+	s.mu.Lock()
+	j := s.findJob(name)
+	if j == nil {
+		s.mu.Unlock()
+		return JobNotFoundErr
+	}
+	if j.running {
+		s.mu.Unlock()
+		return JobRunningErr
+	}
+	j.running = true
+	s.running.Add(1)
+	nextRun := j.nextRun
+	s.mu.Unlock()
+
+	slot := time.Now()
+	acquired, err := s.store.AcquireLease(j.Name, s.owner, slot, s.lease)
+	if err != nil || !acquired {
+		s.finish(j)
+		if err != nil {
+			return err
+		}
+		return JobRunningErr
+	}
+
+	go s.execute(j, slot, nextRun, TriggerManual)
+	return nil
+}
+
+func (s *Scheduler) Pause(name string) error {
+	return s.setPaused(name, true)
+}
+
+func (s *Scheduler) Resume(name string) error {
+	return s.setPaused(name, false)
+}
+
+func (s *Scheduler) setPaused(name string, paused bool) error {
+	s.mu.Lock()
+	j := s.findJob(name)
+	s.mu.Unlock()
+	if j == nil {
+		return JobNotFoundErr
+	}
+	return s.store.SetPaused(name, paused)
+}
+
+func (s *Scheduler) Jobs() ([]JobState, error) {
+	return s.store.GetJobStates()
+}
+
+func (s *Scheduler) Runs(name string, limit int) ([]JobRun, error) {
+	s.mu.Lock()
+	j := s.findJob(name)
+	s.mu.Unlock()
+	if j == nil {
+		return nil, JobNotFoundErr
+	}
+	return s.store.GetRuns(name, limit)
+}
+
+func (s *Scheduler) Run(id int64) (*JobRun, error) {
+	return s.store.GetRun(id)
+}
+
+func (s *Scheduler) findJob(name string) *scheduledJob {
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return j
+		}
+	}
+	return nil
+}
+
+// renewLease keeps the lease alive while the job runs and cancels the job once
+// the lease is lost, since another instance may take it over by then
+func (s *Scheduler) renewLease(ctx context.Context, cancel context.CancelFunc, name string) {
+	ticker := time.NewTicker(s.lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			renewed, err := s.store.RenewLease(name, s.owner, s.lease)
+			if err != nil {
+				// a transient error is retried until the lease expires
+				log.Errorf("could not renew lease of job %s: %v", name, err)
+				continue
+			}
+			if !renewed {
+				log.Errorf("Job %s lost its lease, cancelling the run", name)
+				cancel()
+				return
+			}
+		}
+	}
+}
+
+// runSafely turns a panicking job into a failed run instead of crashing the process
+func runSafely(ctx context.Context, run func(ctx context.Context) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return run(ctx)
+}
+
+// saveSchedule stores the next run, read by the caller under s.mu
+func (s *Scheduler) saveSchedule(j *scheduledJob, nextRun time.Time) {
+	if err := s.store.SaveSchedule(j.Name, j.Cron, j.location.String(), nextRun); err != nil {
+		log.Errorf("could not save schedule of job %s: %v", j.Name, err)
+	}
+}
+
+// Stop stops triggering jobs and waits for the running ones until ctx is done,
+// then cancels the context passed to them
+func (s *Scheduler) Stop(ctx context.Context) error {
+	close(s.stop)
+
+	done := make(chan struct{})
+	go func() {
+		s.running.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		s.cancel()
+		return nil
+	case <-ctx.Done():
+		s.cancel()
+		return fmt.Errorf("scheduler jobs still running: %w", ctx.Err())
 	}
 }
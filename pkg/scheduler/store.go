@@ -0,0 +1,269 @@
+package scheduler
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	StatusRunning   = "running"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// JobState is what the scheduler remembers about a job between restarts
+type JobState struct {
+	Name           string         `db:"name" json:"name"`
+	Schedule       string         `db:"schedule" json:"schedule"`
+	Timezone       string         `db:"timezone" json:"timezone"`
+	NextRunAt      sql.NullTime   `db:"next_run_at" json:"next_run_at"`
+	LastRunAt      sql.NullTime   `db:"last_run_at" json:"last_run_at"`
+	LastFinishedAt sql.NullTime   `db:"last_finished_at" json:"last_finished_at"`
+	LastStatus     sql.NullString `db:"last_status" json:"last_status"`
+	LastError      sql.NullString `db:"last_error" json:"last_error"`
+	// ScheduledFor is the slot of the last claimed run, LockedBy the instance holding its lease
+	ScheduledFor  sql.NullTime   `db:"scheduled_for" json:"scheduled_for"`
+	LockedBy      sql.NullString `db:"locked_by" json:"locked_by"`
+	LockExpiresAt sql.NullTime   `db:"lock_expires_at" json:"lock_expires_at"`
+	Paused        bool           `db:"paused" json:"paused"`
+	UpdatedAt     time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// JobRun is one attempt of a job, retries of the same slot share ScheduledFor
+type JobRun struct {
+	Id           int64          `db:"id" json:"id"`
+	JobName      string         `db:"job_name" json:"job_name"`
+	ScheduledFor time.Time      `db:"scheduled_for" json:"scheduled_for"`
+	Trigger      string         `db:"trigger" json:"trigger"`
+	Attempt      int            `db:"attempt" json:"attempt"`
+	Owner        string         `db:"owner" json:"owner"`
+	Status       string         `db:"status" json:"status"`
+	Error        sql.NullString `db:"error" json:"error"`
+	StartedAt    time.Time      `db:"started_at" json:"started_at"`
+	FinishedAt   sql.NullTime   `db:"finished_at" json:"finished_at"`
+	Items        []ItemResult   `db:"-" json:"items,omitempty"`
+}
+
+// ItemResult is the outcome of one item a run processed, like a single asset
+type ItemResult struct {
+	Item   string         `db:"item" json:"item"`
+	Status string         `db:"status" json:"status"`
+	Error  sql.NullString `db:"error" json:"error"`
+}
+
+// Store persists job state, a nil state is returned for a job that was never stored.
+// Runs are claimed through leases, so instances sharing a store run each slot once.
+type Store interface {
+	GetJobState(name string) (*JobState, error)
+	SaveSchedule(name, schedule, timezone string, nextRun time.Time) error
+	// AcquireLease claims the run of a slot, it fails when the slot was already
+	// claimed or another instance holds an unexpired lease
+	AcquireLease(name, owner string, slot time.Time, lease time.Duration) (bool, error)
+	// TakeOverLease claims a run whose holder stopped renewing its lease and returns its slot
+	TakeOverLease(name, owner string, lease time.Duration) (time.Time, bool, error)
+	RenewLease(name, owner string, lease time.Duration) (bool, error)
+	// RecordRunEnd stores the outcome and releases the lease if it's still held by owner
+	RecordRunEnd(name, owner string, finishedAt time.Time, status, runErr string, nextRun time.Time) error
+
+	GetJobStates() ([]JobState, error)
+	SetPaused(name string, paused bool) error
+	StartRun(run *JobRun) error
+	FinishRun(run *JobRun) error
+	GetRuns(name string, limit int) ([]JobRun, error)
+	// GetRun returns sql.ErrNoRows for an unknown run
+	GetRun(id int64) (*JobRun, error)
+}
+
+// SQLStore keeps job state in the scheduled_job table
+type SQLStore struct {
+	db *sqlx.DB
+}
+
+func NewSQLStore(db *sqlx.DB) *SQLStore {
+	return &SQLStore{db: db}
+}
+
+func (s *SQLStore) GetJobState(name string) (*JobState, error) {
+	var state JobState
+	err := s.db.Get(&state, `
+		SELECT name, schedule, timezone, next_run_at, last_run_at, last_finished_at, last_status, last_error,
+			scheduled_for, locked_by, lock_expires_at, paused, updated_at
+		FROM scheduled_job
+		WHERE name = $1
+	`, name)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (s *SQLStore) GetJobStates() ([]JobState, error) {
+	var states []JobState
+	err := s.db.Select(&states, `
+		SELECT name, schedule, timezone, next_run_at, last_run_at, last_finished_at, last_status, last_error,
+			scheduled_for, locked_by, lock_expires_at, paused, updated_at
+		FROM scheduled_job
+		ORDER BY name
+	`)
+	return states, err
+}
+
+func (s *SQLStore) SaveSchedule(name, schedule, timezone string, nextRun time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO scheduled_job (name, schedule, timezone, next_run_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+		SET schedule = EXCLUDED.schedule, timezone = EXCLUDED.timezone, next_run_at = EXCLUDED.next_run_at
+	`, name, schedule, timezone, nullTime(nextRun))
+	return err
+}
+
+// Lease expiry uses the database clock, so clock drift between instances doesn't matter
+
+func (s *SQLStore) AcquireLease(name, owner string, slot time.Time, lease time.Duration) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE scheduled_job
+		SET scheduled_for = $3, locked_by = $2, lock_expires_at = NOW() + make_interval(secs => $4),
+			last_run_at = NOW(), last_finished_at = NULL, last_status = $5, last_error = NULL
+		WHERE name = $1
+		AND (scheduled_for IS NULL OR scheduled_for < $3)
+		AND (locked_by IS NULL OR lock_expires_at < NOW())
+	`, name, owner, slot, lease.Seconds(), StatusRunning)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (s *SQLStore) TakeOverLease(name, owner string, lease time.Duration) (time.Time, bool, error) {
+	var slot time.Time
+	err := s.db.Get(&slot, `
+		UPDATE scheduled_job
+		SET locked_by = $2, lock_expires_at = NOW() + make_interval(secs => $3), last_run_at = NOW()
+		WHERE name = $1
+		AND last_status = $4
+		AND locked_by IS NOT NULL
+		AND lock_expires_at < NOW()
+		AND scheduled_for IS NOT NULL
+		RETURNING scheduled_for
+	`, name, owner, lease.Seconds(), StatusRunning)
+	if err == sql.ErrNoRows {
+		return time.Time{}, false, nil
+	}
+	if err != nil {
+		return time.Time{}, false, err
+	}
+	return slot, true, nil
+}
+
+func (s *SQLStore) RenewLease(name, owner string, lease time.Duration) (bool, error) {
+	result, err := s.db.Exec(`
+		UPDATE scheduled_job
+		SET lock_expires_at = NOW() + make_interval(secs => $3)
+		WHERE name = $1 AND locked_by = $2
+	`, name, owner, lease.Seconds())
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	return affected == 1, err
+}
+
+func (s *SQLStore) RecordRunEnd(name, owner string, finishedAt time.Time, status, runErr string, nextRun time.Time) error {
+	_, err := s.db.Exec(`
+		UPDATE scheduled_job
+		SET last_finished_at = $3, last_status = $4, last_error = $5, next_run_at = $6,
+			locked_by = NULL, lock_expires_at = NULL
+		WHERE name = $1 AND locked_by = $2
+	`, name, owner, finishedAt, status, sql.NullString{String: runErr, Valid: runErr != ""}, nullTime(nextRun))
+	return err
+}
+
+func (s *SQLStore) SetPaused(name string, paused bool) error {
+	_, err := s.db.Exec(`UPDATE scheduled_job SET paused = $2 WHERE name = $1`, name, paused)
+	return err
+}
+
+func (s *SQLStore) StartRun(run *JobRun) error {
+	return s.db.QueryRowx(`
+		INSERT INTO job_run (job_name, scheduled_for, trigger, attempt, owner, status)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, started_at
+	`, run.JobName, run.ScheduledFor, run.Trigger, run.Attempt, run.Owner, run.Status).Scan(&run.Id, &run.StartedAt)
+}
+
+// FinishRun stores the outcome of the run together with its item results
+func (s *SQLStore) FinishRun(run *JobRun) error {
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRowx(`
+		UPDATE job_run
+		SET status = $2, error = $3, finished_at = NOW()
+		WHERE id = $1
+		RETURNING finished_at
+	`, run.Id, run.Status, run.Error).Scan(&run.FinishedAt)
+	if err != nil {
+		return err
+	}
+
+	for _, item := range run.Items {
+		_, err = tx.Exec(`
+			INSERT INTO job_run_item (run_id, item, status, error)
+			VALUES ($1, $2, $3, $4)
+		`, run.Id, item.Item, item.Status, item.Error)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLStore) GetRuns(name string, limit int) ([]JobRun, error) {
+	var runs []JobRun
+	err := s.db.Select(&runs, `
+		SELECT id, job_name, scheduled_for, trigger, attempt, owner, status, error, started_at, finished_at
+		FROM job_run
+		WHERE job_name = $1
+		ORDER BY started_at DESC
+		LIMIT $2
+	`, name, limit)
+	return runs, err
+}
+
+func (s *SQLStore) GetRun(id int64) (*JobRun, error) {
+	var run JobRun
+	err := s.db.Get(&run, `
+		SELECT id, job_name, scheduled_for, trigger, attempt, owner, status, error, started_at, finished_at
+		FROM job_run
+		WHERE id = $1
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.db.Select(&run.Items, `
+		SELECT item, status, error
+		FROM job_run_item
+		WHERE run_id = $1
+		ORDER BY status, item
+	`, id)
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}